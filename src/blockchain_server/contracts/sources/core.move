@@ -19,6 +19,15 @@ module game_assets::core {
         card_loser: u64,
     }
 
+    // Classificação final de um torneio (Imutável)
+    // standings[i] terminou em i+1º lugar e recebeu prizes[i]
+    public struct TournamentLog has key {
+        id: UID,
+        tournament: String,
+        standings: vector<address>,
+        prizes: vector<u64>,
+    }
+
     // Permissão do Servidor
     public struct AdminCap has key, store { id: UID }
 
@@ -61,7 +70,24 @@ module game_assets::core {
         transfer::freeze_object(log);
     }
 
-    // 3. Transferir (Troca) - O servidor vai orquestrar, mas o dono assina
+    // 3. Registrar Torneio - Chamado ao fim do torneio, após os prêmios
+    public entry fun log_tournament(
+        _: &AdminCap,
+        tournament: vector<u8>,
+        standings: vector<address>,
+        prizes: vector<u64>,
+        ctx: &mut TxContext
+    ) {
+        let log = TournamentLog {
+            id: object::new(ctx),
+            tournament: string::utf8(tournament),
+            standings: standings,
+            prizes: prizes
+        };
+        transfer::freeze_object(log);
+    }
+
+    // 4. Transferir (Troca) - O servidor vai orquestrar, mas o dono assina
     public entry fun transfer_card(card: MonsterCard, recipient: address) {
         transfer::public_transfer(card, recipient);
     }
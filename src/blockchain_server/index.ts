@@ -92,6 +92,14 @@ async function transferFunds(signer: Ed25519Keypair, dest: string, amount: numbe
     return client.signAndExecuteTransaction({ signer, transaction: tx });
 }
 
+// Trace ID propagado pelo game server no header W3C "traceparent",
+// usado nos logs para correlacionar a operação com o trace da compra
+function traceOf(msg: nats.Msg): string {
+    const parent = msg.headers?.get("traceparent") || "";
+    const traceId = parent.split("-")[1];
+    return traceId ? ` [trace ${traceId}]` : "";
+}
+
 // --- EXECUÇÃO COM RETENTATIVA ---
 // Executa uma transação Move com tentativas automáticas quando objeto está trancado
 async function executeWithRetry(
@@ -123,7 +131,8 @@ async function executeWithRetry(
             });
 
             if (res.effects?.status.status === 'failure') {
-                throw new Error(`Falha na execução: ${res.effects.status.error}`);
+                // Transação abortada: nenhum efeito foi aplicado on-chain.
+                throw notExecuted(`Falha na execução: ${res.effects.status.error}`);
             }
             return res; 
 
@@ -145,7 +154,14 @@ async function executeWithRetry(
             }
         }
     }
-    throw new Error(`Falha após ${MAX_ATTEMPTS} tentativas em ${label}`);
+    // Todas as tentativas foram rejeitadas antes de executar.
+    throw notExecuted(`Falha após ${MAX_ATTEMPTS} tentativas em ${label}`);
+}
+
+// Erro de uma transação que com certeza não foi executada; o servidor de
+// jogo pode repetir a operação por outro caminho sem risco de duplicar.
+function notExecuted(message: string): Error {
+    return Object.assign(new Error(message), { notExecuted: true });
 }
 
 // --- HANDLERS ---
@@ -194,19 +210,46 @@ async function handleGetBalance(nc: nats.NatsConnection, jc: nats.Codec<unknown>
     });
 }
 
+// Pede tokens de teste ao Faucet para um usuário e retorna o novo saldo
+async function handleFaucet(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient) {
+    nc.subscribe("internalServer.faucet", {
+        async callback(err, msg) {
+            if (err) return;
+            const d = jc.decode(msg.data) as any;
+            console.log(`\n🚰 Faucet para ${d.client.address.substring(0,6)}...`);
+            try {
+                const before = await getBalance(d.client.address, client);
+                await requestIotaFromFaucetV0({ host: FAUCET_URL, recipient: d.client.address });
+
+                // Espera o saldo novo aparecer na rede
+                let bal = before, attempts = 0;
+                while (bal <= before && attempts < 10) {
+                    await new Promise(r => setTimeout(r, 1000));
+                    bal = await getBalance(d.client.address, client);
+                    attempts++;
+                }
+                msg.respond(jc.encode({ ok: true, client: d.client, price: bal }));
+            } catch (error: any) {
+                console.error("   ❌ Erro Faucet:", error?.message || error);
+                msg.respond(jc.encode({ ok: false, error: error?.message }));
+            }
+        },
+    });
+}
+
 // Cobra usuário → transfere para outro
 async function handleTransaction(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient){
     nc.subscribe("internalServer.transaction", {
         async callback(err, msg) {
             if (err) return;
             const d = jc.decode(msg.data) as any;
-            console.log("\n💳 Processando Pagamento...");
+            console.log(`\n💳 Processando Pagamento...${traceOf(msg)}`);
             await new Promise(r => setTimeout(r, 2000)); 
 
             const bal = await getBalance(d.client.address, client);
             if (bal < d.price) {
                 console.error(`   ❌ Saldo Insuficiente: ${bal}`);
-                msg.respond(jc.encode({ ok: false, error: "Saldo Insuficiente" }));
+                msg.respond(jc.encode({ ok: false, code: "INSUFFICIENT_FUNDS", error: "Saldo Insuficiente" }));
                 return;
             }
 
@@ -220,13 +263,13 @@ async function handleTransaction(nc: nats.NatsConnection, jc: nats.Codec<unknown
                 
                 if (res.effects?.status.status === 'success') {
                     console.log(`   ✅ Pago! Digest: ${res.digest}`);
-                    msg.respond(jc.encode({ ok: true, client: d.client }));
+                    msg.respond(jc.encode({ ok: true, client: d.client, digest: res.digest }));
                 } else {
-                    msg.respond(jc.encode({ ok: false }));
+                    msg.respond(jc.encode({ ok: false, error: res.effects?.status.error }));
                 }
-            } catch (error) {
+            } catch (error: any) {
                 console.error("   ❌ Erro Pagamento:", error);
-                msg.respond(jc.encode({ ok: false }));
+                msg.respond(jc.encode({ ok: false, error: error?.message }));
             }
         },
     });
@@ -240,7 +283,7 @@ async function handleMintCard(nc: nats.NatsConnection, jc: nats.Codec<unknown>,
             
             adminQueue = adminQueue.then(async () => {
                 const req = jc.decode(msg.data) as any;
-                console.log(`📦 Mintando carta ${req.value}...`);
+                console.log(`📦 Mintando carta ${req.value}...${traceOf(msg)}`);
                 
                 try {
                     const res = await executeWithRetry(client, adminKey, () => {
@@ -266,7 +309,70 @@ async function handleMintCard(nc: nats.NatsConnection, jc: nats.Codec<unknown>,
 
                 } catch (error: any) {
                     console.error("   ❌ Erro Fatal Mint:", error.message);
-                    msg.respond(jc.encode({ ok: false }));
+                    msg.respond(jc.encode({ ok: false, error: error.message }));
+                }
+            })
+            .then(() => new Promise(r => setTimeout(r, 1000)));
+        }
+    });
+}
+
+// Mint em lote: várias cartas (de um ou mais jogadores) em uma única
+// transação, com um moveCall mint_card por carta
+async function handleMintBatch(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient, adminKey: Ed25519Keypair) {
+    nc.subscribe("internalServer.mintBatch", {
+        callback(err, msg) {
+            if (err) return;
+
+            adminQueue = adminQueue.then(async () => {
+                const req = jc.decode(msg.data) as any;
+                const cards: { address: string, value: number }[] = req.cards || [];
+                console.log(`📦 Mintando lote de ${cards.length} cartas...${traceOf(msg)}`);
+
+                try {
+                    if (cards.length === 0) throw notExecuted("lote vazio");
+
+                    const res = await executeWithRetry(client, adminKey, () => {
+                        const tx = new Transaction();
+                        for (const card of cards) {
+                            tx.moveCall({
+                                target: `${PACKAGE_ID}::core::mint_card`,
+                                arguments: [ tx.object(ADMIN_CAP_ID), tx.pure.u64(card.value), tx.pure.address(card.address) ]
+                            });
+                        }
+                        return tx;
+                    }, "MintBatch");
+
+                    // Os objetos criados não vêm na ordem das chamadas: casa cada
+                    // carta pedida com um MonsterCard criado de mesmo dono e valor
+                    const structType = `${PACKAGE_ID}::core::MonsterCard`;
+                    const createdIds = (res.objectChanges || [])
+                        .filter((o: any) => o.type === 'created' && o.objectType === structType)
+                        .map((o: any) => o.objectId as string);
+
+                    const objects = createdIds.length === 0 ? [] : await client.multiGetObjects({
+                        ids: createdIds,
+                        options: { showContent: true, showOwner: true }
+                    });
+                    const pool = objects.map((obj: any) => ({
+                        id: obj.data?.objectId as string,
+                        owner: (obj.data?.owner as any)?.AddressOwner as string,
+                        value: parseInt((obj.data?.content as any)?.fields?.value || "0"),
+                    }));
+
+                    const objectIds = cards.map(card => {
+                        const i = pool.findIndex(o => o.owner === card.address && o.value === Number(card.value));
+                        if (i < 0) return "";
+                        return pool.splice(i, 1)[0].id;
+                    });
+
+                    console.log(`   ✅ Lote OK: ${objectIds.filter(id => id).length}/${cards.length} cartas (Digest: ${res.digest})`);
+                    msg.respond(jc.encode({ ok: true, digest: res.digest, objectIds }));
+
+                } catch (error: any) {
+                    console.error("   ❌ Erro Fatal Mint em lote:", error.message);
+                    const code = error?.notExecuted ? "NOT_EXECUTED" : undefined;
+                    msg.respond(jc.encode({ ok: false, code, error: error.message }));
                 }
             })
             .then(() => new Promise(r => setTimeout(r, 1000)));
@@ -300,9 +406,86 @@ async function handleLogMatch(nc: nats.NatsConnection, jc: nats.Codec<unknown>,
                     }
                     console.log("   ✅ Match Logged.");
                     msg.respond(jc.encode({ ok: true, digest: res.digest, objectId: createdId }));
-                } catch (error) {
+                } catch (error: any) {
                     console.error("   ❌ Erro Log:", error);
-                    msg.respond(jc.encode({ ok: false }));
+                    msg.respond(jc.encode({ ok: false, error: error?.message }));
+                }
+            })
+            .then(() => new Promise(r => setTimeout(r, 1000)));
+        }
+    });
+}
+
+// Log da classificação final de um torneio (histórico on-chain)
+async function handleLogTournament(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient, adminKey: Ed25519Keypair) {
+    nc.subscribe("internalServer.logTournament", {
+        callback(err, msg) {
+            if (err) return;
+            adminQueue = adminQueue.then(async () => {
+                const req = jc.decode(msg.data) as any;
+                console.log(`🏆 Registrando torneio ${req.tournament}...${traceOf(msg)}`);
+                try {
+                    const res = await executeWithRetry(client, adminKey, () => {
+                        const tx = new Transaction();
+                        tx.moveCall({
+                            target: `${PACKAGE_ID}::core::log_tournament`,
+                            arguments: [
+                                tx.object(ADMIN_CAP_ID),
+                                tx.pure.string(req.tournament),
+                                tx.pure.vector('address', req.standings || []),
+                                tx.pure.vector('u64', req.prizes || []),
+                            ]
+                        });
+                        return tx;
+                    }, "LogTournament");
+
+                    let createdId = "";
+                    if (res.objectChanges) {
+                        const created = res.objectChanges.find((o: any) => o.type === 'created');
+                        if (created) {
+                            createdId = (created as any).objectId;
+                        }
+                    }
+                    console.log(`   ✅ Torneio registrado: ${createdId}`);
+                    msg.respond(jc.encode({ ok: true, digest: res.digest, objectId: createdId }));
+                } catch (error: any) {
+                    console.error("   ❌ Erro Log Torneio:", error);
+                    msg.respond(jc.encode({ ok: false, error: error?.message }));
+                }
+            })
+            .then(() => new Promise(r => setTimeout(r, 1000)));
+        }
+    });
+}
+
+// Pagamento pela carteira da loja (prêmios e reembolsos)
+async function handlePayout(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient, adminKey: Ed25519Keypair) {
+    nc.subscribe("internalServer.payout", {
+        callback(err, msg) {
+            if (err) return;
+            adminQueue = adminQueue.then(async () => {
+                const req = jc.decode(msg.data) as any;
+                console.log(`💸 Pagando ${req.amount} para ${req.recipient.substring(0,6)}...${traceOf(msg)}`);
+                try {
+                    const bal = await getBalance(adminKey.toIotaAddress(), client);
+                    if (bal < req.amount) {
+                        console.error(`   ❌ Saldo da loja insuficiente: ${bal}`);
+                        msg.respond(jc.encode({ ok: false, code: "INSUFFICIENT_FUNDS", error: "Saldo da loja insuficiente" }));
+                        return;
+                    }
+
+                    const res = await executeWithRetry(client, adminKey, () => {
+                        const tx = new Transaction();
+                        const [coin] = tx.splitCoins(tx.gas, [tx.pure.u64(req.amount)]);
+                        tx.transferObjects([coin], req.recipient);
+                        return tx;
+                    }, "Payout");
+
+                    console.log(`   ✅ Pago! Digest: ${res.digest}`);
+                    msg.respond(jc.encode({ ok: true, digest: res.digest }));
+                } catch (error: any) {
+                    console.error("   ❌ Erro Pagamento da loja:", error?.message || error);
+                    msg.respond(jc.encode({ ok: false, error: error?.message }));
                 }
             })
             .then(() => new Promise(r => setTimeout(r, 1000)));
@@ -318,15 +501,19 @@ async function handleTransferCard(nc: nats.NatsConnection, jc: nats.Codec<unknow
             const req = jc.decode(msg.data) as any;
             try {
                 const signer = Ed25519Keypair.fromSecretKey(req.ownerSecret);
+                if (!await verifyOwnership(client, signer.toIotaAddress(), req.cardObjectId)) {
+                    msg.respond(jc.encode({ ok: false, code: "NOT_OWNER", error: "Carta não pertence ao remetente" }));
+                    return;
+                }
                 const tx = new Transaction();
                 tx.moveCall({
                     target: `${PACKAGE_ID}::core::transfer_card`,
                     arguments: [ tx.object(req.cardObjectId), tx.pure.address(req.recipient) ]
                 });
-                await client.signAndExecuteTransaction({ signer: signer, transaction: tx });
-                msg.respond(jc.encode({ ok: true }));
-            } catch (error) {
-                msg.respond(jc.encode({ ok: false }));
+                const res = await client.signAndExecuteTransaction({ signer: signer, transaction: tx });
+                msg.respond(jc.encode({ ok: true, digest: res.digest }));
+            } catch (error: any) {
+                msg.respond(jc.encode({ ok: false, error: error?.message }));
             }
         }
     });
@@ -440,6 +627,70 @@ async function handleAtomicSwap(nc: nats.NatsConnection, jc: nats.Codec<unknown>
     });
 }
 
+// Health check: responde sem tocar na blockchain
+async function handlePing(nc: nats.NatsConnection, jc: nats.Codec<unknown>) {
+    nc.subscribe("internalServer.ping", {
+        callback(err, msg) {
+            if (err) return;
+            msg.respond(jc.encode({ ok: true }));
+        }
+    });
+}
+
+// --- MODO SEM CUSTÓDIA ---
+// Monta a transferência de uma carta sem assiná-la: o jogador assina no cliente
+async function handleBuildTransferCard(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient) {
+    nc.subscribe("internalServer.buildTransferCard", {
+        async callback(err, msg) {
+            if (err) return;
+            const req = jc.decode(msg.data) as any;
+            try {
+                if (!await verifyOwnership(client, req.sender, req.cardObjectId)) {
+                    msg.respond(jc.encode({ ok: false, code: "NOT_OWNER", error: "Carta não pertence ao remetente" }));
+                    return;
+                }
+                await ensureFunds(req.sender, client);
+
+                const tx = new Transaction();
+                tx.setSender(req.sender);
+                tx.moveCall({
+                    target: `${PACKAGE_ID}::core::transfer_card`,
+                    arguments: [ tx.object(req.cardObjectId), tx.pure.address(req.recipient) ]
+                });
+                const bytes = await tx.build({ client });
+                msg.respond(jc.encode({ ok: true, txBytes: Buffer.from(bytes).toString("base64") }));
+            } catch (error: any) {
+                msg.respond(jc.encode({ ok: false, error: error?.message }));
+            }
+        }
+    });
+}
+
+// Submete uma transação assinada pelo cliente
+async function handleExecuteSigned(nc: nats.NatsConnection, jc: nats.Codec<unknown>, client: IotaClient) {
+    nc.subscribe("internalServer.executeSigned", {
+        async callback(err, msg) {
+            if (err) return;
+            const req = jc.decode(msg.data) as any;
+            try {
+                const res = await client.executeTransactionBlock({
+                    transactionBlock: req.txBytes,
+                    signature: req.signature,
+                    options: { showEffects: true }
+                });
+                if (res.effects?.status.status === 'success') {
+                    console.log(`✍️ Transação assinada pelo cliente executada: ${res.digest}`);
+                    msg.respond(jc.encode({ ok: true, digest: res.digest }));
+                } else {
+                    msg.respond(jc.encode({ ok: false, error: res.effects?.status.error }));
+                }
+            } catch (error: any) {
+                msg.respond(jc.encode({ ok: false, error: error?.message }));
+            }
+        }
+    });
+}
+
 // --- MAIN ---
 async function main() {
     if (!PACKAGE_ID || !ADMIN_SECRET) {
@@ -459,13 +710,20 @@ async function main() {
     // Inicializa todos os handlers
     handleCreateWallet(nc, jc, client, adminKey);
     handleGetBalance(nc, jc, client); 
+    handleFaucet(nc, jc, client);
     handleTransaction(nc, jc, client);
     handleMintCard(nc, jc, client, adminKey);
+    handleMintBatch(nc, jc, client, adminKey);
     handleLogMatch(nc, jc, client, adminKey);
+    handleLogTournament(nc, jc, client, adminKey);
+    handlePayout(nc, jc, client, adminKey);
     handleTransferCard(nc, jc, client);
     handleGetPlayerCards(nc, jc, client);
     handleValidateOwnership(nc, jc, client);
     handleAtomicSwap(nc, jc, client);
+    handleBuildTransferCard(nc, jc, client);
+    handleExecuteSigned(nc, jc, client);
+    handlePing(nc, jc);
 }
 
 main().catch(console.error);
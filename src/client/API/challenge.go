@@ -0,0 +1,115 @@
+package API
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- DESAFIOS PRIVADOS ---
+
+// Validade de um desafio no servidor; o desafiante espera no máximo isso.
+const challengeWait = 2 * time.Minute
+
+// Challenge é um desafio pendente; To == 0 indica um código de sala
+// aberto, que qualquer jogador com o código pode aceitar.
+type Challenge struct {
+	Code    string    `json:"code"`
+	From    int       `json:"from"`
+	To      int       `json:"to"`
+	Expires time.Time `json:"expires"`
+}
+
+// Evento enviado pelo servidor em player.<id>.challenge.
+type challengeEvent struct {
+	Event     string      `json:"event"`
+	Challenge Challenge   `json:"challenge"`
+	By        int         `json:"by"`
+	Match     matchStruct `json:"match"`
+	Err       string      `json:"err"`
+}
+
+func challengeRequest(nc *nats.Conn, cmd string, req map[string]any, out any) error {
+	return commandRequest(nc, "topic.challenge."+cmd, req, out, 15*time.Second)
+}
+
+// RequestChallenge desafia target (0 = gera um código de sala) e aguarda
+// a resposta em player.<id>.challenge. onCreated recebe o desafio assim
+// que ele é registrado, para exibir o código. Retorna o ID da partida.
+func RequestChallenge(nc *nats.Conn, id, target int, onCreated func(Challenge)) (string, error) {
+	events := make(chan challengeEvent, 4)
+	sub, err := nc.Subscribe(fmt.Sprintf("player.%d.challenge", id), func(m *nats.Msg) {
+		var ev challengeEvent
+		if json.Unmarshal(m.Data, &ev) != nil {
+			return
+		}
+		select {
+		case events <- ev:
+		default:
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	defer sub.Unsubscribe()
+
+	var challenge Challenge
+	if err := challengeRequest(nc, "create", map[string]any{"client_id": id, "target_id": target}, &challenge); err != nil {
+		return "", err
+	}
+	onCreated(challenge)
+
+	timeout := time.After(challengeWait)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Challenge.Code != challenge.Code {
+				continue
+			}
+			switch ev.Event {
+			case "accepted":
+				return ev.Match.SelfId, nil
+			case "declined":
+				return "", fmt.Errorf("jogador %d recusou o desafio", ev.By)
+			case "canceled":
+				return "", fmt.Errorf("desafio cancelado: %s", ev.Err)
+			}
+		case <-timeout:
+			RequestCancelChallenge(nc, id)
+			return "", errors.New("desafio expirou sem resposta")
+		}
+	}
+}
+
+// RequestChallenges lista os desafios pendentes enviados e recebidos.
+func RequestChallenges(nc *nats.Conn, id int) (sent, received []Challenge, err error) {
+	var result struct {
+		Sent     []Challenge `json:"sent"`
+		Received []Challenge `json:"received"`
+	}
+	err = challengeRequest(nc, "list", map[string]any{"client_id": id}, &result)
+	return result.Sent, result.Received, err
+}
+
+// RequestAcceptChallenge aceita um desafio (ou entra com um código de
+// sala) e retorna o ID da partida criada.
+func RequestAcceptChallenge(nc *nats.Conn, id int, code string) (string, error) {
+	var result struct {
+		Match matchStruct `json:"match"`
+	}
+	err := challengeRequest(nc, "accept", map[string]any{"client_id": id, "code": code}, &result)
+	return result.Match.SelfId, err
+}
+
+// RequestDeclineChallenge recusa um desafio recebido.
+func RequestDeclineChallenge(nc *nats.Conn, id int, code string) error {
+	return challengeRequest(nc, "decline", map[string]any{"client_id": id, "code": code}, nil)
+}
+
+// RequestCancelChallenge desiste do desafio enviado pelo jogador.
+func RequestCancelChallenge(nc *nats.Conn, id int) error {
+	return challengeRequest(nc, "cancel", map[string]any{"client_id": id}, nil)
+}
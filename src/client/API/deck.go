@@ -0,0 +1,109 @@
+package API
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- DECKS ---
+
+// DeckCard é uma carta de um deck com a força conhecida pelo servidor.
+type DeckCard struct {
+	ID    string `json:"id"`
+	Power int    `json:"power"`
+}
+
+// Deck descreve um deck do jogador; Valid indica se ele ainda respeita
+// as regras (cartas possuídas, tamanho e limite de força).
+type Deck struct {
+	Name       string     `json:"name"`
+	Cards      []DeckCard `json:"cards"`
+	TotalPower int        `json:"total_power"`
+	Active     bool       `json:"active"`
+	Valid      bool       `json:"valid"`
+	Err        string     `json:"err"`
+}
+
+// DeckRules são as regras de montagem do servidor.
+type DeckRules struct {
+	Size     int `json:"size"`
+	PowerCap int `json:"power_cap"`
+}
+
+// Envia um comando topic.deck.<cmd> e decodifica o campo result em out.
+func deckRequest(nc *nats.Conn, cmd string, req map[string]any, out any) error {
+	return commandRequest(nc, "topic.deck."+cmd, req, out, 15*time.Second)
+}
+
+// Envia um comando a um tópico que responde {"result": ...} ou
+// {"err": ...} e decodifica o campo result em out.
+func commandRequest(nc *nats.Conn, subject string, req map[string]any, out any, timeout time.Duration) error {
+	data, _ := json.Marshal(req)
+	response, err := request(nc, subject, data, timeout)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Err    string          `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// RequestDecks lista os decks do jogador e as regras de montagem.
+func RequestDecks(nc *nats.Conn, id int) ([]Deck, DeckRules, error) {
+	var result struct {
+		Decks []Deck    `json:"decks"`
+		Rules DeckRules `json:"rules"`
+	}
+	err := deckRequest(nc, "list", map[string]any{"client_id": id}, &result)
+	return result.Decks, result.Rules, err
+}
+
+// RequestSaveDeck cria um deck novo ou substitui as cartas de um existente.
+func RequestSaveDeck(nc *nats.Conn, id int, name string, cards []string, create bool) error {
+	cmd := "edit"
+	if create {
+		cmd = "create"
+	}
+	return deckRequest(nc, cmd, map[string]any{"client_id": id, "name": name, "cards": cards}, nil)
+}
+
+// RequestRenameDeck muda o nome de um deck mantendo as cartas.
+func RequestRenameDeck(nc *nats.Conn, id int, name, newName string, cards []string) error {
+	return deckRequest(nc, "edit", map[string]any{"client_id": id, "name": name, "new_name": newName, "cards": cards}, nil)
+}
+
+// RequestDeleteDeck apaga um deck.
+func RequestDeleteDeck(nc *nats.Conn, id int, name string) error {
+	return deckRequest(nc, "delete", map[string]any{"client_id": id, "name": name}, nil)
+}
+
+// RequestSelectDeck define o deck ativo usado no matchmaking.
+func RequestSelectDeck(nc *nats.Conn, id int, name string) error {
+	return deckRequest(nc, "select", map[string]any{"client_id": id, "name": name}, nil)
+}
+
+// ActiveDeck retorna o deck ativo do jogador, se houver.
+func ActiveDeck(decks []Deck) (Deck, bool) {
+	for _, d := range decks {
+		if d.Active {
+			return d, true
+		}
+	}
+	return Deck{}, false
+}
@@ -0,0 +1,111 @@
+package API
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- SORTEIO VERIFICÁVEL (PROVABLY FAIR) ---
+
+// Força máxima de uma carta; precisa ser igual à do servidor.
+const maxCardPower = 900
+
+// FairDraw é o comprovante de um sorteio de pacote enviado pelo servidor.
+type FairDraw struct {
+	Epoch      int    `json:"epoch"`
+	Commitment string `json:"commitment"`
+	PlayerID   int    `json:"player_id"`
+	ClientSeed string `json:"client_seed"`
+	Nonce      int    `json:"nonce"`
+	Pack       [3]int `json:"pack"`
+}
+
+// FairExpect é o compromisso da época e o nonce vistos antes de escolher
+// a semente: o servidor recusa o sorteio se algum deles mudou.
+type FairExpect struct {
+	Commitment string
+	Nonce      int
+}
+
+// FairEpoch descreve uma época de sorteios; Seed só vem preenchida
+// depois que a época foi encerrada e a semente revelada.
+type FairEpoch struct {
+	Number     int    `json:"number"`
+	Commitment string `json:"commitment"`
+	Seed       string `json:"seed"`
+	Draws      int    `json:"draws"`
+}
+
+// NewClientSeed gera uma semente aleatória para o próximo sorteio.
+func NewClientSeed() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestFairEpochs consulta o compromisso da época corrente, as
+// sementes já reveladas e o nonce do próximo sorteio do jogador.
+func RequestFairEpochs(nc *nats.Conn, id int) (FairEpoch, []FairEpoch, int, error) {
+	data, _ := json.Marshal(map[string]any{"client_id": id})
+	response, err := request(nc, "topic.fair.epochs", data, 5*time.Second)
+	if err != nil {
+		return FairEpoch{}, nil, 0, err
+	}
+
+	var resp struct {
+		Current   FairEpoch   `json:"current"`
+		Revealed  []FairEpoch `json:"revealed"`
+		NextNonce int         `json:"next_nonce"`
+		Err       string      `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return FairEpoch{}, nil, 0, fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return FairEpoch{}, nil, 0, errors.New(resp.Err)
+	}
+	return resp.Current, resp.Revealed, resp.NextNonce, nil
+}
+
+// VerifyDraw confere que a semente revelada corresponde ao compromisso
+// publicado e recalcula o pacote: HMAC-SHA256(semente, "<jogador>:<semente
+// do cliente>:<nonce>"), 8 bytes por carta, reduzidos a 1..maxCardPower.
+func VerifyDraw(epoch FairEpoch, draw FairDraw) error {
+	if epoch.Seed == "" {
+		return fmt.Errorf("semente da época %d ainda não revelada", epoch.Number)
+	}
+	seed, err := hex.DecodeString(epoch.Seed)
+	if err != nil {
+		return fmt.Errorf("semente inválida: %v", err)
+	}
+
+	commitment := sha256.Sum256(seed)
+	if hex.EncodeToString(commitment[:]) != epoch.Commitment {
+		return fmt.Errorf("semente revelada não corresponde ao compromisso da época %d", epoch.Number)
+	}
+	if draw.Commitment != "" && draw.Commitment != epoch.Commitment {
+		return fmt.Errorf("comprovante aponta outro compromisso (%s…)", draw.Commitment[:min(12, len(draw.Commitment))])
+	}
+
+	mac := hmac.New(sha256.New, seed)
+	fmt.Fprintf(mac, "%d:%s:%d", draw.PlayerID, draw.ClientSeed, draw.Nonce)
+	sum := mac.Sum(nil)
+
+	var pack [3]int
+	for i := range pack {
+		pack[i] = int(binary.BigEndian.Uint64(sum[i*8:])%maxCardPower) + 1
+	}
+	if pack != draw.Pack {
+		return fmt.Errorf("pacote recalculado %v difere do recebido %v", pack, draw.Pack)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package API
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// --- LOGS DE DIAGNÓSTICO ---
+
+// SetupLogging configura os logs de diagnóstico do cliente (conexão,
+// assinaturas, respostas inválidas). Eles vão para w (stderr), separados
+// do menu; o nível padrão "warn" mantém a tela limpa durante o jogo.
+func SetupLogging(w io.Writer, level, format string) {
+	lvl := slog.LevelWarn
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			lvl = slog.LevelWarn
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
@@ -1,10 +1,14 @@
 package API
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -17,13 +21,15 @@ import (
 type CardDisplay struct {
 	ID    string `json:"id"`
 	Power int    `json:"power"`
+	Owner string `json:"owner"`
 }
 
 // Estrutura para mostrar ao usuário suas credenciais armazenadas na blockchain.
 // Usada na opção “Ver credenciais”.
 type UserCredentials struct {
-	Address string `json:"address"`
-	Secret  string `json:"secret"`
+	Address     string `json:"address"`
+	Secret      string `json:"secret"`
+	SelfCustody bool   `json:"self_custody"`
 }
 
 // Estruturas internas usadas apenas para comunicação interna do matchmaking.
@@ -44,14 +50,46 @@ type NatsMessage struct {
 
 // --- INFRAESTRUTURA ---
 
-// BrokerConnect conecta ao servidor NATS baseado no número fornecido.
-// Cada servidor NATS está em localhost com offset +4222.
-func BrokerConnect(serverNumber int) *nats.Conn {
-	url := "nats://localhost:" + strconv.Itoa(serverNumber+4222)
-	nc, _ := nats.Connect(url)
+// BrokerConnect conecta a um dos servidores NATS da lista.
+// Se a conexão cair, tenta os demais servidores indefinidamente com
+// backoff exponencial; as assinaturas são refeitas automaticamente e
+// onReconnect é chamado para que o cliente retome a sessão.
+func BrokerConnect(servers []string, onReconnect func(*nats.Conn)) *nats.Conn {
+	if len(servers) == 0 {
+		servers = []string{nats.DefaultURL}
+	}
+	opts := []nats.Option{
+		nats.Name("Game-Client"),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(reconnectBackoff),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			slog.Warn("NATS disconnected", "err", err)
+			fmt.Println("\n⚠️ Conexão com o broker perdida. Tentando reconectar...")
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			slog.Info("NATS reconnected", "url", nc.ConnectedUrl())
+			fmt.Println("\n🔄 Reconectado em", nc.ConnectedUrl())
+			if onReconnect != nil {
+				onReconnect(nc)
+			}
+		}),
+	}
+	nc, err := nats.Connect(strings.Join(servers, ","), opts...)
+	if err != nil {
+		slog.Error("NATS connect failed", "servers", servers, "err", err)
+		return nil
+	}
 	return nc
 }
 
+// Espera entre tentativas de reconexão: começa em 250ms e dobra
+// até o limite de 10s, com jitter para espalhar os clientes.
+func reconnectBackoff(attempts int) time.Duration {
+	delay := 250 * time.Millisecond << min(attempts, 6)
+	delay = min(delay, 10*time.Second)
+	return delay + rand.N(delay/4+1)
+}
+
 // RequestPing mede o ping entre cliente e servidor através de um request NATS.
 // Retorna latência em ms ou -1 se ocorreu erro.
 func RequestPing(nc *nats.Conn) int64 {
@@ -59,7 +97,7 @@ func RequestPing(nc *nats.Conn) int64 {
 		"send_time": time.Now().UnixMilli(),
 	}
 	data, _ := json.Marshal(msg)
-	response, err := nc.Request("topic.ping", data, 5*time.Second)
+	response, err := request(nc, "topic.ping", data, 5*time.Second)
 	if err != nil {
 		return -1
 	}
@@ -73,16 +111,29 @@ func RequestPing(nc *nats.Conn) int64 {
 // --- CONTA E LOGIN ---
 
 // RequestCreateAccount cria uma conta blockchain no servidor.
-// Retorna o ID do jogador criado ou 0 caso erro.
-func RequestCreateAccount(nc *nats.Conn) int {
-	response, err := nc.Request("topic.createAccount", nil, 10*time.Second)
+// Retorna o ID do jogador criado ou o erro informado pelo servidor
+// (por exemplo, quando a carteira não pôde ser provisionada).
+func RequestCreateAccount(nc *nats.Conn) (int, error) {
+	response, err := request(nc, "topic.createAccount", nil, 45*time.Second)
 	if err != nil {
-		fmt.Println("Erro NATS:", err.Error())
-		return 0
+		return 0, err
 	}
-	msg := make(map[string]int)
-	json.Unmarshal(response.Data, &msg)
-	return msg["player_id"]
+
+	var msg struct {
+		PlayerID int    `json:"player_id"`
+		Err      string `json:"err"`
+		Msg      string `json:"msg"`
+	}
+	if err := json.Unmarshal(response.Data, &msg); err != nil {
+		return 0, err
+	}
+	if msg.Err != "" {
+		if msg.Msg != "" {
+			return 0, errors.New(msg.Msg)
+		}
+		return 0, errors.New(msg.Err)
+	}
+	return msg.PlayerID, nil
 }
 
 // RequestLogin tenta realizar login usando o ID do jogador.
@@ -92,7 +143,7 @@ func RequestLogin(nc *nats.Conn, id int) (bool, error) {
 		"client_id": id,
 	}
 	data, _ := json.Marshal(msg)
-	response, err := nc.Request("topic.login", data, 10*time.Second)
+	response, err := request(nc, "topic.login", data, 10*time.Second)
 	if err != nil {
 		return false, err
 	}
@@ -106,37 +157,168 @@ func RequestLogin(nc *nats.Conn, id int) (bool, error) {
 
 // --- ECONOMIA (PACOTES E CARTAS) ---
 
-// RequestOpenPack solicita ao servidor a abertura de um pacote.
-// Retorna slice de IDs das cartas recebidas.
-func RequestOpenPack(nc *nats.Conn, id int) ([]int, error) {
+// PackEvent é um evento de progresso da abertura de pacote, recebido
+// em player.<id>.pack. Stage: "paid", "drawn" (com o comprovante do
+// sorteio em Draw), "minted", "completed" ou "failed".
+type PackEvent struct {
+	JobID    string    `json:"job_id"`
+	Stage    string    `json:"stage"`
+	Digest   string    `json:"digest"`
+	Card     int       `json:"card"`
+	Total    int       `json:"total"`
+	Power    int       `json:"power"`
+	ObjectID string    `json:"object_id"`
+	Cards    []int     `json:"cards"`
+	Draw     *FairDraw `json:"draw"`
+	Err      string    `json:"err"`
+}
+
+// Tempo máximo sem nenhum evento do job antes de desistir de acompanhá-lo
+// (o pagamento e cada mint levam até ~20s).
+const packEventTimeout = 60 * time.Second
+
+// RequestOpenPack solicita ao servidor a abertura de um pacote e
+// acompanha o job: cada evento de progresso é repassado a onEvent.
+// clientSeed entra no sorteio verificável do conteúdo do pacote; com
+// expect, o servidor só sorteia se a época e o nonce ainda forem esses.
+// Retorna as forças das cartas obtidas quando o job termina.
+func RequestOpenPack(nc *nats.Conn, id int, clientSeed string, expect *FairExpect, onEvent func(PackEvent)) ([]int, error) {
+	// Assina antes de pedir para não perder eventos que cheguem
+	// antes da resposta com o ID do job.
+	events := make(chan PackEvent, 16)
+	sub, err := nc.Subscribe(fmt.Sprintf("player.%d.pack", id), func(m *nats.Msg) {
+		var ev PackEvent
+		if json.Unmarshal(m.Data, &ev) == nil {
+			events <- ev
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
 	msg := map[string]any{
-		"client_id": id,
+		"client_id":   id,
+		"client_seed": clientSeed,
+	}
+	if expect != nil {
+		msg["commitment"] = expect.Commitment
+		msg["nonce"] = expect.Nonce
 	}
 	data, _ := json.Marshal(msg)
-	response, err := nc.Request("topic.openPack", data, 60*time.Second)
-
+	response, err := request(nc, "topic.openPack", data, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
 
-	json.Unmarshal(response.Data, &msg)
+	var resp struct {
+		JobID string `json:"job_id"`
+		Err   string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return nil, fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	slog.Debug("pack job started", "job_id", resp.JobID)
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.JobID != resp.JobID {
+				continue
+			}
+			if onEvent != nil {
+				onEvent(ev)
+			}
+			switch ev.Stage {
+			case "completed":
+				return ev.Cards, nil
+			case "failed":
+				return nil, errors.New(ev.Err)
+			}
+		case <-time.After(packEventTimeout):
+			return nil, fmt.Errorf("sem notícias do job %s; confira suas cartas mais tarde", resp.JobID)
+		}
+	}
+}
 
-	if msg["err"] != nil {
-		return nil, errors.New(msg["err"].(string))
+// Balance é o saldo on-chain do jogador junto do preço atual do pacote.
+type Balance struct {
+	Balance   uint64 `json:"balance"`
+	PackPrice uint64 `json:"pack_price"`
+	Err       string `json:"err"`
+}
+
+// RequestBalance consulta o saldo da carteira do jogador.
+func RequestBalance(nc *nats.Conn, id int) (*Balance, error) {
+	msg := map[string]any{
+		"client_id": id,
+	}
+	data, _ := json.Marshal(msg)
+	response, err := request(nc, "topic.balance", data, 30*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
-	if msg["result"] == nil {
-		return []int{}, nil
+	var resp Balance
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return nil, fmt.Errorf("erro parse json: %v", err)
 	}
+	if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return &resp, nil
+}
 
-	resultSlice := msg["result"].([]any)
-	cards := make([]int, 0, len(resultSlice))
+// RequestFaucet pede tokens de teste e retorna o novo saldo.
+func RequestFaucet(nc *nats.Conn, id int) (uint64, error) {
+	msg := map[string]any{
+		"client_id": id,
+	}
+	data, _ := json.Marshal(msg)
+	response, err := request(nc, "topic.faucet", data, 30*time.Second)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp Balance
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return 0, fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return 0, errors.New(resp.Err)
+	}
+	return resp.Balance, nil
+}
 
-	for _, item := range resultSlice {
-		cards = append(cards, int(item.(float64)))
+// RequestSendTokens envia IOTA para outro jogador (toID) ou para um
+// endereço (toAddress, usado quando toID é 0). Retorna o digest da transação.
+func RequestSendTokens(nc *nats.Conn, id int, toID int, toAddress string, amount uint64) (string, error) {
+	msg := map[string]any{
+		"client_id":  id,
+		"to_id":      toID,
+		"to_address": toAddress,
+		"amount":     amount,
+	}
+	data, _ := json.Marshal(msg)
+	response, err := request(nc, "topic.sendTokens", data, 60*time.Second)
+	if err != nil {
+		return "", err
 	}
 
-	return cards, nil
+	var resp struct {
+		Digest string `json:"digest"`
+		Err    string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Digest, nil
 }
 
 // RequestSeeCards retorna todas as cartas que o usuário possui,
@@ -146,7 +328,7 @@ func RequestSeeCards(nc *nats.Conn, id int) ([]CardDisplay, error) {
 		"client_id": id,
 	}
 	data, _ := json.Marshal(msg)
-	response, err := nc.Request("topic.seeCards", data, 10*time.Second)
+	response, err := request(nc, "topic.seeCards", data, 10*time.Second)
 
 	if err != nil {
 		return nil, err
@@ -173,8 +355,35 @@ func RequestSeeCards(nc *nats.Conn, id int) ([]CardDisplay, error) {
 // RequestFindMatch envia pedido para entrar na fila de partida e aguarda pareamento.
 // O servidor responde por broadcast no tópico matchmaking.
 func RequestFindMatch(nc *nats.Conn, id int) (string, error) {
+	return findMatch(nc, id, nil, nil)
+}
+
+// RequestFindWagerMatch entra na fila de apostas com stake IOTA e
+// aguarda um adversário com a mesma aposta. onQueued recebe a taxa da
+// casa (%) assim que o servidor aceita o pedido. As duas apostas são
+// retidas antes de a partida ser criada; o pagamento ou reembolso chega
+// em player.<id>.wager.
+func RequestFindWagerMatch(nc *nats.Conn, id int, stake uint64, onQueued func(rake uint64)) (string, error) {
+	return findMatch(nc, id, map[string]any{"wager": stake}, func(resp map[string]any) {
+		rake, _ := resp["rake"].(float64)
+		onQueued(uint64(rake))
+	})
+}
+
+// RequestFindAnteMatch entra na fila ante apostando a carta cardID do
+// deck ativo. Na partida só essa carta pode ser jogada; se perder, ela
+// vai para o adversário (aviso em player.<id>.ante).
+func RequestFindAnteMatch(nc *nats.Conn, id int, cardID string) (string, error) {
+	return findMatch(nc, id, map[string]any{"ante_card": cardID}, nil)
+}
+
+// findMatch entra na fila indicada pelos campos extras do pedido (opts)
+// e aguarda o pareamento. onQueued recebe a resposta do servidor assim
+// que o jogador entra na fila.
+func findMatch(nc *nats.Conn, id int, opts map[string]any, onQueued func(resp map[string]any)) (string, error) {
 	matchValue := ""
 	match := &matchValue
+	queueErr := ""
 	onQueue := make(chan int)
 
 	// Inscrição temporária no canal de matchmaking para capturar resposta destinada ao jogador.
@@ -187,6 +396,7 @@ func RequestFindMatch(nc *nats.Conn, id int) (string, error) {
 		// Em caso de erro do servidor
 		if natsPayload.Err != nil {
 			*match = ""
+			queueErr = fmt.Sprint(natsPayload.Err)
 			onQueue <- -1
 			return
 		}
@@ -202,8 +412,11 @@ func RequestFindMatch(nc *nats.Conn, id int) (string, error) {
 	msg := map[string]any{
 		"client_id": id,
 	}
+	for k, v := range opts {
+		msg[k] = v
+	}
 	data, _ := json.Marshal(msg)
-	response, err := nc.Request("topic.findMatch", data, 30*time.Second)
+	response, err := request(nc, "topic.findMatch", data, 30*time.Second)
 
 	if err != nil {
 		return "", err
@@ -213,11 +426,17 @@ func RequestFindMatch(nc *nats.Conn, id int) (string, error) {
 	if msg["err"] != nil {
 		return "", errors.New(msg["err"].(string))
 	}
+	if onQueued != nil {
+		onQueued(msg)
+	}
 
 	// Aguarda resposta do servidor ou timeout
 	select {
 	case res := <-onQueue:
 		if res == -1 {
+			if queueErr != "" {
+				return "", errors.New(queueErr)
+			}
 			return "", errors.New("erro na fila")
 		}
 		return *match, nil
@@ -226,6 +445,30 @@ func RequestFindMatch(nc *nats.Conn, id int) (string, error) {
 	}
 }
 
+// RequestPractice cria uma partida de treino contra o bot do servidor
+// (strategy: random, greedy, adaptive ou vazio para o padrão).
+// Retorna o ID da partida; a jogada segue por SendCards.
+func RequestPractice(nc *nats.Conn, id int, strategy string) (string, error) {
+	msg := map[string]any{
+		"client_id": id,
+		"strategy":  strategy,
+	}
+	data, _ := json.Marshal(msg)
+	response, err := request(nc, "topic.practice", data, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp NatsMessage
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != nil {
+		return "", fmt.Errorf("%v", resp.Err)
+	}
+	return resp.Match.SelfId, nil
+}
+
 // --- BLIND TRADE ---
 
 // JoinBlindTrade envia uma carta para participar de uma troca cega.
@@ -235,7 +478,7 @@ func JoinBlindTrade(nc *nats.Conn, myID int, myCard string) error {
 		"card_id":   myCard,
 	}
 	data, _ := json.Marshal(req)
-	_, err := nc.Request("topic.trade.joinBlind", data, 5*time.Second)
+	_, err := request(nc, "topic.trade.joinBlind", data, 5*time.Second)
 	return err
 }
 
@@ -265,34 +508,290 @@ func WaitForTradeResult(nc *nats.Conn, myID int) {
 	sub.Unsubscribe()
 }
 
+// --- PRESENTES E NOTIFICAÇÕES ---
+
+// RequestGiftCard envia uma carta (ID hex) de presente para outro jogador.
+// Retorna o digest da transferência. Enquanto o pedido está aberto, este
+// cliente só assina a transferência dessa carta para a carteira de toID.
+func RequestGiftCard(nc *nats.Conn, myID int, toID int, cardID string) (string, error) {
+	address, err := RequestPlayerAddress(nc, myID, toID)
+	if err != nil {
+		return "", err
+	}
+	defer expectTransfer(cardID, address, time.Minute)()
+
+	req := map[string]any{
+		"client_id": myID,
+		"to_id":     toID,
+		"card_id":   cardID,
+	}
+	data, _ := json.Marshal(req)
+	response, err := request(nc, "topic.giftCard", data, 30*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Digest string `json:"digest"`
+		Err    string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Digest, nil
+}
+
+// RequestPlayerAddress retorna o endereço da carteira principal de playerID.
+func RequestPlayerAddress(nc *nats.Conn, id, playerID int) (string, error) {
+	req := map[string]any{
+		"client_id": id,
+		"player_id": playerID,
+	}
+	data, _ := json.Marshal(req)
+	response, err := request(nc, "topic.playerAddress", data, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Address string `json:"address"`
+		Err     string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Address, nil
+}
+
+// --- CARTEIRAS EXTERNAS ---
+
+// RequestLinkChallenge pede ao servidor a mensagem que deve ser assinada
+// com a chave da carteira externa (address) para vinculá-la à conta.
+func RequestLinkChallenge(nc *nats.Conn, id int, address string) (string, error) {
+	req := map[string]any{
+		"client_id": id,
+		"address":   address,
+	}
+	data, _ := json.Marshal(req)
+	response, err := request(nc, "topic.wallet.challenge", data, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Challenge string `json:"challenge"`
+		Err       string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Challenge, nil
+}
+
+// RequestLinkWallet envia a assinatura do desafio. Retorna o endereço
+// vinculado, conforme derivado pelo servidor a partir da assinatura.
+func RequestLinkWallet(nc *nats.Conn, id int, signature string) (string, error) {
+	req := map[string]any{
+		"client_id": id,
+		"signature": signature,
+	}
+	data, _ := json.Marshal(req)
+	response, err := request(nc, "topic.wallet.link", data, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Address string `json:"address"`
+		Err     string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Address, nil
+}
+
+// ListenNotifications assina o tópico exclusivo do jogador (player.<id>.>)
+// e exibe os avisos enviados pelo servidor enquanto ele estiver logado.
+func ListenNotifications(nc *nats.Conn, id int) *nats.Subscription {
+	prefix := fmt.Sprintf("player.%d.", id)
+	sub, _ := nc.Subscribe(prefix+">", func(m *nats.Msg) {
+		var payload map[string]any
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			slog.Warn("invalid notification", "subject", m.Subject, "err", err)
+			return
+		}
+		slog.Debug("notification received", "subject", m.Subject)
+
+		switch strings.TrimPrefix(m.Subject, prefix) {
+		case "sign":
+			signRequest(m, payload)
+		case "gift":
+			fmt.Println("\n\n🎁 VOCÊ RECEBEU UM PRESENTE!")
+			fmt.Printf("   Jogador %v enviou a carta %v (Força: %v)\n", payload["from_id"], payload["card_id"], payload["power"])
+			fmt.Printf("   Digest: %v\n", payload["digest"])
+		case "challenge":
+			// Aceite e recusa são tratados por quem aguarda em RequestChallenge.
+			challenge, _ := payload["challenge"].(map[string]any)
+			switch payload["event"] {
+			case "invite":
+				fmt.Println("\n\n⚔️ VOCÊ FOI DESAFIADO!")
+				fmt.Printf("   Jogador %v quer uma partida. Código: %v (opção 16 para responder)\n", challenge["from"], challenge["code"])
+			case "canceled":
+				fmt.Printf("\n\n⚔️ O desafio %v foi cancelado.\n", challenge["code"])
+			}
+		case "tournament":
+			tournamentNotice(id, payload)
+		case "wager":
+			wagerNotice(payload)
+		case "ante":
+			anteNotice(payload)
+		}
+	})
+	return sub
+}
+
+// Mostra o desfecho financeiro de uma partida apostada.
+func wagerNotice(payload map[string]any) {
+	amount := uint64(0)
+	if v, ok := payload["amount"].(float64); ok {
+		amount = uint64(v)
+	}
+	if e, ok := payload["err"].(string); ok {
+		fmt.Printf("\n\n💰 Falha ao transferir %d IOTA da aposta: %s (procure um administrador)\n", amount, e)
+		return
+	}
+	switch payload["event"] {
+	case "won":
+		fmt.Printf("\n\n💰 Você ganhou o pote: %d IOTA (taxa da casa: %v IOTA). Digest: %v\n", amount, payload["rake"], payload["digest"])
+	case "lost":
+		fmt.Printf("\n\n💸 Você perdeu a aposta de %d IOTA.\n", amount)
+	case "refunded":
+		fmt.Printf("\n\n💰 Aposta de %d IOTA devolvida. Digest: %v\n", amount, payload["digest"])
+	}
+}
+
+// Mostra o destino da carta apostada em uma partida ante.
+func anteNotice(payload map[string]any) {
+	if e, ok := payload["err"].(string); ok {
+		fmt.Printf("\n\n🎴 Falha ao transferir a carta %v da partida ante: %s (procure um administrador)\n", payload["card_id"], e)
+		return
+	}
+	switch payload["event"] {
+	case "won":
+		fmt.Printf("\n\n🎴 Você ganhou a carta %v (Força: %v) do adversário! Digest: %v\n", payload["card_id"], payload["power"], payload["digest"])
+	case "lost":
+		fmt.Printf("\n\n🎴 Sua carta %v (Força: %v) foi para o adversário.\n", payload["card_id"], payload["power"])
+	}
+}
+
+// Responde a um pedido de assinatura do servidor com a chave local
+// do endereço indicado. Recusa o pedido sem a chave ou se a transação
+// não for a transferência de carta que o jogador pediu (ver checkTransfer).
+func signRequest(m *nats.Msg, payload map[string]any) {
+	address, _ := payload["address"].(string)
+	txB64, _ := payload["tx_bytes"].(string)
+
+	reply := func(resp map[string]any) {
+		data, _ := json.Marshal(resp)
+		m.Respond(data)
+	}
+
+	signer, ok := lookupSigner(address)
+	if !ok {
+		slog.Warn("sign request for unknown key", "subject", m.Subject, "address", address)
+		reply(map[string]any{"err": "chave não carregada neste cliente"})
+		return
+	}
+	txBytes, err := base64.StdEncoding.DecodeString(txB64)
+	if err != nil {
+		slog.Warn("invalid transaction in sign request", "subject", m.Subject, "err", err)
+		reply(map[string]any{"err": "transação inválida"})
+		return
+	}
+	tx, err := checkTransfer(txBytes, address)
+	if err != nil {
+		slog.Warn("sign request refused", "subject", m.Subject, "address", address, "err", err)
+		fmt.Printf("\n⛔ Pedido de assinatura recusado: %v\n", err)
+		reply(map[string]any{"err": err.Error()})
+		return
+	}
+	slog.Info("signing transaction", "subject", m.Subject, "address", address, "tx_bytes", len(txBytes))
+
+	fmt.Printf("\n✍️ Assinando localmente: carta %s para %s\n", tx.Card, tx.Recipient)
+	reply(map[string]any{"signature": signer.SignTransaction(txBytes)})
+}
+
+// RequestSetCustody liga/desliga o modo sem custódia no servidor. Ao
+// ligar, o servidor entrega a chave da carteira principal (handover) e
+// a apaga; se o modo já estava ligado, handover vem vazio. Para
+// desligar, secret devolve a chave ao servidor.
+func RequestSetCustody(nc *nats.Conn, id int, selfCustody bool, secret string) (handover string, err error) {
+	req := map[string]any{
+		"client_id":    id,
+		"self_custody": selfCustody,
+		"secret":       secret,
+	}
+	data, _ := json.Marshal(req)
+	response, err := request(nc, "topic.custody", data, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Secret string `json:"secret"`
+		Err    string `json:"err"`
+	}
+	if err := json.Unmarshal(response.Data, &resp); err != nil {
+		return "", fmt.Errorf("erro parse json: %v", err)
+	}
+	if resp.Err != "" {
+		return "", errors.New(resp.Err)
+	}
+	return resp.Secret, nil
+}
+
 // --- CREDENCIAIS ---
 
 // RequestCredentials pede ao servidor o par (address, secret)
-// para exibir ao usuário. O servidor retorna erro se não existirem.
+// para exibir ao usuário. O servidor retorna erro se não existirem; no
+// modo sem custódia o servidor não tem a chave e Secret vem vazio.
 func RequestCredentials(nc *nats.Conn, id int) (*UserCredentials, error) {
 	msg := map[string]any{
 		"client_id": id,
 	}
 	data, _ := json.Marshal(msg)
 	
-	response, err := nc.Request("topic.getCredentials", data, 5*time.Second)
+	response, err := request(nc, "topic.getCredentials", data, 5*time.Second)
 	if err != nil {
 		return nil, err
 	}
 
-	var raw map[string]string
+	var raw struct {
+		UserCredentials
+		Err string `json:"err"`
+	}
 	if err := json.Unmarshal(response.Data, &raw); err != nil {
 		return nil, err
 	}
 	
-	if val, ok := raw["err"]; ok {
-		return nil, errors.New(val)
+	if raw.Err != "" {
+		return nil, errors.New(raw.Err)
 	}
 
-	return &UserCredentials{
-		Address: raw["address"],
-		Secret:  raw["secret"],
-	}, nil
+	return &raw.UserCredentials, nil
 }
 
 // --- GAME LOOP ---
@@ -305,7 +804,7 @@ func SendCards(nc *nats.Conn, id int, card int, game string) {
 		"game":      game,
 	}
 	data, _ := json.Marshal(msg)
-	nc.Publish("game.client", data)
+	publish(nc, "game.client", data)
 }
 
 // ManageGame2 escuta mensagens de jogo enviadas pelo servidor
@@ -318,15 +817,18 @@ func ManageGame2(nc *nats.Conn, id *int, card chan int, roundResult chan string,
 		currId := *id
 		if currId == 0 { return }
 		
+		// Erros endereçados a outro jogador (ex.: partida cancelada) são ignorados.
+		pID, hasID := payload["client_id"].(float64)
+		if hasID && int(pID) != currId { return }
+
 		// Se o servidor sinalizou erro
 		if payload["err"] != nil {
+			fmt.Println("\n⚠️", payload["err"])
 			card <- 0
 			roundResult <- "error"
+			object <- ""
 			return
 		}
-		
-		pID, _ := payload["client_id"].(float64)
-		if int(pID) != currId { return }
 
 		card <- int(payload["card"].(float64))
 		roundResult <- payload["result"].(string)
@@ -363,12 +865,14 @@ func LoggedIn(nc *nats.Conn, id int) *nats.Subscription {
 
 // Heartbeat registra e atualiza o ping vindo do servidor
 // e aumenta os limites do buffer para evitar slow consumer.
-func Heartbeat(nc *nats.Conn, value *int64) {
+func Heartbeat(nc *nats.Conn, value *atomic.Int64) {
 	sub, err := nc.Subscribe("topic.heartbeat", func(msg *nats.Msg) {
 		var ping map[string]int64
-		if err := json.Unmarshal(msg.Data, &ping); err == nil {
-			*value = ping["server_ping"]
+		if err := json.Unmarshal(msg.Data, &ping); err != nil {
+			slog.Debug("invalid heartbeat", "subject", msg.Subject, "err", err)
+			return
 		}
+		value.Store(ping["server_ping"])
 	})
 	
 	if err == nil {
@@ -0,0 +1,176 @@
+package API
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// --- ASSINATURA LOCAL (CARTEIRA EXTERNA) ---
+
+// A chave privada da carteira externa só é usada aqui, no cliente:
+// o servidor recebe apenas a assinatura e o endereço.
+
+const (
+	ed25519Flag   = 0x00
+	privKeyPrefix = "iotaprivkey"
+)
+
+// Intents das mensagens assinadas: mensagem pessoal (scope=3) e
+// dados de transação (scope=0), ambos com version=0 e app=0.
+var (
+	personalMessageIntent = []byte{3, 0, 0}
+	transactionIntent     = []byte{0, 0, 0}
+)
+
+// Signer guarda a chave Ed25519 de uma carteira IOTA externa.
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// ParsePrivateKey aceita a chave no formato exportado pela CLI/carteiras
+// IOTA (bech32 "iotaprivkey1...") ou no formato do keystore (base64 de
+// flag || chave de 32 bytes).
+func ParsePrivateKey(text string) (*Signer, error) {
+	text = strings.TrimSpace(text)
+
+	var raw []byte
+	var err error
+	if strings.HasPrefix(strings.ToLower(text), privKeyPrefix+"1") {
+		raw, err = bech32Decode(text, privKeyPrefix)
+	} else {
+		raw, err = base64.StdEncoding.DecodeString(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 1+ed25519.SeedSize {
+		return nil, errors.New("tamanho de chave inválido")
+	}
+	if raw[0] != ed25519Flag {
+		return nil, errors.New("apenas chaves Ed25519 são suportadas")
+	}
+	return &Signer{key: ed25519.NewKeyFromSeed(raw[1:])}, nil
+}
+
+// Address retorna o endereço IOTA da chave: blake2b-256(flag || chave pública).
+func (s *Signer) Address() string {
+	pub := s.key.Public().(ed25519.PublicKey)
+	sum := blake2b.Sum256(append([]byte{ed25519Flag}, pub...))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// SignPersonalMessage assina msg como signPersonalMessage das carteiras
+// IOTA e retorna a assinatura serializada (base64 de flag || sig || pub).
+func (s *Signer) SignPersonalMessage(msg []byte) string {
+	payload := append(append([]byte{}, personalMessageIntent...), binary.AppendUvarint(nil, uint64(len(msg)))...)
+	payload = append(payload, msg...)
+	digest := blake2b.Sum256(payload)
+
+	return s.serialize(ed25519.Sign(s.key, digest[:]))
+}
+
+// SignTransaction assina os bytes BCS de uma transação (TransactionData)
+// e retorna a assinatura serializada aceita pelo nó.
+func (s *Signer) SignTransaction(txBytes []byte) string {
+	digest := blake2b.Sum256(append(append([]byte{}, transactionIntent...), txBytes...))
+	return s.serialize(ed25519.Sign(s.key, digest[:]))
+}
+
+func (s *Signer) serialize(sig []byte) string {
+	out := append([]byte{ed25519Flag}, sig...)
+	out = append(out, s.key.Public().(ed25519.PublicKey)...)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+// --- CHAVEIRO ---
+
+// Chaves carregadas nesta sessão, por endereço. Usadas para responder
+// aos pedidos de assinatura do servidor (modo sem custódia).
+var keyring sync.Map
+
+// RegisterSigner guarda a chave para assinar transações do seu endereço.
+func RegisterSigner(s *Signer) {
+	keyring.Store(s.Address(), s)
+}
+
+// lookupSigner retorna a chave carregada para o endereço, se houver.
+func lookupSigner(address string) (*Signer, bool) {
+	v, ok := keyring.Load(address)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Signer), true
+}
+
+// --- BECH32 ---
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Decodifica uma string bech32 com o prefixo esperado e retorna os dados em bytes.
+func bech32Decode(text, hrp string) ([]byte, error) {
+	text = strings.ToLower(text)
+	sep := strings.LastIndexByte(text, '1')
+	if sep < 1 || text[:sep] != hrp || len(text)-sep-1 < 6 {
+		return nil, errors.New("chave bech32 inválida")
+	}
+
+	data := make([]byte, 0, len(text)-sep-1)
+	for _, c := range text[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return nil, errors.New("caractere inválido na chave bech32")
+		}
+		data = append(data, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), data...)) != 1 {
+		return nil, errors.New("checksum bech32 inválido")
+	}
+
+	// Converte os grupos de 5 bits (sem o checksum) para bytes.
+	var out []byte
+	acc, bits := 0, 0
+	for _, v := range data[:len(data)-6] {
+		acc = acc<<5 | int(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>bits))
+		}
+		acc &= 1<<bits - 1
+	}
+	return out, nil
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		out = append(out, byte(c>>5))
+	}
+	out = append(out, 0)
+	for _, c := range hrp {
+		out = append(out, byte(c&31))
+	}
+	return out
+}
+
+func bech32Polymod(values []byte) int {
+	gen := [5]int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ int(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,148 @@
+package API
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- TORNEIOS ---
+
+// Inscrição e criação cobram a taxa na blockchain e demoram mais.
+const tournamentTimeout = 45 * time.Second
+
+// Pairing é um confronto de uma rodada; P2 == 0 indica folga.
+type Pairing struct {
+	P1     int    `json:"p1"`
+	P2     int    `json:"p2"`
+	Game   string `json:"game"`
+	Winner int    `json:"winner"`
+}
+
+type TournamentRound struct {
+	Number   int       `json:"number"`
+	Pairings []Pairing `json:"pairings"`
+}
+
+// Standing é a colocação final de um jogador e o prêmio pago.
+type Standing struct {
+	Place    int    `json:"place"`
+	PlayerID int    `json:"player_id"`
+	Wins     int    `json:"wins"`
+	Prize    uint64 `json:"prize"`
+	Digest   string `json:"digest"`
+	Err      string `json:"err"`
+}
+
+type Tournament struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Format      string            `json:"format"`
+	Creator     int               `json:"creator"`
+	EntryFee    uint64            `json:"entry_fee"`
+	MaxPlayers  int               `json:"max_players"`
+	Status      string            `json:"status"`
+	Players     []int             `json:"players"`
+	Rounds      []TournamentRound `json:"rounds"`
+	TotalRounds int               `json:"total_rounds"`
+	Wins        map[int]int       `json:"wins"`
+	Standings   []Standing        `json:"standings"`
+	ObjectID    string            `json:"object_id"`
+	Err         string            `json:"err"`
+}
+
+// PendingMatch retorna a partida da rodada corrente que o jogador ainda
+// precisa jogar.
+func (t Tournament) PendingMatch(id int) (game string, round int, ok bool) {
+	if len(t.Rounds) == 0 || t.Status != "running" {
+		return "", 0, false
+	}
+	r := t.Rounds[len(t.Rounds)-1]
+	for _, p := range r.Pairings {
+		if p.Winner == 0 && p.P2 != 0 && (p.P1 == id || p.P2 == id) {
+			return p.Game, r.Number, true
+		}
+	}
+	return "", 0, false
+}
+
+// Exibe um aviso de torneio recebido em player.<id>.tournament.
+func tournamentNotice(id int, payload map[string]any) {
+	switch payload["event"] {
+	case "match", "rematch":
+		label := "Sua partida"
+		if payload["event"] == "rematch" {
+			label = "Empate! Revanche"
+		}
+		fmt.Printf("\n\n🏆 %s da rodada %v contra o jogador %v está pronta (opção 17 para jogar).\n", label, payload["round"], payload["opponent"])
+	case "bye":
+		fmt.Printf("\n\n🏆 Você folga na rodada %v e avança direto.\n", payload["round"])
+	case "walkover":
+		fmt.Printf("\n\n🏆 Partida encerrada por W.O.; vencedor: jogador %v.\n", payload["winner"])
+	case "canceled":
+		fmt.Printf("\n\n🏆 Torneio cancelado. Inscrição devolvida: %v IOTA.\n", payload["refund"])
+	case "finished":
+		var standings []Standing
+		raw, _ := json.Marshal(payload["standings"])
+		json.Unmarshal(raw, &standings)
+		fmt.Println("\n\n🏆 TORNEIO ENCERRADO!")
+		for _, st := range standings {
+			if st.PlayerID == id {
+				fmt.Printf("   Você terminou em %dº lugar", st.Place)
+				if st.Prize > 0 {
+					fmt.Printf(" e ganhou %d IOTA", st.Prize)
+				}
+				fmt.Println(".")
+			}
+		}
+		fmt.Printf("   Classificação registrada na blockchain: %v\n", payload["object_id"])
+	}
+}
+
+func tournamentRequest(nc *nats.Conn, cmd string, req map[string]any, out any) error {
+	return commandRequest(nc, "topic.tournament."+cmd, req, out, tournamentTimeout)
+}
+
+// RequestTournaments lista os torneios, dos mais recentes para os mais antigos.
+func RequestTournaments(nc *nats.Conn, id int) ([]Tournament, error) {
+	var out []Tournament
+	err := tournamentRequest(nc, "list", map[string]any{"client_id": id}, &out)
+	return out, err
+}
+
+// RequestTournament consulta um torneio (chave, vitórias e classificação).
+func RequestTournament(nc *nats.Conn, id int, tid string) (Tournament, error) {
+	var out Tournament
+	err := tournamentRequest(nc, "get", map[string]any{"client_id": id, "tournament": tid}, &out)
+	return out, err
+}
+
+// RequestCreateTournament cria um torneio (format: elimination ou swiss)
+// já inscrevendo o criador, que paga a taxa de inscrição.
+func RequestCreateTournament(nc *nats.Conn, id int, name, format string, fee uint64, maxPlayers int) (Tournament, error) {
+	var out Tournament
+	err := tournamentRequest(nc, "create", map[string]any{
+		"client_id":   id,
+		"name":        name,
+		"format":      format,
+		"entry_fee":   fee,
+		"max_players": maxPlayers,
+	}, &out)
+	return out, err
+}
+
+// RequestJoinTournament inscreve o jogador, pagando a taxa à loja.
+func RequestJoinTournament(nc *nats.Conn, id int, tid string) (Tournament, error) {
+	var out Tournament
+	err := tournamentRequest(nc, "join", map[string]any{"client_id": id, "tournament": tid}, &out)
+	return out, err
+}
+
+// RequestStartTournament inicia o torneio antes de lotar (só o criador).
+func RequestStartTournament(nc *nats.Conn, id int, tid string) (Tournament, error) {
+	var out Tournament
+	err := tournamentRequest(nc, "start", map[string]any{"client_id": id, "tournament": tid}, &out)
+	return out, err
+}
@@ -0,0 +1,98 @@
+package API
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// --- RASTREAMENTO (OPENTELEMETRY) ---
+
+// Cada requisição ao servidor abre um span e leva o contexto de trace
+// nos headers NATS; o servidor continua o mesmo trace até o worker.
+var tracer = otel.Tracer("client/API")
+
+// SetupTracing instala o propagador W3C e, quando exporter é "stdout",
+// escreve os spans finalizados em w (JSON). Retorna a função que
+// descarrega os spans pendentes.
+func SetupTracing(exporter string, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if exporter != "stdout" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("game-client"))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// headerCarrier adapta nats.Header ao formato usado pelos propagadores.
+type headerCarrier nats.Header
+
+func (c headerCarrier) Get(key string) string {
+	if v := c[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = []string{value}
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Monta a mensagem com o contexto de trace nos headers.
+func tracedMsg(ctx context.Context, subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(msg.Header))
+	return msg
+}
+
+// request envia uma requisição ao servidor dentro de um span próprio.
+func request(nc *nats.Conn, subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	ctx, span := tracer.Start(context.Background(), subject, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("messaging.destination.name", subject)))
+	defer span.End()
+
+	response, err := nc.RequestMsg(tracedMsg(ctx, subject, data), timeout)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return response, err
+}
+
+// publish envia uma mensagem sem resposta, também com o contexto de trace.
+func publish(nc *nats.Conn, subject string, data []byte) error {
+	ctx, span := tracer.Start(context.Background(), subject, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination.name", subject)))
+	defer span.End()
+
+	return nc.PublishMsg(tracedMsg(ctx, subject, data))
+}
@@ -0,0 +1,230 @@
+package API
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- CONFERÊNCIA DAS TRANSAÇÕES ANTES DE ASSINAR ---
+
+// Qualquer um conectado ao NATS pode publicar em player.<id>.sign, então
+// o cliente não confia na descrição do pedido: decodifica os bytes BCS
+// da TransactionData e só assina uma chamada única a core::transfer_card
+// de uma carta que ele mesmo mandou presentear, para o destinatário
+// esperado.
+
+// cardTransfer é o conteúdo de uma transação de transfer_card.
+type cardTransfer struct {
+	Sender    string
+	Package   string
+	Card      string
+	Recipient string
+}
+
+// packageID, se configurado, é o único pacote aceito na chamada.
+var packageID string
+
+// SetPackageID fixa o pacote do jogo (PACKAGE_ID do worker); sem ele,
+// qualquer pacote com core::transfer_card é aceito.
+func SetPackageID(id string) {
+	if id != "" {
+		packageID = normalizeID(id)
+	}
+}
+
+// normalizeID deixa um ID de objeto/endereço em "0x" + 64 dígitos minúsculos.
+func normalizeID(id string) string {
+	id = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(id)), "0x")
+	if len(id) < 64 {
+		id = strings.Repeat("0", 64-len(id)) + id
+	}
+	return "0x" + id
+}
+
+// Transferências que o jogador pediu e ainda esperam assinatura, por carta.
+var (
+	pendingMu sync.Mutex
+	pending   = map[string]pendingTransfer{}
+)
+
+type pendingTransfer struct {
+	recipient string
+	expires   time.Time
+}
+
+// expectTransfer autoriza uma única assinatura da transferência de card
+// para recipient dentro de ttl. release desfaz a autorização.
+func expectTransfer(card, recipient string, ttl time.Duration) (release func()) {
+	card = normalizeID(card)
+	pendingMu.Lock()
+	pending[card] = pendingTransfer{recipient: normalizeID(recipient), expires: time.Now().Add(ttl)}
+	pendingMu.Unlock()
+	return func() {
+		pendingMu.Lock()
+		delete(pending, card)
+		pendingMu.Unlock()
+	}
+}
+
+// takeTransfer consome a autorização que cobre tx, se houver.
+func takeTransfer(tx cardTransfer) error {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	p, ok := pending[tx.Card]
+	if !ok || time.Now().After(p.expires) {
+		delete(pending, tx.Card)
+		return fmt.Errorf("nenhuma transferência pendente da carta %s", tx.Card)
+	}
+	if p.recipient != tx.Recipient {
+		return fmt.Errorf("destinatário %s diferente do esperado (%s)", tx.Recipient, p.recipient)
+	}
+	delete(pending, tx.Card)
+	return nil
+}
+
+// checkTransfer decodifica txBytes e confere remetente, pacote e a
+// transferência pendente. Só então a transação pode ser assinada.
+func checkTransfer(txBytes []byte, sender string) (cardTransfer, error) {
+	tx, err := decodeCardTransfer(txBytes)
+	if err != nil {
+		return tx, err
+	}
+	if tx.Sender != normalizeID(sender) {
+		return tx, fmt.Errorf("remetente %s diferente da chave %s", tx.Sender, sender)
+	}
+	if packageID != "" && tx.Package != packageID {
+		return tx, fmt.Errorf("pacote %s diferente do jogo (%s)", tx.Package, packageID)
+	}
+	return tx, takeTransfer(tx)
+}
+
+// --- BCS ---
+
+var errTruncated = errors.New("transação truncada")
+
+type bcsReader struct {
+	buf []byte
+	err error
+}
+
+func (r *bcsReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.buf) {
+		r.err = errTruncated
+		return nil
+	}
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out
+}
+
+func (r *bcsReader) uleb() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 || v > 1<<20 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return int(v)
+}
+
+func (r *bcsReader) u16() int {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint16(b))
+}
+
+func (r *bcsReader) address() string {
+	return "0x" + hex.EncodeToString(r.bytes(32))
+}
+
+func (r *bcsReader) str() string {
+	return string(r.bytes(r.uleb()))
+}
+
+// Entradas da transação programável (CallArg).
+type callArg struct {
+	pure   []byte // Pure(vector<u8>)
+	object string // Object(ImmOrOwnedObject): ID do objeto
+}
+
+// decodeCardTransfer lê uma TransactionData::V1 cuja única instrução é
+// MoveCall <pacote>::core::transfer_card(Input objeto, Input endereço).
+func decodeCardTransfer(txBytes []byte) (cardTransfer, error) {
+	var tx cardTransfer
+	r := &bcsReader{buf: txBytes}
+
+	if r.uleb() != 0 { // TransactionData::V1
+		return tx, errors.New("versão de transação desconhecida")
+	}
+	if r.uleb() != 0 { // TransactionKind::ProgrammableTransaction
+		return tx, errors.New("a transação não é programável")
+	}
+
+	inputs := make([]callArg, r.uleb())
+	for i := range inputs {
+		switch r.uleb() {
+		case 0: // Pure
+			inputs[i].pure = r.bytes(r.uleb())
+		case 1: // Object
+			if r.uleb() != 0 {
+				return tx, errors.New("a carta precisa ser um objeto próprio")
+			}
+			inputs[i].object = r.address()
+			r.bytes(8)        // versão
+			r.bytes(r.uleb()) // digest
+		default:
+			return tx, errors.New("entrada desconhecida na transação")
+		}
+	}
+
+	if r.uleb() != 1 {
+		return tx, errors.New("a transação deve ter uma única instrução")
+	}
+	if r.uleb() != 0 { // Command::MoveCall
+		return tx, errors.New("a instrução não é uma chamada Move")
+	}
+	tx.Package = r.address()
+	module, function := r.str(), r.str()
+	if module != "core" || function != "transfer_card" {
+		return tx, fmt.Errorf("chamada %s::%s não é core::transfer_card", module, function)
+	}
+	if r.uleb() != 0 {
+		return tx, errors.New("transfer_card não recebe tipos genéricos")
+	}
+	if r.uleb() != 2 {
+		return tx, errors.New("transfer_card recebe dois argumentos")
+	}
+	var args [2]callArg
+	for i := range args {
+		if r.uleb() != 1 { // Argument::Input
+			return tx, errors.New("argumento de transfer_card fora das entradas")
+		}
+		idx := r.u16()
+		if r.err != nil || idx >= len(inputs) {
+			return tx, errTruncated
+		}
+		args[i] = inputs[idx]
+	}
+	if args[0].object == "" || len(args[1].pure) != 32 {
+		return tx, errors.New("argumentos de transfer_card inválidos")
+	}
+	tx.Card = args[0].object
+	tx.Recipient = "0x" + hex.EncodeToString(args[1].pure)
+
+	tx.Sender = r.address()
+	if r.err != nil {
+		return tx, r.err
+	}
+	return tx, nil
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	// Alias "API" para usar as funções do pacote definido em src/client/API/pubsub.go
@@ -16,11 +19,44 @@ import (
 
 var conn *nats.Conn
 
+// ID do jogador logado (0 = ninguém), usado para retomar a sessão
+// automaticamente quando o cliente troca de broker.
+var loggedID atomic.Int64
+
+// Comprovantes dos sorteios de pacotes desta sessão, conferidos na
+// opção "Verificar Sorteios" quando a semente da época é revelada.
+var drawReceipts []API.FairDraw
+
 func main() {
-	var htb = time.Now().UnixMilli()
+	// Lista de servidores NATS: flag -servers ou variável NATS_URL.
+	servers := flag.String("servers", os.Getenv("NATS_URL"), "URLs dos servidores NATS, separadas por vírgula")
+
+	// Logs de diagnóstico em stderr: LOG_LEVEL (padrão warn) e LOG_FORMAT (text/json).
+	logLevel := flag.String("log-level", os.Getenv("LOG_LEVEL"), "nível dos logs de diagnóstico: debug, info, warn ou error")
+	logFormat := flag.String("log-format", os.Getenv("LOG_FORMAT"), "formato dos logs de diagnóstico: text ou json")
+
+	// Traces: com OTEL_TRACES_EXPORTER=stdout os spans vão para stderr (o menu usa stdout).
+	traceExporter := flag.String("trace-exporter", os.Getenv("OTEL_TRACES_EXPORTER"), "exportador de traces: stdout ou vazio (só propaga)")
+
+	// Pacote do jogo: pedidos de assinatura para outro pacote são recusados.
+	packageID := flag.String("package-id", os.Getenv("PACKAGE_ID"), "ID do pacote Move do jogo (PACKAGE_ID do worker)")
+	flag.Parse()
+
+	API.SetupLogging(os.Stderr, *logLevel, *logFormat)
+	API.SetPackageID(*packageID)
+
+	shutdownTracing, err := API.SetupTracing(*traceExporter, os.Stderr)
+	if err != nil {
+		fmt.Println("❌ Falha ao configurar traces:", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
+
+	var htb atomic.Int64
+	htb.Store(time.Now().UnixMilli())
 
 	// Conecta ao NATS usando a função da biblioteca API
-	nc := API.BrokerConnect(0)
+	nc := API.BrokerConnect(splitList(*servers), resumeSession)
 	if nc == nil {
 		fmt.Println("❌ Falha ao conectar no NATS. Verifique se o servidor está rodando.")
 		return
@@ -35,11 +71,46 @@ func main() {
 	// Inicia o Menu do Usuário
 	go userMenu(nc)
 
-	// Loop principal para manter o programa vivo enquanto houver conexão
-	for time.Now().UnixMilli()-htb < 5000 {
+	// Loop principal: a falta de heartbeat só gera aviso, pois o cliente
+	// NATS continua tentando reconectar; encerra apenas se a conexão fechar.
+	stale := false
+	for !nc.IsClosed() {
 		time.Sleep(1 * time.Second)
+		silent := time.Now().UnixMilli()-htb.Load() >= 5000
+		if silent && !stale {
+			fmt.Println("\n⚠️ Sem heartbeat do servidor. Aguardando reconexão...")
+		} else if !silent && stale {
+			fmt.Println("\n✅ Servidor voltou a responder.")
+		}
+		stale = silent
+	}
+	fmt.Println("❌ Desconectado do servidor.")
+}
+
+// Refaz o login após uma troca de broker para que o jogador continue
+// de onde parou sem precisar voltar ao menu inicial.
+func resumeSession(nc *nats.Conn) {
+	id := int(loggedID.Load())
+	if id == 0 {
+		return
+	}
+	ok, err := API.RequestLogin(nc, id)
+	if err != nil || !ok {
+		fmt.Println("⚠️ Não foi possível retomar a sessão:", err)
+		return
+	}
+	fmt.Printf("✅ Sessão do jogador %d retomada.\n", id)
+}
+
+// Separa uma lista "a,b,c" ignorando espaços e itens vazios.
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
 	}
-	fmt.Println("❌ Desconectado do servidor (Timeout no Heartbeat).")
+	return out
 }
 
 func userMenu(nc *nats.Conn) {
@@ -59,9 +130,32 @@ func userMenu(nc *nats.Conn) {
 		
 		if id != 0 {
 			// Fase 2: Menu Principal (Logado)
+			loggedID.Store(int64(id))
 			sub := API.LoggedIn(nc, id) // Avisa ao servidor que este cliente está ativo
+			notifications := API.ListenNotifications(nc, id)
 			menuPrincipal(nc, id, reader, cardChan, gameResult, logObj)
+			notifications.Unsubscribe()
 			sub.Unsubscribe()
+			loggedID.Store(0)
+		}
+	}
+}
+
+// Mostra cada etapa da abertura do pacote assim que o servidor avisa.
+func showPackProgress(ev API.PackEvent) {
+	switch ev.Stage {
+	case "paid":
+		fmt.Println("   💳 Pagamento confirmado. Digest:", ev.Digest)
+	case "drawn":
+		if ev.Draw != nil {
+			drawReceipts = append(drawReceipts, *ev.Draw)
+			fmt.Printf("   🎲 Sorteio: época %d, nonce %d, semente do cliente %s → %v\n", ev.Draw.Epoch, ev.Draw.Nonce, ev.Draw.ClientSeed, ev.Draw.Pack)
+		}
+	case "minted":
+		if ev.Err != "" {
+			fmt.Printf("   ⚠️ Carta %d/%d (Força %d) falhou: %s\n", ev.Card, ev.Total, ev.Power, ev.Err)
+		} else {
+			fmt.Printf("   🃏 Carta %d/%d cunhada (Força %d) | ID: %s\n", ev.Card, ev.Total, ev.Power, ev.ObjectID)
 		}
 	}
 }
@@ -103,9 +197,10 @@ func menuInicial(nc *nats.Conn, reader *bufio.Reader) int {
 				return id
 			}
 		case "3":
-			userID := API.RequestCreateAccount(nc)
-			if userID == 0 {
-				fmt.Println("❌ Erro ao criar usuário.")
+			fmt.Println("⏳ Criando carteira na Blockchain...")
+			userID, err := API.RequestCreateAccount(nc)
+			if err != nil {
+				fmt.Println("❌ Erro ao criar usuário:", err)
 			} else {
 				fmt.Printf("✅ Usuário criado! Seu ID é: %d\n", userID)
 				return userID
@@ -127,7 +222,21 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 		fmt.Println("3 - 🎲 Troca Cega (Blind Trade)")
 		fmt.Println("4 - Batalhar (Matchmaking)")
 		fmt.Println("5 - 🔑 Ver Minhas Credenciais (ID/Chaves)")
-		fmt.Println("6 - Logout")
+		fmt.Println("6 - 💰 Ver Saldo")
+		fmt.Println("7 - 🚰 Pedir Tokens de Teste (Faucet)")
+		fmt.Println("8 - 💸 Enviar IOTA para outro Jogador")
+		fmt.Println("9 - 🎁 Presentear Carta")
+		fmt.Println("10 - 🔗 Vincular Carteira Externa")
+		fmt.Println("11 - ✍️  Modo Sem Custódia (assinar localmente)")
+		fmt.Println("12 - 🔍 Verificar Sorteios de Pacotes")
+		fmt.Println("13 - 🃏 Meus Decks")
+		fmt.Println("14 - 🤖 Treino contra o Bot")
+		fmt.Println("15 - ⚔️ Desafiar Jogador")
+		fmt.Println("16 - 📨 Desafios Recebidos / Entrar com Código")
+		fmt.Println("17 - 🏆 Torneios")
+		fmt.Println("18 - 💰 Partida Apostada")
+		fmt.Println("19 - 🎴 Partida Ante (o vencedor leva a carta)")
+		fmt.Println("0 - Logout")
 		fmt.Print("> ")
 
 		opt, _ := reader.ReadString('\n')
@@ -135,8 +244,40 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 
 		switch opt {
 		case "1":
+			// Mostra o saldo antes de cobrar para o jogador decidir.
+			bal, err := API.RequestBalance(nc, id)
+			if err != nil {
+				fmt.Println("❌ Erro ao consultar saldo:", err)
+				continue
+			}
+			fmt.Printf("💰 Saldo: %d IOTA | Preço do pacote: %d IOTA\n", bal.Balance, bal.PackPrice)
+			if bal.Balance < bal.PackPrice {
+				fmt.Println("❌ Saldo insuficiente. Use a opção 7 para pedir tokens de teste.")
+				continue
+			}
+			fmt.Print("Confirmar compra? (s/n): ")
+			confirm, _ := reader.ReadString('\n')
+			if strings.ToLower(strings.TrimSpace(confirm)) != "s" {
+				fmt.Println("Compra cancelada.")
+				continue
+			}
+
+			// A semente do cliente garante que o servidor não escolhe o pacote
+			// sozinho; compromisso e nonce são fixados antes de escolhê-la.
+			var expect *API.FairExpect
+			if current, _, nonce, err := API.RequestFairEpochs(nc, id); err == nil {
+				fmt.Printf("🔒 Compromisso da época %d: %s | seu nonce: %d\n", current.Number, current.Commitment, nonce)
+				expect = &API.FairExpect{Commitment: current.Commitment, Nonce: nonce}
+			}
+			fmt.Print("Semente do sorteio (Enter = aleatória): ")
+			clientSeed, _ := reader.ReadString('\n')
+			clientSeed = strings.TrimSpace(clientSeed)
+			if clientSeed == "" {
+				clientSeed = API.NewClientSeed()
+			}
+
 			fmt.Println("⏳ Processando compra na Blockchain IOTA...")
-			newValues, err := API.RequestOpenPack(nc, id)
+			newValues, err := API.RequestOpenPack(nc, id, clientSeed, expect, showPackProgress)
 			if err != nil {
 				fmt.Println("❌ Erro na compra:", err)
 			} else {
@@ -154,7 +295,11 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 					fmt.Println("Nenhuma carta encontrada.")
 				} else {
 					for i, c := range cards {
-						fmt.Printf("[%d] ID: %s | Força: %d\n", i+1, c.ID, c.Power)
+						fmt.Printf("[%d] ID: %s | Força: %d", i+1, c.ID, c.Power)
+						if len(c.Owner) > 10 {
+							fmt.Printf(" | Carteira: %s…", c.Owner[:10])
+						}
+						fmt.Println()
 					}
 					fmt.Println("-------------------")
 					fmt.Println("Dica: Copie o ID (0x...) para trocar.")
@@ -184,19 +329,17 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 			}
 
 		case "4":
-			fmt.Println("🔍 Buscando partida...")
-			// Atualiza cartas antes de jogar para garantir sincronia
-			cards, _ = API.RequestSeeCards(nc, id)
-			
-			if len(cards) == 0 {
-				fmt.Println("Você precisa de cartas para jogar!")
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
 				continue
 			}
+
+			fmt.Println("🔍 Buscando partida...")
 			game, err := API.RequestFindMatch(nc, id)
 			if err != nil {
 				fmt.Println("❌ Erro no matchmaking:", err)
 			} else {
-				menuJogo(nc, id, cards, reader, cardChan, roundResult, obj, game)
+				menuJogo(nc, id, deckCards, reader, cardChan, roundResult, obj, game, false)
 			}
 
 		case "5":
@@ -208,12 +351,171 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 				fmt.Println("\n--- 🕵️ SEUS DADOS SECRETOS ---")
 				fmt.Printf("🆔 ID de Jogador: %d\n", id)
 				fmt.Printf("gd Endereço (Address): %s\n", creds.Address)
-				fmt.Printf("🔑 Chave Privada (Secret): %s\n", creds.Secret)
-				fmt.Println("⚠️  ATENÇÃO: Não compartilhe sua chave privada!")
+				if creds.SelfCustody {
+					fmt.Println("🔑 Chave Privada (Secret): só com você (modo sem custódia)")
+				} else {
+					fmt.Printf("🔑 Chave Privada (Secret): %s\n", creds.Secret)
+					fmt.Println("⚠️  ATENÇÃO: Não compartilhe sua chave privada!")
+				}
 				fmt.Println("-------------------------------")
 			}
 
 		case "6":
+			bal, err := API.RequestBalance(nc, id)
+			if err != nil {
+				fmt.Println("❌ Erro ao consultar saldo:", err)
+			} else if bal.PackPrice > 0 {
+				fmt.Printf("💰 Saldo: %d IOTA (%d pacotes de %d IOTA)\n", bal.Balance, bal.Balance/bal.PackPrice, bal.PackPrice)
+			} else {
+				fmt.Printf("💰 Saldo: %d IOTA\n", bal.Balance)
+			}
+
+		case "7":
+			fmt.Println("🚰 Pedindo tokens ao Faucet...")
+			balance, err := API.RequestFaucet(nc, id)
+			if err != nil {
+				fmt.Println("❌ Faucet recusado:", err)
+			} else {
+				fmt.Printf("✅ Tokens recebidos! Novo saldo: %d IOTA\n", balance)
+			}
+
+		case "8":
+			menuEnviarTokens(nc, id, reader)
+
+		case "9":
+			fmt.Println("\n--- 🎁 PRESENTEAR CARTA ---")
+			fmt.Print("Cole o ID da carta (Hex): ")
+			cardID, _ := reader.ReadString('\n')
+			cardID = strings.TrimSpace(cardID)
+			fmt.Print("ID do jogador que vai receber: ")
+			text, _ := reader.ReadString('\n')
+			toID, err := strconv.Atoi(strings.TrimSpace(text))
+			if cardID == "" || err != nil || toID <= 0 {
+				fmt.Println("Dados inválidos.")
+				continue
+			}
+
+			fmt.Println("⏳ Validando posse e transferindo...")
+			digest, err := API.RequestGiftCard(nc, id, toID, cardID)
+			if err != nil {
+				fmt.Println("❌ Erro:", err)
+			} else {
+				fmt.Println("✅ Carta enviada! Digest:", digest)
+			}
+
+		case "10":
+			menuVincularCarteira(nc, id, reader)
+
+		case "11":
+			menuCustodia(nc, id, reader)
+
+		case "12":
+			menuVerificarSorteios(nc, id, reader)
+
+		case "13":
+			menuDecks(nc, id, reader)
+
+		case "14":
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
+				continue
+			}
+			fmt.Print("Estratégia do bot (random/greedy/adaptive, Enter = padrão): ")
+			strategy, _ := reader.ReadString('\n')
+
+			game, err := API.RequestPractice(nc, id, strings.TrimSpace(strategy))
+			if err != nil {
+				fmt.Println("❌ Erro ao criar treino:", err)
+			} else {
+				menuJogo(nc, id, deckCards, reader, cardChan, roundResult, obj, game, true)
+			}
+
+		case "15":
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
+				continue
+			}
+			fmt.Print("ID do jogador a desafiar (Enter = gerar código de sala): ")
+			raw, _ := reader.ReadString('\n')
+			target := 0
+			if raw = strings.TrimSpace(raw); raw != "" {
+				if target, err = strconv.Atoi(raw); err != nil {
+					fmt.Println("❌ ID inválido.")
+					continue
+				}
+			}
+
+			game, err := API.RequestChallenge(nc, id, target, func(c API.Challenge) {
+				if c.To == 0 {
+					fmt.Printf("🔑 Código da sala: %s (compartilhe com seu amigo)\n", c.Code)
+				} else {
+					fmt.Printf("📨 Desafio %s enviado ao jogador %d.\n", c.Code, c.To)
+				}
+				fmt.Println("⏳ Aguardando resposta...")
+			})
+			if err != nil {
+				fmt.Println("❌", err)
+			} else {
+				menuJogo(nc, id, deckCards, reader, cardChan, roundResult, obj, game, false)
+			}
+
+		case "16":
+			menuDesafios(nc, id, reader, cardChan, roundResult, obj)
+
+		case "17":
+			menuTorneios(nc, id, reader, cardChan, roundResult, obj)
+
+		case "18":
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
+				continue
+			}
+			fmt.Print("Valor da aposta (IOTA): ")
+			raw, _ := reader.ReadString('\n')
+			stake, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+			if err != nil || stake == 0 {
+				fmt.Println("❌ Valor inválido.")
+				continue
+			}
+
+			game, err := API.RequestFindWagerMatch(nc, id, stake, func(rake uint64) {
+				fmt.Printf("🔍 Buscando adversário que aposte %d IOTA (taxa da casa: %d%% do pote)...\n", stake, rake)
+			})
+			if err != nil {
+				fmt.Println("❌ Erro no matchmaking:", err)
+			} else {
+				fmt.Println("🔒 Apostas retidas pela loja até o fim da partida.")
+				menuJogo(nc, id, deckCards, reader, cardChan, roundResult, obj, game, false)
+			}
+
+		case "19":
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
+				continue
+			}
+			fmt.Println("Qual carta você vai apostar? Se perder, ela vai para o adversário.")
+			for i, c := range deckCards {
+				fmt.Printf("[%d] ID: %s | Força: %d\n", i+1, c.ID, c.Power)
+			}
+			fmt.Print("> ")
+			raw, _ := reader.ReadString('\n')
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || n < 1 || n > len(deckCards) {
+				fmt.Println("❌ Opção inválida.")
+				continue
+			}
+			ante := deckCards[n-1]
+
+			fmt.Println("🔍 Buscando partida ante...")
+			game, err := API.RequestFindAnteMatch(nc, id, ante.ID)
+			if err != nil {
+				fmt.Println("❌ Erro no matchmaking:", err)
+			} else {
+				// Só a carta apostada pode ser jogada.
+				menuJogo(nc, id, []API.CardDisplay{ante}, reader, cardChan, roundResult, obj, game, false)
+			}
+
+		case "0":
 			return // Sai do loop e volta pro Menu Inicial
 
 		default:
@@ -222,8 +524,14 @@ func menuPrincipal(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan in
 	}
 }
 
-func menuJogo(nc *nats.Conn, id int, cards []API.CardDisplay, reader *bufio.Reader, cardChan chan int, gameResult chan string, logObj chan string, game string) {
-	fmt.Println("\n⚔️ PARTIDA ENCONTRADA! ⚔️")
+// Joga uma partida com as cartas do deck. Partidas de treino (practice)
+// não são registradas na blockchain.
+func menuJogo(nc *nats.Conn, id int, cards []API.CardDisplay, reader *bufio.Reader, cardChan chan int, gameResult chan string, logObj chan string, game string, practice bool) {
+	if practice {
+		fmt.Println("\n🤖 TREINO CONTRA O BOT (não ranqueado) 🤖")
+	} else {
+		fmt.Println("\n⚔️ PARTIDA ENCONTRADA! ⚔️")
+	}
 	fmt.Print("Suas cartas (Força): ")
 	for _, c := range cards {
 		fmt.Printf("[%d] ", c.Power)
@@ -263,16 +571,565 @@ func menuJogo(nc *nats.Conn, id int, cards []API.CardDisplay, reader *bufio.Read
 	matchObj := <-logObj
 
 	fmt.Printf("\nOponente jogou força: %d\n", opCard)
-	
+
+	registro := "(Registrado na Blockchain)"
+	if practice {
+		registro = "(Treino, sem registro)"
+	}
 	switch result {
 	case "win":
-		fmt.Println("🏆 VITÓRIA! (Registrado na Blockchain)")
+		fmt.Println("🏆 VITÓRIA!", registro)
 	case "lose":
-		fmt.Println("💀 DERROTA. (Registrado na Blockchain)")
+		fmt.Println("💀 DERROTA.", registro)
 	case "draw":
 		fmt.Println("🤝 EMPATE.")
 	default:
 		fmt.Println("⚠️ Erro na partida.")
 	}
-	fmt.Println("🆔 ID da partida:", matchObj)
-}
\ No newline at end of file
+	if !practice {
+		fmt.Println("🆔 ID da partida:", matchObj)
+	}
+}
+
+// Lista os desafios recebidos e permite aceitar, recusar ou entrar em
+// uma sala pelo código.
+func menuDesafios(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan int, gameResult chan string, logObj chan string) {
+	_, received, err := API.RequestChallenges(nc, id)
+	if err != nil {
+		fmt.Println("❌ Erro ao consultar desafios:", err)
+		return
+	}
+
+	if len(received) == 0 {
+		fmt.Println("Nenhum desafio recebido.")
+	}
+	for _, c := range received {
+		fmt.Printf("  %s - jogador %d (expira em %s)\n", c.Code, c.From, time.Until(c.Expires).Round(time.Second))
+	}
+
+	fmt.Print("Código do desafio ou da sala (Enter = voltar): ")
+	code, _ := reader.ReadString('\n')
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return
+	}
+	fmt.Print("Aceitar? (s/n): ")
+	answer, _ := reader.ReadString('\n')
+
+	if strings.ToLower(strings.TrimSpace(answer)) != "s" {
+		if err := API.RequestDeclineChallenge(nc, id, code); err != nil {
+			fmt.Println("❌", err)
+		} else {
+			fmt.Println("Desafio recusado.")
+		}
+		return
+	}
+
+	deckCards, ok := cartasDoDeck(nc, id)
+	if !ok {
+		return
+	}
+	game, err := API.RequestAcceptChallenge(nc, id, code)
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	menuJogo(nc, id, deckCards, reader, cardChan, gameResult, logObj, game, false)
+}
+
+// Lista os torneios e permite criar, se inscrever, iniciar, ver a chave
+// e jogar a partida pendente da rodada.
+func menuTorneios(nc *nats.Conn, id int, reader *bufio.Reader, cardChan chan int, gameResult chan string, logObj chan string) {
+	for {
+		tournaments, err := API.RequestTournaments(nc, id)
+		if err != nil {
+			fmt.Println("❌ Erro ao consultar torneios:", err)
+			return
+		}
+
+		fmt.Println("\n--- 🏆 TORNEIOS ---")
+		if len(tournaments) == 0 {
+			fmt.Println("Nenhum torneio.")
+		}
+		for i, t := range tournaments {
+			fmt.Printf("[%d] %s (%s) | %s | %d/%d jogadores | Inscrição: %d IOTA\n", i+1, t.Name, t.Format, t.Status, len(t.Players), t.MaxPlayers, t.EntryFee)
+		}
+		fmt.Println("1 - Criar | 2 - Inscrever-se | 3 - Iniciar | 4 - Ver chave | 5 - Jogar minha partida | 0 - Voltar")
+		fmt.Print("Escolha: ")
+		opt, _ := reader.ReadString('\n')
+		opt = strings.TrimSpace(opt)
+
+		if opt == "0" {
+			return
+		}
+		if opt == "1" {
+			criarTorneio(nc, id, reader)
+			continue
+		}
+
+		var t API.Tournament
+		switch opt {
+		case "2", "3", "4", "5":
+			fmt.Print("Número do torneio: ")
+			raw, _ := reader.ReadString('\n')
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || n < 1 || n > len(tournaments) {
+				fmt.Println("Torneio inválido.")
+				continue
+			}
+			t = tournaments[n-1]
+		default:
+			fmt.Println("Opção inválida.")
+			continue
+		}
+
+		switch opt {
+		case "2":
+			fmt.Printf("⏳ Pagando inscrição de %d IOTA...\n", t.EntryFee)
+			if _, err := API.RequestJoinTournament(nc, id, t.ID); err != nil {
+				fmt.Println("❌", err)
+			} else {
+				fmt.Println("✅ Inscrição confirmada!")
+			}
+		case "3":
+			if _, err := API.RequestStartTournament(nc, id, t.ID); err != nil {
+				fmt.Println("❌", err)
+			} else {
+				fmt.Println("✅ Torneio iniciado! Aguarde o aviso da sua partida.")
+			}
+		case "4":
+			mostrarChave(t)
+		case "5":
+			game, round, ok := t.PendingMatch(id)
+			if !ok {
+				fmt.Println("Você não tem partida pendente neste torneio.")
+				continue
+			}
+			deckCards, ok := cartasDoDeck(nc, id)
+			if !ok {
+				continue
+			}
+			fmt.Printf("🏆 Rodada %d de %s\n", round, t.Name)
+			menuJogo(nc, id, deckCards, reader, cardChan, gameResult, logObj, game, false)
+		}
+	}
+}
+
+func criarTorneio(nc *nats.Conn, id int, reader *bufio.Reader) {
+	fmt.Print("Nome do torneio: ")
+	name, _ := reader.ReadString('\n')
+	fmt.Print("Formato (elimination/swiss, Enter = elimination): ")
+	format, _ := reader.ReadString('\n')
+	fmt.Print("Vagas: ")
+	rawMax, _ := reader.ReadString('\n')
+	fmt.Print("Taxa de inscrição (IOTA): ")
+	rawFee, _ := reader.ReadString('\n')
+
+	maxPlayers, err1 := strconv.Atoi(strings.TrimSpace(rawMax))
+	fee, err2 := strconv.ParseUint(strings.TrimSpace(rawFee), 10, 64)
+	if err1 != nil || err2 != nil {
+		fmt.Println("Entrada inválida.")
+		return
+	}
+
+	fmt.Println("⏳ Criando torneio e pagando sua inscrição...")
+	t, err := API.RequestCreateTournament(nc, id, strings.TrimSpace(name), strings.TrimSpace(format), fee, maxPlayers)
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	fmt.Printf("✅ Torneio %q criado! Compartilhe o nome para os amigos se inscreverem.\n", t.Name)
+}
+
+// Mostra as rodadas do torneio e, se encerrado, a classificação.
+func mostrarChave(t API.Tournament) {
+	fmt.Printf("\n--- %s (%s, %s) ---\n", t.Name, t.Format, t.Status)
+	fmt.Println("Inscritos:", t.Players)
+	for _, r := range t.Rounds {
+		fmt.Printf("Rodada %d:\n", r.Number)
+		for _, p := range r.Pairings {
+			switch {
+			case p.P2 == 0:
+				fmt.Printf("  Jogador %d folga\n", p.P1)
+			case p.Winner == 0:
+				fmt.Printf("  %d x %d (em andamento)\n", p.P1, p.P2)
+			default:
+				fmt.Printf("  %d x %d → vencedor %d\n", p.P1, p.P2, p.Winner)
+			}
+		}
+	}
+	for _, st := range t.Standings {
+		fmt.Printf("%dº jogador %d (%d vitórias)", st.Place, st.PlayerID, st.Wins)
+		if st.Prize > 0 {
+			fmt.Printf(" | Prêmio: %d IOTA", st.Prize)
+		}
+		if st.Err != "" {
+			fmt.Printf(" | ⚠️ pagamento pendente: %s", st.Err)
+		}
+		fmt.Println()
+	}
+	if t.ObjectID != "" {
+		fmt.Println("🔗 Registro on-chain:", t.ObjectID)
+	}
+}
+
+// Cartas do deck ativo, as únicas que podem ser jogadas em partidas.
+func cartasDoDeck(nc *nats.Conn, id int) ([]API.CardDisplay, bool) {
+	decks, _, err := API.RequestDecks(nc, id)
+	if err != nil {
+		fmt.Println("❌ Erro ao consultar decks:", err)
+		return nil, false
+	}
+	deck, ok := API.ActiveDeck(decks)
+	if !ok {
+		fmt.Println("Você precisa de um deck ativo para jogar! Use a opção 13.")
+		return nil, false
+	}
+	if !deck.Valid {
+		fmt.Printf("❌ O deck %q não é mais válido: %s\n", deck.Name, deck.Err)
+		return nil, false
+	}
+
+	cards := make([]API.CardDisplay, 0, len(deck.Cards))
+	for _, c := range deck.Cards {
+		cards = append(cards, API.CardDisplay{ID: c.ID, Power: c.Power})
+	}
+	fmt.Printf("🃏 Deck ativo: %s\n", deck.Name)
+	return cards, true
+}
+
+// Fluxo de envio de IOTA: coleta destino e valor, mostra o saldo
+// e só envia após confirmação explícita do jogador.
+func menuEnviarTokens(nc *nats.Conn, id int, reader *bufio.Reader) {
+	fmt.Println("\n--- 💸 ENVIAR IOTA ---")
+	fmt.Print("ID do jogador ou endereço (0x...): ")
+	dest, _ := reader.ReadString('\n')
+	dest = strings.TrimSpace(dest)
+
+	toID, toAddress := 0, ""
+	if strings.HasPrefix(dest, "0x") {
+		toAddress = dest
+	} else if n, err := strconv.Atoi(dest); err == nil && n > 0 {
+		toID = n
+	} else {
+		fmt.Println("Destino inválido.")
+		return
+	}
+
+	fmt.Print("Valor (IOTA): ")
+	text, _ := reader.ReadString('\n')
+	amount, err := strconv.ParseUint(strings.TrimSpace(text), 10, 64)
+	if err != nil || amount == 0 {
+		fmt.Println("Valor inválido.")
+		return
+	}
+
+	if bal, err := API.RequestBalance(nc, id); err == nil {
+		fmt.Printf("💰 Saldo atual: %d IOTA\n", bal.Balance)
+	}
+	fmt.Printf("Enviar %d IOTA para %s? (s/n): ", amount, dest)
+	confirm, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(confirm)) != "s" {
+		fmt.Println("Envio cancelado.")
+		return
+	}
+
+	fmt.Println("⏳ Enviando transação...")
+	digest, err := API.RequestSendTokens(nc, id, toID, toAddress, amount)
+	if err != nil {
+		fmt.Println("❌ Falha no envio:", err)
+		return
+	}
+	fmt.Println("✅ Enviado! Digest:", digest)
+}
+
+// Vincula uma carteira IOTA externa: a chave privada é lida localmente
+// (variável IOTA_PRIVATE_KEY ou digitada) e usada só para assinar o
+// desafio; apenas a assinatura é enviada ao servidor.
+func menuVincularCarteira(nc *nats.Conn, id int, reader *bufio.Reader) {
+	fmt.Println("\n--- 🔗 VINCULAR CARTEIRA EXTERNA ---")
+	key := os.Getenv("IOTA_PRIVATE_KEY")
+	if key == "" {
+		fmt.Print("Chave privada (iotaprivkey1... ou base64 do keystore): ")
+		key, _ = reader.ReadString('\n')
+	}
+
+	signer, err := API.ParsePrivateKey(key)
+	if err != nil {
+		fmt.Println("❌ Chave inválida:", err)
+		return
+	}
+	address := signer.Address()
+	fmt.Println("📬 Endereço:", address)
+	API.RegisterSigner(signer) // Assina transferências das cartas desta carteira
+
+	challenge, err := API.RequestLinkChallenge(nc, id, address)
+	if err != nil {
+		fmt.Println("❌ Erro:", err)
+		return
+	}
+
+	linked, err := API.RequestLinkWallet(nc, id, signer.SignPersonalMessage([]byte(challenge)))
+	if err != nil {
+		fmt.Println("❌ Vínculo recusado:", err)
+		return
+	}
+	fmt.Println("✅ Carteira vinculada:", linked)
+	fmt.Println("   As cartas dela agora aparecem em \"Ver Minhas Cartas\" e podem ser usadas em partidas.")
+}
+
+// Liga/desliga o modo sem custódia. Ao ligar, o servidor entrega a chave
+// da carteira principal e a apaga: dali em diante só este cliente assina
+// as transferências de cartas, e as cobranças feitas pelo servidor
+// (pacotes, inscrições, apostas) ficam bloqueadas. Para desligar, a
+// chave é devolvida ao servidor.
+func menuCustodia(nc *nats.Conn, id int, reader *bufio.Reader) {
+	fmt.Print("Ativar assinatura local? (s/n): ")
+	text, _ := reader.ReadString('\n')
+	enable := strings.ToLower(strings.TrimSpace(text)) == "s"
+
+	if !enable {
+		key := lerChavePrincipal(reader)
+		if _, err := API.RequestSetCustody(nc, id, false, key); err != nil {
+			fmt.Println("❌ Erro:", err)
+			return
+		}
+		fmt.Println("✅ O servidor voltou a assinar as transferências da carteira principal.")
+		return
+	}
+
+	key, err := API.RequestSetCustody(nc, id, true, "")
+	if err != nil {
+		fmt.Println("❌ Erro:", err)
+		return
+	}
+	if key != "" {
+		fmt.Println("🔑 Chave da carteira principal (o servidor não guarda mais cópia, anote-a):")
+		fmt.Println("   ", key)
+		fmt.Println("   Nas próximas sessões, informe-a nesta opção (ou em IOTA_MAIN_KEY) para assinar as transferências.")
+	} else {
+		// O modo já estava ativo: a chave só existe com o jogador.
+		key = lerChavePrincipal(reader)
+	}
+
+	signer, err := API.ParsePrivateKey(key)
+	if err != nil {
+		fmt.Println("❌ Chave inválida:", err)
+		return
+	}
+	creds, err := API.RequestCredentials(nc, id)
+	if err != nil {
+		fmt.Println("❌ Erro ao obter credenciais:", err)
+		return
+	}
+	if signer.Address() != creds.Address {
+		fmt.Println("❌ A chave não é da carteira principal", creds.Address)
+		return
+	}
+	API.RegisterSigner(signer)
+
+	fmt.Println("✅ Modo sem custódia ativo: presentes serão assinados neste cliente.")
+	fmt.Println("   Mantenha o cliente aberto para responder aos pedidos de assinatura (a troca cega só aceita cartas sob custódia do servidor).")
+}
+
+// Lê a chave da carteira principal (variável IOTA_MAIN_KEY ou digitada).
+func lerChavePrincipal(reader *bufio.Reader) string {
+	if key := os.Getenv("IOTA_MAIN_KEY"); key != "" {
+		return key
+	}
+	fmt.Print("Chave privada da carteira principal: ")
+	key, _ := reader.ReadString('\n')
+	return strings.TrimSpace(key)
+}
+
+// Confere os sorteios desta sessão (ou um comprovante digitado) com as
+// sementes já reveladas pelo servidor.
+func menuVerificarSorteios(nc *nats.Conn, id int, reader *bufio.Reader) {
+	current, revealed, _, err := API.RequestFairEpochs(nc, id)
+	if err != nil {
+		fmt.Println("❌ Erro ao consultar épocas:", err)
+		return
+	}
+	fmt.Printf("\n--- 🔍 SORTEIOS VERIFICÁVEIS ---\nÉpoca atual: %d (%d sorteios) | Compromisso: %s\n", current.Number, current.Draws, current.Commitment)
+
+	epochs := map[int]API.FairEpoch{}
+	for _, e := range revealed {
+		epochs[e.Number] = e
+	}
+
+	receipts := drawReceipts
+	if len(receipts) == 0 {
+		fmt.Println("Nenhum sorteio nesta sessão. Informe um comprovante.")
+		draw, ok := lerComprovante(id, reader)
+		if !ok {
+			return
+		}
+		receipts = []API.FairDraw{draw}
+	}
+
+	for _, d := range receipts {
+		fmt.Printf("Época %d, nonce %d, semente %s, pacote %v: ", d.Epoch, d.Nonce, d.ClientSeed, d.Pack)
+		epoch, ok := epochs[d.Epoch]
+		if !ok {
+			fmt.Println("⏳ semente ainda não revelada")
+			continue
+		}
+		if err := API.VerifyDraw(epoch, d); err != nil {
+			fmt.Println("❌", err)
+			continue
+		}
+		fmt.Println("✅ confere")
+	}
+}
+
+// Lê época, nonce, semente do cliente e as três forças de um pacote
+// sorteado para o jogador id.
+func lerComprovante(id int, reader *bufio.Reader) (API.FairDraw, bool) {
+	d := API.FairDraw{PlayerID: id}
+	fmt.Print("Época e nonce (ex.: 3 17): ")
+	line, _ := reader.ReadString('\n')
+	if _, err := fmt.Sscan(line, &d.Epoch, &d.Nonce); err != nil {
+		fmt.Println("Entrada inválida.")
+		return d, false
+	}
+	fmt.Print("Semente do cliente: ")
+	seed, _ := reader.ReadString('\n')
+	d.ClientSeed = strings.TrimSpace(seed)
+	fmt.Print("Forças recebidas (ex.: 12 480 77): ")
+	line, _ = reader.ReadString('\n')
+	if _, err := fmt.Sscan(line, &d.Pack[0], &d.Pack[1], &d.Pack[2]); err != nil {
+		fmt.Println("Entrada inválida.")
+		return d, false
+	}
+	return d, true
+}
+
+// Gerencia os decks do jogador: criar, trocar cartas, renomear, apagar
+// e escolher o deck ativo usado nas partidas.
+func menuDecks(nc *nats.Conn, id int, reader *bufio.Reader) {
+	for {
+		decks, rules, err := API.RequestDecks(nc, id)
+		if err != nil {
+			fmt.Println("❌ Erro ao consultar decks:", err)
+			return
+		}
+
+		fmt.Printf("\n--- 🃏 MEUS DECKS (%d cartas", rules.Size)
+		if rules.PowerCap > 0 {
+			fmt.Printf(", força total até %d", rules.PowerCap)
+		}
+		fmt.Println(") ---")
+		if len(decks) == 0 {
+			fmt.Println("Nenhum deck montado.")
+		}
+		for i, d := range decks {
+			mark := " "
+			if d.Active {
+				mark = "*"
+			}
+			powers := make([]int, 0, len(d.Cards))
+			for _, c := range d.Cards {
+				powers = append(powers, c.Power)
+			}
+			fmt.Printf("[%d]%s %s | Forças: %v | Total: %d", i+1, mark, d.Name, powers, d.TotalPower)
+			if !d.Valid {
+				fmt.Printf(" | ❌ %s", d.Err)
+			}
+			fmt.Println()
+		}
+
+		fmt.Println("1 - Criar | 2 - Trocar cartas | 3 - Renomear | 4 - Apagar | 5 - Ativar | 0 - Voltar")
+		fmt.Print("> ")
+		opt, _ := reader.ReadString('\n')
+		opt = strings.TrimSpace(opt)
+
+		if opt == "0" {
+			return
+		}
+		if opt == "1" {
+			fmt.Print("Nome do deck: ")
+			name, _ := reader.ReadString('\n')
+			cards, ok := escolherCartas(nc, id, reader, rules.Size)
+			if !ok {
+				continue
+			}
+			if err := API.RequestSaveDeck(nc, id, strings.TrimSpace(name), cards, true); err != nil {
+				fmt.Println("❌ Erro:", err)
+			} else {
+				fmt.Println("✅ Deck criado.")
+			}
+			continue
+		}
+
+		switch opt {
+		case "2", "3", "4", "5":
+		default:
+			fmt.Println("Opção inválida.")
+			continue
+		}
+		fmt.Print("Número do deck: ")
+		text, _ := reader.ReadString('\n')
+		n, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || n < 1 || n > len(decks) {
+			fmt.Println("Deck inválido.")
+			continue
+		}
+		deck := decks[n-1]
+		ids := make([]string, 0, len(deck.Cards))
+		for _, c := range deck.Cards {
+			ids = append(ids, c.ID)
+		}
+
+		switch opt {
+		case "2":
+			cards, ok := escolherCartas(nc, id, reader, rules.Size)
+			if !ok {
+				continue
+			}
+			err = API.RequestSaveDeck(nc, id, deck.Name, cards, false)
+		case "3":
+			fmt.Print("Novo nome: ")
+			name, _ := reader.ReadString('\n')
+			err = API.RequestRenameDeck(nc, id, deck.Name, strings.TrimSpace(name), ids)
+		case "4":
+			err = API.RequestDeleteDeck(nc, id, deck.Name)
+		case "5":
+			err = API.RequestSelectDeck(nc, id, deck.Name)
+		}
+		if err != nil {
+			fmt.Println("❌ Erro:", err)
+		} else {
+			fmt.Println("✅ Pronto.")
+		}
+	}
+}
+
+// Lista as cartas on-chain do jogador e lê os números das escolhidas.
+func escolherCartas(nc *nats.Conn, id int, reader *bufio.Reader, size int) ([]string, bool) {
+	cards, err := API.RequestSeeCards(nc, id)
+	if err != nil {
+		fmt.Println("❌ Erro ao buscar cartas:", err)
+		return nil, false
+	}
+	if len(cards) < size {
+		fmt.Printf("Você precisa de pelo menos %d cartas para montar um deck.\n", size)
+		return nil, false
+	}
+	for i, c := range cards {
+		fmt.Printf("[%d] Força: %d | ID: %s\n", i+1, c.Power, c.ID)
+	}
+
+	fmt.Printf("Escolha %d cartas (ex.: 1 4 7): ", size)
+	line, _ := reader.ReadString('\n')
+	var chosen []string
+	for _, field := range strings.Fields(line) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(cards) {
+			fmt.Println("Carta inválida:", field)
+			return nil, false
+		}
+		chosen = append(chosen, cards[n-1].ID)
+	}
+	return chosen, true
+}
+
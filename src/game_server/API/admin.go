@@ -0,0 +1,434 @@
+package API
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- COMANDOS ADMINISTRATIVOS ---
+
+// Requisições admin.* não carregam o token: cada uma é assinada com
+// HMAC-SHA256(ADMIN_TOKEN, "<tópico>\n<timestamp>\n<nonce>\n<corpo>"),
+// enviado nos cabeçalhos Admin-Timestamp, Admin-Nonce e Admin-Signature.
+// Sem ADMIN_TOKEN configurado, o plano administrativo fica desligado.
+type adminRequest struct {
+	ID     int    `json:"id"`
+	GameID string `json:"game_id"`
+	Banned bool   `json:"banned"`
+	Packs  int    `json:"packs"`
+
+	Tournament string `json:"tournament"`
+}
+
+type adminCommand func(ctx context.Context, s *Store, req adminRequest) (any, error)
+
+// Comandos disponíveis, pelo sufixo do tópico (admin.<comando>).
+var adminCommands = map[string]adminCommand{
+	"players": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.AdminPlayers(), nil
+	},
+	"player": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.AdminPlayer(ctx, req.ID)
+	},
+	"queues": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.AdminQueues(), nil
+	},
+	"cancelMatch": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return map[string]any{"cancelled": req.GameID}, s.CancelMatch(req.GameID)
+	},
+	"ban": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return map[string]any{"id": req.ID, "banned": req.Banned}, s.BanPlayer(req.ID, req.Banned)
+	},
+	"refillPacks": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		total, err := s.RefillPacks(req.Packs)
+		return map[string]any{"packs_available": total}, err
+	},
+	"reconcile": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.Reconcile(ctx), nil
+	},
+	"rotateSeed": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.RotateSeed(), nil
+	},
+	"tournaments": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.Tournaments(), nil
+	},
+	"cancelTournament": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		return s.CancelTournament(ctx, req.Tournament)
+	},
+	"repairWallets": func(ctx context.Context, s *Store, req adminRequest) (any, error) {
+		repaired, failed := s.RepairWallets(ctx)
+		return map[string]any{"repaired": repaired, "failed": errorStrings(failed)}, nil
+	},
+}
+
+// Cabeçalhos da assinatura e janela em que uma requisição assinada é
+// aceita. Nonces vistos dentro da janela são recusados (replay).
+const (
+	AdminTimestampHeader = "Admin-Timestamp"
+	AdminNonceHeader     = "Admin-Nonce"
+	AdminSignatureHeader = "Admin-Signature"
+
+	adminSignatureWindow = 30 * time.Second
+)
+
+// AdminSignature calcula a assinatura de uma requisição admin.*; a CLI
+// administrativa usa a mesma função.
+func AdminSignature(token, subject, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", subject, timestamp, nonce)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// adminAuth confere assinatura, prazo e nonce das requisições admin.*.
+type adminAuth struct {
+	token string
+	now   func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newAdminAuth(token string) *adminAuth {
+	return &adminAuth{token: token, now: time.Now, seen: map[string]time.Time{}}
+}
+
+func (a *adminAuth) verify(m *nats.Msg) error {
+	if a.token == "" || m.Header == nil {
+		return fmt.Errorf("unauthorized")
+	}
+	ts, nonce := m.Header.Get(AdminTimestampHeader), m.Header.Get(AdminNonceHeader)
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || nonce == "" {
+		return fmt.Errorf("unauthorized")
+	}
+	want := AdminSignature(a.token, m.Subject, ts, nonce, m.Data)
+	if !hmac.Equal([]byte(m.Header.Get(AdminSignatureHeader)), []byte(want)) {
+		return fmt.Errorf("unauthorized")
+	}
+
+	now := a.now()
+	if age := now.Sub(time.Unix(sent, 0)); age > adminSignatureWindow || age < -adminSignatureWindow {
+		return fmt.Errorf("unauthorized: request expired")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for n, at := range a.seen {
+		if now.Sub(at) > 2*adminSignatureWindow {
+			delete(a.seen, n)
+		}
+	}
+	if _, replay := a.seen[nonce]; replay {
+		return fmt.Errorf("unauthorized: nonce already used")
+	}
+	a.seen[nonce] = now
+	return nil
+}
+
+// AdminCommands atende admin.* (ex.: admin.players, admin.ban) após
+// validar a assinatura. Uso: go run ./cmd/admin players
+func AdminCommands(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		slog.Warn("admin commands disabled: ADMIN_TOKEN not set")
+	}
+	auth := newAdminAuth(token)
+
+	return serveCommands(nc, s, "admin.", adminCommands, func(m *nats.Msg, req adminRequest) error {
+		if err := auth.verify(m); err != nil {
+			slog.Warn("admin access denied", logSubject, m.Subject, "err", err)
+			return err
+		}
+		slog.Info("admin command", logSubject, m.Subject)
+		return nil
+	})
+}
+
+func errorStrings(errs map[int]error) map[int]string {
+	out := make(map[int]string, len(errs))
+	for id, err := range errs {
+		out[id] = err.Error()
+	}
+	return out
+}
+
+// --- CONSULTAS ---
+
+// Resumo de um jogador para o painel administrativo (sem o segredo da carteira).
+type AdminPlayerInfo struct {
+	ID            int            `json:"id"`
+	Address       string         `json:"address"`
+	LinkedWallets []string       `json:"linked_wallets,omitempty"`
+	SelfCustody   bool           `json:"self_custody"`
+	Banned        bool           `json:"banned"`
+	Cards         map[string]int `json:"cards"`
+	Balance       *uint64        `json:"balance,omitempty"`
+	BalanceErr    string         `json:"balance_err,omitempty"`
+}
+
+func playerInfo(p Player) AdminPlayerInfo {
+	cards := make(map[string]int, len(p.Cards))
+	for k, v := range p.Cards {
+		cards[k] = v
+	}
+	return AdminPlayerInfo{
+		ID:            p.Id,
+		Address:       p.Wallet.Address,
+		LinkedWallets: append([]string(nil), p.LinkedWallets...),
+		SelfCustody:   p.SelfCustody,
+		Banned:        p.Banned,
+		Cards:         cards,
+	}
+}
+
+// AdminPlayers lista todos os jogadores, ordenados por ID.
+func (s *Store) AdminPlayers() []AdminPlayerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]AdminPlayerInfo, 0, len(s.players))
+	for _, p := range s.players {
+		out = append(out, playerInfo(p))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// AdminPlayer detalha um jogador, incluindo o saldo on-chain.
+func (s *Store) AdminPlayer(ctx context.Context, id int) (AdminPlayerInfo, error) {
+	s.mu.Lock()
+	p, exists := s.players[id]
+	info := playerInfo(p)
+	s.mu.Unlock()
+
+	if !exists {
+		return AdminPlayerInfo{}, fmt.Errorf("player not found")
+	}
+
+	if balance, err := s.bridge.Balance(ctx, p.Wallet); err != nil {
+		info.BalanceErr = err.Error()
+	} else {
+		info.Balance = &balance
+	}
+	return info, nil
+}
+
+// Filas e partidas em andamento.
+type AdminQueuesInfo struct {
+	GameQueue      []int         `json:"game_queue"`
+	WagerQueue     []wagerEntry  `json:"wager_queue"`
+	AnteQueue      []anteEntry   `json:"ante_queue"`
+	BlindTrade     []int         `json:"blind_trade"`
+	ActiveMatches  []matchStruct `json:"active_matches"`
+	PacksAvailable int           `json:"packs_available"`
+	Journal        []string      `json:"journal,omitempty"`
+}
+
+// AdminQueues retorna as filas, as partidas ainda sem resultado e as
+// operações pendentes (journal).
+func (s *Store) AdminQueues() AdminQueuesInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := AdminQueuesInfo{
+		GameQueue:      append([]int{}, s.gameQueue...),
+		WagerQueue:     append([]wagerEntry{}, s.wagerQueue...),
+		AnteQueue:      append([]anteEntry{}, s.anteQueue...),
+		BlindTrade:     []int{},
+		ActiveMatches:  []matchStruct{},
+		PacksAvailable: s.packs,
+		Journal:        s.journalLocked(),
+	}
+	for _, r := range s.BlindTradeQueue {
+		info.BlindTrade = append(info.BlindTrade, r.PlayerID)
+	}
+	for _, m := range s.matchHistory {
+		if m.Card1 == 0 || m.Card2 == 0 {
+			info.ActiveMatches = append(info.ActiveMatches, m)
+		}
+	}
+	return info
+}
+
+// --- AÇÕES ---
+
+// CancelMatch encerra uma partida que ainda não foi resolvida e avisa
+// os dois jogadores pelo tópico de resultados.
+func (s *Store) CancelMatch(gameID string) error {
+	s.mu.Lock()
+	game, exists := s.matchHistory[gameID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("game not found")
+	}
+	if game.Card1 != 0 && game.Card2 != 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("partida já resolvida")
+	}
+	// Descartar a partida deixaria o confronto do torneio sem vencedor e
+	// a rodada travada: torneios são encerrados por cancelTournament.
+	if game.Tournament != "" {
+		s.mu.Unlock()
+		return fmt.Errorf("partida do torneio %s: use cancelTournament", game.Tournament)
+	}
+	delete(s.matchHistory, gameID)
+	if game.Ante {
+		s.releaseAnteLocked(game)
+	}
+	var refund func()
+	if game.Wager > 0 {
+		refund = s.trackWorkLocked(fmt.Sprintf("wagerRefund game=%s", gameID))
+	}
+	s.mu.Unlock()
+
+	slog.Info("match cancelled", logGame, gameID, "p1", game.P1, "p2", game.P2)
+	if refund != nil {
+		go func() {
+			defer refund()
+			s.refundStakes(context.Background(), gameID, game.Wager, game.P1, game.P2)
+		}()
+	}
+	for _, id := range []int{game.P1, game.P2} {
+		if id == botID {
+			continue
+		}
+		resp := map[string]any{"client_id": id, "game": gameID, "err": "partida cancelada pelo administrador"}
+		data, _ := json.Marshal(resp)
+		s.pub.Publish("game.server", data)
+	}
+	return nil
+}
+
+// BanPlayer bane (ou reabilita) um jogador. O banido sai das filas e
+// quem estava aguardando troca é avisado.
+func (s *Store) BanPlayer(id int, banned bool) error {
+	s.mu.Lock()
+	p, exists := s.players[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("player not found")
+	}
+	p.Banned = banned
+	s.players[id] = p
+
+	removedTrade := false
+	if banned {
+		queue := s.gameQueue[:0]
+		for _, q := range s.gameQueue {
+			if q != id {
+				queue = append(queue, q)
+			}
+		}
+		s.gameQueue = queue
+
+		wagers := s.wagerQueue[:0]
+		for _, w := range s.wagerQueue {
+			if w.PlayerID != id {
+				wagers = append(wagers, w)
+			}
+		}
+		s.wagerQueue = wagers
+
+		antes := s.anteQueue[:0]
+		for _, a := range s.anteQueue {
+			if a.PlayerID == id {
+				delete(s.anteLocks, a.CardID)
+				continue
+			}
+			antes = append(antes, a)
+		}
+		s.anteQueue = antes
+
+		trades := s.BlindTradeQueue[:0]
+		for _, r := range s.BlindTradeQueue {
+			if r.PlayerID == id {
+				removedTrade = true
+				continue
+			}
+			trades = append(trades, r)
+		}
+		s.BlindTradeQueue = trades
+	}
+	s.mu.Unlock()
+
+	if removedTrade {
+		s.pub.Publish(fmt.Sprintf("trade.result.%d", id), []byte(`{"status": "error", "msg": "jogador banido"}`))
+	}
+	slog.Info("player ban updated", logPlayer, id, "banned", banned)
+	return nil
+}
+
+// RefillPacks adiciona n pacotes ao estoque (o conteúdo de cada um é
+// definido no sorteio). Retorna o total disponível.
+func (s *Store) RefillPacks(n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("quantidade de pacotes inválida")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.packs += n
+	slog.Info("pack pool refilled", "added", n, "packs_available", s.packs)
+	return s.packs, nil
+}
+
+// Resultado de uma reconciliação forçada.
+type ReconcileReport struct {
+	Repaired     []int          `json:"repaired"`
+	WalletErrors map[int]string `json:"wallet_errors,omitempty"`
+	Synced       int            `json:"synced"`
+	SyncErrors   map[int]string `json:"sync_errors,omitempty"`
+	Journal      []string       `json:"journal,omitempty"`
+}
+
+// Reconcile repara carteiras vazias, ressincroniza o cache de cartas de
+// todos os jogadores com a blockchain e descarta o journal herdado de um
+// líder anterior (devolvido no relatório para conferência).
+func (s *Store) Reconcile(ctx context.Context) ReconcileReport {
+	repaired, failed := s.RepairWallets(ctx)
+	report := ReconcileReport{
+		Repaired:     repaired,
+		WalletErrors: errorStrings(failed),
+		SyncErrors:   map[int]string{},
+	}
+
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.players))
+	for id, p := range s.players {
+		if p.Wallet.Address != "" && !p.Banned {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		if _, err := s.SyncCards(ctx, id); err != nil {
+			report.SyncErrors[id] = err.Error()
+			continue
+		}
+		report.Synced++
+	}
+
+	s.mu.Lock()
+	report.Journal = s.journal
+	s.journal = nil
+	s.mu.Unlock()
+
+	slog.Info("reconciliation finished", "repaired", len(repaired), "synced", report.Synced, "sync_errors", len(report.SyncErrors))
+	return report
+}
@@ -0,0 +1,55 @@
+package API
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func signedAdminMsg(token, subject, nonce string, at time.Time, body []byte) *nats.Msg {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	m := nats.NewMsg(subject)
+	m.Data = body
+	m.Header.Set(AdminTimestampHeader, ts)
+	m.Header.Set(AdminNonceHeader, nonce)
+	m.Header.Set(AdminSignatureHeader, AdminSignature(token, subject, ts, nonce, body))
+	return m
+}
+
+// Só passa a requisição assinada com o token, dentro da janela e com
+// nonce novo; o corpo e o tópico fazem parte da assinatura.
+func TestAdminAuthVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	auth := newAdminAuth("segredo")
+	auth.now = func() time.Time { return now }
+	body := []byte(`{"id":7,"banned":true}`)
+
+	if err := auth.verify(signedAdminMsg("segredo", "admin.ban", "n1", now, body)); err != nil {
+		t.Fatalf("valid request refused: %v", err)
+	}
+
+	tampered := signedAdminMsg("segredo", "admin.ban", "n2", now, body)
+	tampered.Data = []byte(`{"id":8,"banned":true}`)
+	moved := signedAdminMsg("segredo", "admin.ban", "n3", now, body)
+	moved.Subject = "admin.refillPacks"
+
+	for name, m := range map[string]*nats.Msg{
+		"replay":        signedAdminMsg("segredo", "admin.ban", "n1", now, body),
+		"wrong token":   signedAdminMsg("outro", "admin.ban", "n4", now, body),
+		"expired":       signedAdminMsg("segredo", "admin.ban", "n5", now.Add(-time.Minute), body),
+		"future":        signedAdminMsg("segredo", "admin.ban", "n6", now.Add(time.Minute), body),
+		"tampered body": tampered,
+		"other subject": moved,
+		"unsigned":      {Subject: "admin.ban", Data: body},
+	} {
+		if err := auth.verify(m); err == nil {
+			t.Errorf("%s: request accepted", name)
+		}
+	}
+
+	if err := newAdminAuth("").verify(signedAdminMsg("", "admin.ban", "n7", time.Now(), body)); err == nil {
+		t.Error("request accepted with ADMIN_TOKEN unset")
+	}
+}
@@ -0,0 +1,192 @@
+package API
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// --- PARTIDAS ANTE ---
+//
+// Na fila ante cada jogador aposta uma carta do deck ativo. A carta fica
+// travada desde a entrada na fila (não pode ser presenteada nem ir para
+// a troca cega) e é a única que o jogador pode jogar na partida. Ao
+// resolver, a carta do perdedor é transferida para o vencedor e os
+// caches dos dois são atualizados; empate ou cancelamento apenas
+// destravam as cartas.
+
+// Jogador aguardando adversário na fila ante, com a carta apostada.
+type anteEntry struct {
+	PlayerID int    `json:"player_id"`
+	CardID   string `json:"card_id"`
+	Power    int    `json:"power"`
+}
+
+// anteCard devolve a carta apostada por id na partida ("" se não houver).
+func (m matchStruct) anteCard(id int) string {
+	switch id {
+	case m.P1:
+		return m.AnteCard1
+	case m.P2:
+		return m.AnteCard2
+	}
+	return ""
+}
+
+// JoinAnteQueue coloca o jogador na fila ante apostando cardHex, que
+// precisa estar no deck ativo e sob custódia do servidor (a transferência
+// ao vencedor não pode depender da assinatura do perdedor).
+func (s *Store) JoinAnteQueue(ctx context.Context, id int, cardHex string) error {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return err
+	}
+
+	// Sem a força no cache a partida correria com uma carta de força 0:
+	// antes de recusar, o cache é ressincronizado com a blockchain.
+	if _, cached := player.Cards[cardHex]; !cached {
+		if _, err := s.SyncCards(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	err = s.canAnteLocked(id, cardHex)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	card, err := s.locateCard(ctx, player, cardHex)
+	if err != nil {
+		return err
+	}
+	if card.SelfSigned {
+		return fmt.Errorf("só cartas sob custódia do servidor podem ser apostadas")
+	}
+
+	// A validação on-chain foi feita com a Store liberada: confere de novo.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.canAnteLocked(id, cardHex); err != nil {
+		return err
+	}
+
+	power := s.players[id].Cards[cardHex]
+	s.anteLocks[cardHex] = id
+	s.anteQueue = append(s.anteQueue, anteEntry{PlayerID: id, CardID: cardHex, Power: power})
+	slog.Info("ante queued", logPlayer, id, logObject, cardHex, "power", power, "queue_len", len(s.anteQueue))
+	return nil
+}
+
+// canAnteLocked confere se id pode apostar cardHex. Exige s.mu travado.
+func (s *Store) canAnteLocked(id int, cardHex string) error {
+	if s.closing {
+		return ErrShuttingDown
+	}
+	if err := s.canDuelLocked(id); err != nil {
+		return err
+	}
+	deck, err := s.activeDeckLocked(id)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(deck.Cards, cardHex) {
+		return fmt.Errorf("a carta %s não está no deck ativo", cardHex)
+	}
+	if power, cached := s.players[id].Cards[cardHex]; !cached || power <= 0 {
+		return fmt.Errorf("a carta %s não foi encontrada na sua carteira", cardHex)
+	}
+	for _, r := range s.BlindTradeQueue {
+		if r.CardHex == cardHex {
+			return fmt.Errorf("carta reservada na fila de troca")
+		}
+	}
+	if _, locked := s.anteLocks[cardHex]; locked {
+		return fmt.Errorf("carta já apostada em outra partida")
+	}
+	return nil
+}
+
+// anteLockedLocked informa se a carta está travada por uma partida ante.
+// Exige s.mu travado.
+func (s *Store) anteLockedLocked(cardHex string) bool {
+	_, locked := s.anteLocks[cardHex]
+	return locked
+}
+
+// CreateAnteMatch cria uma partida quando houver ao menos 2 jogadores
+// na fila ante, registrando a carta apostada por cada um.
+func (s *Store) CreateAnteMatch() (matchStruct, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.anteQueue) < 2 {
+		return matchStruct{}, fmt.Errorf("not enough players")
+	}
+	a, b := s.anteQueue[0], s.anteQueue[1]
+	s.anteQueue = s.anteQueue[2:]
+
+	match := s.newMatchLocked(a.PlayerID, b.PlayerID)
+	match.Ante = true
+	match.AnteCard1, match.AnteCard2 = a.CardID, b.CardID
+	s.matchHistory[match.SelfId] = match
+	return match, nil
+}
+
+// releaseAnte destrava as cartas apostadas na partida.
+func (s *Store) releaseAnte(game matchStruct) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseAnteLocked(game)
+}
+
+func (s *Store) releaseAnteLocked(game matchStruct) {
+	delete(s.anteLocks, game.AnteCard1)
+	delete(s.anteLocks, game.AnteCard2)
+}
+
+// settleAnte transfere a carta apostada pelo perdedor para o vencedor,
+// atualiza os caches e avisa os dois em player.<id>.ante.
+func (s *Store) settleAnte(ctx context.Context, game matchStruct, winnerID, loserID int) {
+	defer s.releaseAnte(game)
+
+	cardHex := game.anteCard(loserID)
+	s.mu.Lock()
+	loser := s.players[loserID].clone()
+	winnerAddr := s.players[winnerID].Wallet.Address
+	s.mu.Unlock()
+
+	power := loser.Cards[cardHex]
+	ev := map[string]any{"game": game.SelfId, "card_id": cardHex, "power": power}
+
+	card, err := s.locateCard(ctx, loser, cardHex)
+	var digest string
+	if err == nil {
+		digest, err = s.transferCard(ctx, card, winnerAddr)
+	}
+	if err != nil {
+		slog.Error("ante transfer failed", logGame, game.SelfId, logPlayer, loserID, logObject, cardHex, "err", err)
+		ev["err"] = err.Error()
+	} else {
+		s.mu.Lock()
+		s.updateCardsLocked(loserID, func(cards map[string]int) {
+			delete(cards, cardHex)
+		})
+		s.updateCardsLocked(winnerID, func(cards map[string]int) {
+			cards[cardHex] = power
+		})
+		s.mu.Unlock()
+
+		slog.Info("ante card transferred", logGame, game.SelfId, "winner_id", winnerID, "loser_id", loserID, logObject, cardHex, logDigest, digest)
+		ev["digest"] = digest
+	}
+
+	won, lost := map[string]any{"event": "won"}, map[string]any{"event": "lost"}
+	for k, v := range ev {
+		won[k], lost[k] = v, v
+	}
+	s.notify(winnerID, "ante", won)
+	s.notify(loserID, "ante", lost)
+}
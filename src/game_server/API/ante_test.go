@@ -0,0 +1,60 @@
+package API
+
+import (
+	"context"
+	"testing"
+)
+
+// Ao resolver uma partida ante, a carta apostada pelo perdedor passa
+// para o vencedor na blockchain e nos caches dos dois, e as cartas
+// apostadas são destravadas.
+func TestAnteMatchMovesLoserCard(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	ctx := context.Background()
+	a, b := newDuelists(t, s)
+
+	stakes := map[int]string{}
+	for _, id := range []int{a, b} {
+		s.mu.Lock()
+		deck, _ := s.activeDeckLocked(id)
+		s.mu.Unlock()
+		stakes[id] = deck.Cards[0]
+		if err := s.JoinAnteQueue(ctx, id, stakes[id]); err != nil {
+			t.Fatalf("JoinAnteQueue(%d): %v", id, err)
+		}
+	}
+	game, err := s.CreateAnteMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := map[int]Player{}
+	for _, id := range []int{a, b} {
+		before[id], _ = s.getPlayer(id)
+	}
+	winner, loser := playBoth(t, s, game.SelfId, a, b)
+	card := stakes[loser]
+	power := before[loser].Cards[card]
+
+	winnerP, _ := s.getPlayer(winner)
+	loserP, _ := s.getPlayer(loser)
+	if owner := bridge.owner(card); owner != winnerP.Wallet.Address {
+		t.Errorf("card %s owned by %q on-chain, want winner %q", card, owner, winnerP.Wallet.Address)
+	}
+	if got, ok := winnerP.Cards[card]; !ok || got != power {
+		t.Errorf("winner cache has card %s = %d (present %v), want power %d", card, got, ok, power)
+	}
+	if _, ok := loserP.Cards[card]; ok {
+		t.Errorf("loser cache still lists card %s", card)
+	}
+	if _, ok := winnerP.Cards[stakes[winner]]; !ok {
+		t.Errorf("winner lost their own staked card %s", stakes[winner])
+	}
+
+	s.mu.Lock()
+	locks := len(s.anteLocks)
+	s.mu.Unlock()
+	if locks != 0 {
+		t.Errorf("%d ante locks left after the match", locks)
+	}
+}
@@ -0,0 +1,349 @@
+package API
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestMain(m *testing.M) {
+	// Os testes geram milhares de eventos: só erros inesperados aparecem.
+	SetupLogging(io.Discard, "error", "text")
+	os.Exit(m.Run())
+}
+
+// fakeBridge simula o worker em memória: carteiras, cartas (com dono) e
+// transferências. latency imita o tempo de uma chamada à blockchain,
+// sempre fora do lock interno, como no worker real.
+type fakeBridge struct {
+	latency time.Duration
+
+	mu      sync.Mutex
+	seq     int
+	secrets map[string]string  // Secret → endereço da carteira
+	cards   map[string]CardDTO // ID do objeto → carta (Owner = dono atual)
+	calls   map[string]int     // Chamadas por operação
+	payouts map[string]uint64  // Total pago pela loja a cada endereço
+	debits  map[string]uint64  // Total enviado por cada endereço (Transaction)
+
+	failFrom map[string]error // Transaction falha para estes remetentes
+}
+
+func newFakeBridge(latency time.Duration) *fakeBridge {
+	return &fakeBridge{
+		latency: latency,
+		secrets: make(map[string]string),
+		cards:   make(map[string]CardDTO),
+		calls:   make(map[string]int),
+		payouts: make(map[string]uint64),
+		debits:  make(map[string]uint64),
+
+		failFrom: make(map[string]error),
+	}
+}
+
+// wait simula a latência da chamada e registra a operação.
+func (f *fakeBridge) wait(ctx context.Context, op string) error {
+	f.mu.Lock()
+	f.calls[op]++
+	f.mu.Unlock()
+
+	if f.latency <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-time.After(f.latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// nextLocked gera um identificador único. Exige f.mu travado.
+func (f *fakeBridge) nextLocked(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("0x%s%060x", prefix, f.seq)
+}
+
+func (f *fakeBridge) mintLocked(address string, value int) (digest, objectID string) {
+	objectID = f.nextLocked("c0")
+	f.cards[objectID] = CardDTO{ID: objectID, Power: value, Owner: address}
+	return f.nextLocked("d0"), objectID
+}
+
+// ownerCheckLocked confere que address detém a carta. Exige f.mu travado.
+func (f *fakeBridge) ownerCheckLocked(address, objectID string) error {
+	if card, ok := f.cards[objectID]; !ok || card.Owner != address {
+		return fmt.Errorf("%w: %s", ErrNotOwner, objectID)
+	}
+	return nil
+}
+
+func (f *fakeBridge) CreateWallet(ctx context.Context) (Wallet, error) {
+	if err := f.wait(ctx, "createWallet"); err != nil {
+		return Wallet{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := Wallet{Address: f.nextLocked("a0"), Secret: f.nextLocked("5e")}
+	f.secrets[w.Secret] = w.Address
+	return w, nil
+}
+
+func (f *fakeBridge) Balance(ctx context.Context, wallet Wallet) (uint64, error) {
+	return 1_000_000_000_000, f.wait(ctx, "balance")
+}
+
+func (f *fakeBridge) Faucet(ctx context.Context, wallet Wallet) (uint64, error) {
+	return 1_000_000_000, f.wait(ctx, "faucet")
+}
+
+func (f *fakeBridge) Transaction(ctx context.Context, source, destination Wallet, value uint64) (string, error) {
+	if err := f.wait(ctx, "transaction"); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failFrom[source.Address]; err != nil {
+		return "", err
+	}
+	f.debits[source.Address] += value
+	return f.nextLocked("d0"), nil
+}
+
+func (f *fakeBridge) MintCard(ctx context.Context, address string, value int) (string, string, error) {
+	if err := f.wait(ctx, "mintCard"); err != nil {
+		return "", "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	digest, objectID := f.mintLocked(address, value)
+	return digest, objectID, nil
+}
+
+func (f *fakeBridge) MintBatch(ctx context.Context, cards []MintReq) (string, []string, error) {
+	if err := f.wait(ctx, "mintBatch"); err != nil {
+		return "", nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	digest := f.nextLocked("d0")
+	ids := make([]string, len(cards))
+	for i, c := range cards {
+		_, ids[i] = f.mintLocked(c.Address, int(c.Value))
+	}
+	return digest, ids, nil
+}
+
+func (f *fakeBridge) LogMatch(ctx context.Context, winnerAddr, loserAddr string, valWin, valLose int) (string, string, error) {
+	if err := f.wait(ctx, "logMatch"); err != nil {
+		return "", "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nextLocked("d0"), f.nextLocked("10"), nil
+}
+
+func (f *fakeBridge) LogTournament(ctx context.Context, tournamentID string, standings []string, prizes []uint64) (string, string, error) {
+	if err := f.wait(ctx, "logTournament"); err != nil {
+		return "", "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nextLocked("d0"), f.nextLocked("70"), nil
+}
+
+func (f *fakeBridge) Payout(ctx context.Context, recipient string, amount uint64) (string, error) {
+	if err := f.wait(ctx, "payout"); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payouts[recipient] += amount
+	return f.nextLocked("d0"), nil
+}
+
+func (f *fakeBridge) TransferCard(ctx context.Context, ownerSecret, cardObjectID, recipientAddr string) (string, error) {
+	if err := f.wait(ctx, "transferCard"); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.ownerCheckLocked(f.secrets[ownerSecret], cardObjectID); err != nil {
+		return "", err
+	}
+	card := f.cards[cardObjectID]
+	card.Owner = recipientAddr
+	f.cards[cardObjectID] = card
+	return f.nextLocked("d0"), nil
+}
+
+func (f *fakeBridge) ValidateOwnership(ctx context.Context, address, objectId string) error {
+	if err := f.wait(ctx, "validateOwnership"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ownerCheckLocked(address, objectId)
+}
+
+func (f *fakeBridge) AtomicSwap(ctx context.Context, userA Wallet, cardA string, userB Wallet, cardB string) error {
+	if err := f.wait(ctx, "atomicSwap"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.ownerCheckLocked(f.secrets[userA.Secret], cardA); err != nil {
+		return err
+	}
+	if err := f.ownerCheckLocked(f.secrets[userB.Secret], cardB); err != nil {
+		return err
+	}
+	a, b := f.cards[cardA], f.cards[cardB]
+	a.Owner, b.Owner = userB.Address, userA.Address
+	f.cards[cardA], f.cards[cardB] = a, b
+	return nil
+}
+
+func (f *fakeBridge) GetCards(ctx context.Context, address string) ([]CardDTO, error) {
+	if err := f.wait(ctx, "getCards"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []CardDTO
+	for _, c := range f.cards {
+		if c.Owner == address {
+			out = append(out, CardDTO{ID: c.ID, Power: c.Power})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeBridge) BuildTransferCard(ctx context.Context, senderAddr, cardObjectID, recipientAddr string) (string, error) {
+	return "", errors.New("fakeBridge: modo sem custódia não suportado")
+}
+
+func (f *fakeBridge) ExecuteSigned(ctx context.Context, txBytes, signature string) (string, error) {
+	return "", errors.New("fakeBridge: modo sem custódia não suportado")
+}
+
+func (f *fakeBridge) Ping(ctx context.Context) error {
+	return f.wait(ctx, "ping")
+}
+
+// owner devolve o dono atual da carta ("" se ela não existe).
+func (f *fakeBridge) owner(objectID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cards[objectID].Owner
+}
+
+// paid devolve o total pago pela loja ao endereço.
+func (f *fakeBridge) paid(address string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.payouts[address]
+}
+
+// debited devolve o total enviado pelo endereço via Transaction.
+func (f *fakeBridge) debited(address string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.debits[address]
+}
+
+// failTransactionsFrom faz as próximas Transaction do endereço falharem.
+func (f *fakeBridge) failTransactionsFrom(address string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failFrom[address] = err
+}
+
+// minted conta as cartas criadas.
+func (f *fakeBridge) minted() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.cards)
+}
+
+// fakePublisher descarta as notificações, contando-as por tópico.
+type fakePublisher struct {
+	mu        sync.Mutex
+	published map[string]int
+}
+
+func (p *fakePublisher) Publish(subject string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.published == nil {
+		p.published = make(map[string]int)
+	}
+	p.published[subject]++
+	return nil
+}
+
+func (p *fakePublisher) RequestWithContext(ctx context.Context, subject string, data []byte) (*nats.Msg, error) {
+	return nil, nats.ErrNoResponders
+}
+
+// newTestStore cria uma Store sobre o fakeBridge, sem cluster nem NATS.
+func newTestStore(tb testing.TB, latency time.Duration) (*Store, *fakeBridge) {
+	tb.Helper()
+	bridge := newFakeBridge(latency)
+	return NewStore("test", bridge, &fakePublisher{}), bridge
+}
+
+// newTestPlayer cria um jogador, abre packs pacotes e monta o deck
+// ativo com as DeckSize primeiras cartas. Devolve as cartas fora do
+// deck, livres para trocas e presentes. Não usa tb.Fatal para poder
+// ser chamada de várias goroutines.
+func newTestPlayer(s *Store, packs int) (id int, spare []string, err error) {
+	ctx := context.Background()
+
+	if id, err = s.CreatePlayer(ctx); err != nil {
+		return 0, nil, fmt.Errorf("CreatePlayer: %w", err)
+	}
+	for i := 0; i < packs; i++ {
+		if _, err := s.OpenPack(ctx, id, fmt.Sprintf("seed-%d-%d", id, i), func(PackEvent) {}); err != nil {
+			return 0, nil, fmt.Errorf("OpenPack(%d): %w", id, err)
+		}
+	}
+
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	var cards []string
+	for card := range player.Cards {
+		cards = append(cards, card)
+	}
+	if len(cards) < s.DeckSize {
+		return 0, nil, fmt.Errorf("player %d has %d cards, need %d", id, len(cards), s.DeckSize)
+	}
+	if err := s.SaveDeck(ctx, id, "main", "", cards[:s.DeckSize], true); err != nil {
+		return 0, nil, fmt.Errorf("SaveDeck(%d): %w", id, err)
+	}
+	if err := s.SelectDeck(ctx, id, "main"); err != nil {
+		return 0, nil, fmt.Errorf("SelectDeck(%d): %w", id, err)
+	}
+	return id, cards[s.DeckSize:], nil
+}
+
+// deckPower devolve a força de uma carta do deck ativo do jogador.
+func deckPower(s *Store, id int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deck, err := s.activeDeckLocked(id)
+	if err != nil {
+		return 0, err
+	}
+	return s.players[id].Cards[deck.Cards[0]], nil
+}
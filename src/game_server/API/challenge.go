@@ -0,0 +1,288 @@
+package API
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- DESAFIOS PRIVADOS ---
+//
+// Um jogador desafia outro pelo ID (ou gera um código de sala para
+// compartilhar). O convidado recebe o desafio em player.<id>.challenge e
+// aceita ou recusa pelo código; ao aceitar, a partida é criada direto
+// entre os dois, sem passar pela fila pública.
+
+// Validade de um desafio pendente.
+const challengeTTL = 2 * time.Minute
+
+// Tamanho do código de sala.
+const challengeCodeLen = 6
+
+// Alfabeto dos códigos, sem caracteres ambíguos (0/O, 1/I).
+const challengeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Challenge é um desafio pendente. To == 0 indica um código de sala
+// aberto: qualquer jogador que conheça o código pode aceitar.
+type Challenge struct {
+	Code    string    `json:"code"`
+	From    int       `json:"from"`
+	To      int       `json:"to,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+func newChallengeCode() string {
+	b := make([]byte, challengeCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	for i := range b {
+		b[i] = challengeAlphabet[int(b[i])%len(challengeAlphabet)]
+	}
+	return string(b)
+}
+
+// canDuelLocked confere se o jogador pode entrar em uma partida privada:
+// existe, não está banido, tem deck ativo válido e não está na fila
+// pública, na de apostas ou na ante. Exige s.mu travado.
+func (s *Store) canDuelLocked(id int) error {
+	p, exists := s.players[id]
+	if !exists {
+		return fmt.Errorf("jogador %d não encontrado", id)
+	}
+	if p.Banned {
+		return ErrBanned
+	}
+	if _, err := s.activeDeckLocked(id); err != nil {
+		return err
+	}
+	for _, q := range s.gameQueue {
+		if q == id {
+			return fmt.Errorf("jogador %d já está na fila de partidas", id)
+		}
+	}
+	for _, w := range s.wagerQueue {
+		if w.PlayerID == id {
+			return fmt.Errorf("jogador %d já está na fila de apostas", id)
+		}
+	}
+	for _, a := range s.anteQueue {
+		if a.PlayerID == id {
+			return fmt.Errorf("jogador %d já está na fila ante", id)
+		}
+	}
+	return nil
+}
+
+// Remove os desafios vencidos. Exige s.mu travado.
+func (s *Store) expireChallengesLocked() {
+	now := time.Now()
+	for code, c := range s.challenges {
+		if now.After(c.Expires) {
+			delete(s.challenges, code)
+		}
+	}
+}
+
+// CreateChallenge registra um desafio de id contra target (0 = código de
+// sala aberto) e avisa o convidado. Cada jogador tem no máximo um
+// desafio pendente.
+func (s *Store) CreateChallenge(id, target int) (Challenge, error) {
+	s.mu.Lock()
+
+	s.expireChallengesLocked()
+	if s.closing {
+		s.mu.Unlock()
+		return Challenge{}, ErrShuttingDown
+	}
+	if target == id {
+		s.mu.Unlock()
+		return Challenge{}, fmt.Errorf("você não pode desafiar a si mesmo")
+	}
+	if err := s.canDuelLocked(id); err != nil {
+		s.mu.Unlock()
+		return Challenge{}, err
+	}
+	if _, exists := s.players[target]; target != 0 && !exists {
+		s.mu.Unlock()
+		return Challenge{}, fmt.Errorf("jogador %d não encontrado", target)
+	}
+	for _, c := range s.challenges {
+		if c.From == id {
+			s.mu.Unlock()
+			return Challenge{}, fmt.Errorf("você já tem um desafio pendente (código %s)", c.Code)
+		}
+	}
+
+	code := newChallengeCode()
+	for _, taken := s.challenges[code]; taken; _, taken = s.challenges[code] {
+		code = newChallengeCode()
+	}
+	challenge := Challenge{Code: code, From: id, To: target, Expires: time.Now().Add(challengeTTL)}
+	s.challenges[code] = challenge
+	s.mu.Unlock()
+
+	slog.Info("challenge created", logPlayer, id, "target_id", target, "code", code)
+	if target != 0 {
+		s.notify(target, "challenge", map[string]any{"event": "invite", "challenge": challenge})
+	}
+	return challenge, nil
+}
+
+// takeChallengeLocked busca o desafio pelo código e confere se id é o
+// convidado. Exige s.mu travado.
+func (s *Store) takeChallengeLocked(id int, code string) (Challenge, error) {
+	s.expireChallengesLocked()
+	c, ok := s.challenges[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Challenge{}, fmt.Errorf("desafio não encontrado ou expirado")
+	}
+	if c.From == id {
+		return Challenge{}, fmt.Errorf("você não pode aceitar o próprio desafio")
+	}
+	if c.To != 0 && c.To != id {
+		return Challenge{}, fmt.Errorf("este desafio é para outro jogador")
+	}
+	return c, nil
+}
+
+// AcceptChallenge cria a partida entre o desafiante (P1) e quem aceitou
+// (P2). O desafiante recebe a partida em player.<id>.challenge.
+func (s *Store) AcceptChallenge(id int, code string) (matchStruct, error) {
+	s.mu.Lock()
+
+	if s.closing {
+		s.mu.Unlock()
+		return matchStruct{}, ErrShuttingDown
+	}
+	c, err := s.takeChallengeLocked(id, code)
+	if err != nil {
+		s.mu.Unlock()
+		return matchStruct{}, err
+	}
+	if err := s.canDuelLocked(id); err != nil {
+		s.mu.Unlock()
+		return matchStruct{}, err
+	}
+	// O desafiante pode ter mudado de deck (ou entrado na fila) depois do convite.
+	if err := s.canDuelLocked(c.From); err != nil {
+		delete(s.challenges, c.Code)
+		s.mu.Unlock()
+		s.notify(c.From, "challenge", map[string]any{"event": "canceled", "challenge": c, "err": err.Error()})
+		return matchStruct{}, fmt.Errorf("o desafiante não pode jogar agora: %v", err)
+	}
+
+	delete(s.challenges, c.Code)
+	match := s.newMatchLocked(c.From, id)
+	s.mu.Unlock()
+
+	slog.Info("challenge accepted", logGame, match.SelfId, logPlayer, id, "challenger_id", c.From, "code", c.Code)
+	s.notify(c.From, "challenge", map[string]any{"event": "accepted", "challenge": c, "by": id, "match": match.playerView()})
+	return match, nil
+}
+
+// DeclineChallenge recusa um desafio e avisa o desafiante. Códigos de
+// sala não podem ser recusados: qualquer um que soubesse o código
+// poderia derrubar a sala.
+func (s *Store) DeclineChallenge(id int, code string) error {
+	s.mu.Lock()
+	c, err := s.takeChallengeLocked(id, code)
+	if err == nil && c.To == 0 {
+		err = fmt.Errorf("códigos de sala não podem ser recusados")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	delete(s.challenges, c.Code)
+	s.mu.Unlock()
+
+	slog.Info("challenge declined", logPlayer, id, "challenger_id", c.From, "code", c.Code)
+	s.notify(c.From, "challenge", map[string]any{"event": "declined", "challenge": c, "by": id})
+	return nil
+}
+
+// CancelChallenge desiste do desafio pendente do jogador.
+func (s *Store) CancelChallenge(id int) error {
+	s.mu.Lock()
+	var pending *Challenge
+	for code, c := range s.challenges {
+		if c.From == id {
+			delete(s.challenges, code)
+			pending = &c
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if pending == nil {
+		return fmt.Errorf("nenhum desafio pendente")
+	}
+	if pending.To != 0 {
+		s.notify(pending.To, "challenge", map[string]any{"event": "canceled", "challenge": *pending})
+	}
+	return nil
+}
+
+// Challenges lista os desafios pendentes enviados e recebidos pelo
+// jogador (os códigos de sala abertos só aparecem para quem os criou).
+func (s *Store) Challenges(id int) (sent []Challenge, received []Challenge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireChallengesLocked()
+	sent, received = []Challenge{}, []Challenge{}
+	for _, c := range s.challenges {
+		switch id {
+		case c.From:
+			sent = append(sent, c)
+		case c.To:
+			received = append(received, c)
+		}
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i].Expires.Before(received[j].Expires) })
+	return sent, received
+}
+
+// --- TÓPICOS topic.challenge.* ---
+
+type challengeRequest struct {
+	ClientID int    `json:"client_id"`
+	TargetID int    `json:"target_id"`
+	Code     string `json:"code"`
+}
+
+type challengeCommand func(ctx context.Context, s *Store, req challengeRequest) (any, error)
+
+// Comandos disponíveis, pelo sufixo do tópico (topic.challenge.<comando>).
+var challengeCommands = map[string]challengeCommand{
+	"create": func(ctx context.Context, s *Store, req challengeRequest) (any, error) {
+		return s.CreateChallenge(req.ClientID, req.TargetID)
+	},
+	"accept": func(ctx context.Context, s *Store, req challengeRequest) (any, error) {
+		match, err := s.AcceptChallenge(req.ClientID, req.Code)
+		return map[string]any{"match": match.playerView()}, err
+	},
+	"decline": func(ctx context.Context, s *Store, req challengeRequest) (any, error) {
+		return map[string]any{"code": req.Code}, s.DeclineChallenge(req.ClientID, req.Code)
+	},
+	"cancel": func(ctx context.Context, s *Store, req challengeRequest) (any, error) {
+		return map[string]any{}, s.CancelChallenge(req.ClientID)
+	},
+	"list": func(ctx context.Context, s *Store, req challengeRequest) (any, error) {
+		sent, received := s.Challenges(req.ClientID)
+		return map[string]any{"sent": sent, "received": received}, nil
+	},
+}
+
+// ClientChallenges atende topic.challenge.<comando> (create, accept,
+// decline, cancel, list). A resposta é {"result": ...} ou {"err": ...}.
+func ClientChallenges(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	return serveCommands(nc, s, "topic.challenge.", challengeCommands, nil)
+}
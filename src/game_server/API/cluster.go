@@ -0,0 +1,268 @@
+package API
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- CONFIGURAÇÃO DO CLUSTER ---
+
+const (
+	leaderBucket = "game_leader" // Lease de liderança (expira sozinho via TTL)
+	stateBucket  = "game_state"  // Estado replicado da Store
+	leaderKey    = "leader"
+	stateKey     = "store"
+
+	// O líder renova o lease a cada leaseInterval; se morrer,
+	// a chave expira após leaseTTL e um seguidor assume.
+	leaseInterval = 1 * time.Second
+	leaseTTL      = 5 * time.Second
+)
+
+// Cluster coordena várias instâncias do game server usando o KV do
+// JetStream: a chave "leader" funciona como um lease com TTL e o
+// estado da Store é replicado no bucket de estado a cada renovação e
+// logo depois de cada mudança (mensagem atendida ou operação longa
+// iniciada/concluída). Ainda assim, o que mudou entre a última gravação
+// e a queda do líder se perde: a janela é o tempo de um Put no KV, ou
+// até leaseInterval para mudanças feitas fora dos handlers (ex.: W.O.
+// de torneio).
+// Apenas o líder assina os tópicos do jogo; os seguidores ficam em
+// espera e restauram o último snapshot quando assumem a liderança.
+type Cluster struct {
+	nc       *nats.Conn
+	store    *Store
+	leaderKV nats.KeyValue
+	stateKV  nats.KeyValue
+
+	leader    atomic.Bool
+	revision  uint64
+	lastState []byte
+
+	// Resultado da última replicação do estado (status de persistência).
+	statusMu    sync.Mutex
+	lastSync    time.Time
+	lastSyncErr error
+
+	stop chan struct{} // Fecha para encerrar o laço de eleição
+	done chan struct{} // Fechado quando o laço terminou
+
+	// Callbacks disparados nas transições de liderança.
+	OnElected func()
+	OnDemoted func()
+}
+
+// NewCluster abre (ou cria) os buckets KV usados na eleição.
+// Retorna erro se o servidor NATS não tiver JetStream habilitado.
+func NewCluster(nc *nats.Conn, s *Store) (*Cluster, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	leaderKV, err := openBucket(js, &nats.KeyValueConfig{Bucket: leaderBucket, TTL: leaseTTL, History: 1})
+	if err != nil {
+		return nil, err
+	}
+	stateKV, err := openBucket(js, &nats.KeyValueConfig{Bucket: stateBucket, History: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cluster{
+		nc:       nc,
+		store:    s,
+		leaderKV: leaderKV,
+		stateKV:  stateKV,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Abre um bucket existente ou cria com a configuração informada.
+func openBucket(js nats.JetStreamContext, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	kv, err := js.KeyValue(cfg.Bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		return js.CreateKeyValue(cfg)
+	}
+	return kv, err
+}
+
+// IsLeader informa se este nó detém o lease de liderança.
+func (c *Cluster) IsLeader() bool {
+	return c.leader.Load()
+}
+
+// LeaderID retorna o NodeID do líder atual (vazio se não houver).
+func (c *Cluster) LeaderID() string {
+	entry, err := c.leaderKV.Get(leaderKey)
+	if err != nil {
+		return ""
+	}
+	return string(entry.Value())
+}
+
+// Run executa o laço de eleição: o líder renova o lease e replica o
+// estado; os seguidores tentam adquirir o lease quando ele expira.
+func (c *Cluster) Run() {
+	ticker := time.NewTicker(leaseInterval)
+	defer ticker.Stop()
+	defer close(c.done)
+
+	for {
+		if c.IsLeader() {
+			c.renew()
+		} else {
+			c.campaign()
+		}
+
+	wait:
+		select {
+		case <-c.stop:
+			c.resign()
+			return
+		case <-ticker.C:
+		case <-c.store.changed:
+			if c.IsLeader() {
+				c.replicate()
+			}
+			goto wait
+		}
+	}
+}
+
+// Close encerra a participação no cluster: se este nó é o líder,
+// grava o estado final e libera o lease para um seguidor assumir
+// imediatamente, sem esperar o TTL expirar.
+func (c *Cluster) Close() {
+	close(c.stop)
+	<-c.done
+}
+
+func (c *Cluster) resign() {
+	if !c.IsLeader() {
+		return
+	}
+	c.replicate()
+	if err := c.leaderKV.Delete(leaderKey, nats.LastRevision(c.revision)); err != nil {
+		slog.Warn("failed to release leader lease", "err", err)
+	}
+	c.leader.Store(false)
+	slog.Info("leadership released", logNode, c.store.NodeID)
+}
+
+// Renova o lease; se outro nó assumiu ou o KV ficou inacessível,
+// este nó deixa de ser líder imediatamente.
+func (c *Cluster) renew() {
+	rev, err := c.leaderKV.Update(leaderKey, []byte(c.store.NodeID), c.revision)
+	if err != nil {
+		slog.Warn("leader lease lost", logNode, c.store.NodeID, "err", err)
+		c.demote()
+		return
+	}
+	c.revision = rev
+	c.replicate()
+}
+
+// Tenta adquirir o lease. Também recupera a liderança caso a chave
+// ainda pertença a este nó (ex.: após uma falha transitória no KV).
+func (c *Cluster) campaign() {
+	rev, err := c.leaderKV.Create(leaderKey, []byte(c.store.NodeID))
+	if err != nil {
+		entry, getErr := c.leaderKV.Get(leaderKey)
+		if getErr != nil || string(entry.Value()) != c.store.NodeID {
+			return
+		}
+		rev = entry.Revision()
+	}
+	c.revision = rev
+	c.promote()
+}
+
+// Publica o snapshot da Store no bucket de estado quando ele mudou.
+func (c *Cluster) replicate() {
+	data, err := c.store.Snapshot()
+	if err != nil {
+		slog.Error("failed to serialize store", "err", err)
+		return
+	}
+	if bytes.Equal(data, c.lastState) {
+		c.setSyncStatus(nil)
+		return
+	}
+	if _, err := c.stateKV.Put(stateKey, data); err != nil {
+		slog.Error("failed to replicate state", "err", err)
+		c.setSyncStatus(err)
+		return
+	}
+	c.lastState = data
+	c.setSyncStatus(nil)
+}
+
+func (c *Cluster) setSyncStatus(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.lastSyncErr = err
+	if err == nil {
+		c.lastSync = time.Now()
+	}
+}
+
+// PersistenceStatus informa quando o estado foi gravado no KV pela
+// última vez e o erro da última tentativa, se houver.
+func (c *Cluster) PersistenceStatus() (time.Time, error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.lastSync, c.lastSyncErr
+}
+
+// Carrega o último estado replicado antes de começar a atender. Só
+// assume com o estado carregado ou com o bucket realmente vazio: atender
+// com estado velho (ou vazio) e replicá-lo apagaria o snapshot bom. Em
+// caso de erro o lease é devolvido e a eleição tenta de novo.
+func (c *Cluster) promote() {
+	entry, err := c.stateKV.Get(stateKey)
+	switch {
+	case err == nil:
+		if err := c.store.Restore(entry.Value()); err != nil {
+			slog.Error("invalid replicated snapshot, releasing leadership", "err", err)
+			c.release()
+			return
+		}
+		c.lastState = entry.Value()
+	case errors.Is(err, nats.ErrKeyNotFound):
+		slog.Info("no replicated state, starting empty", logNode, c.store.NodeID)
+	default:
+		slog.Warn("replicated state unavailable, releasing leadership", "err", err)
+		c.release()
+		return
+	}
+
+	c.leader.Store(true)
+	slog.Info("leadership acquired", logNode, c.store.NodeID)
+	if c.OnElected != nil {
+		c.OnElected()
+	}
+}
+
+// Devolve o lease adquirido sem ter assumido a liderança.
+func (c *Cluster) release() {
+	if err := c.leaderKV.Delete(leaderKey, nats.LastRevision(c.revision)); err != nil {
+		slog.Warn("failed to release leader lease", "err", err)
+	}
+}
+
+func (c *Cluster) demote() {
+	c.leader.Store(false)
+	c.lastState = nil
+	slog.Info("demoted to follower", logNode, c.store.NodeID)
+	if c.OnDemoted != nil {
+		c.OnDemoted()
+	}
+}
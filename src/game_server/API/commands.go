@@ -0,0 +1,51 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// serveCommands atende <prefix><comando>: decodifica o payload em R,
+// procura o comando pelo sufixo do tópico e responde {"result": ...} ou
+// {"err": ...}. Se guard não for nil, roda antes do comando e pode
+// recusar a requisição (o erro vira a resposta).
+func serveCommands[R any, C ~func(context.Context, *Store, R) (any, error)](
+	nc *nats.Conn, s *Store, prefix string, cmds map[string]C, guard func(m *nats.Msg, req R) error,
+) (*nats.Subscription, error) {
+	subject := prefix + ">"
+	return nc.Subscribe(subject, instrument(s, subject, func(ctx context.Context, m *nats.Msg) {
+		reply := func(resp map[string]any) {
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+		}
+
+		var req R
+		if err := json.Unmarshal(m.Data, &req); err != nil {
+			reply(map[string]any{"err": "invalid payload"})
+			return
+		}
+		if guard != nil {
+			if err := guard(m, req); err != nil {
+				reply(map[string]any{"err": err.Error()})
+				return
+			}
+		}
+
+		name := strings.TrimPrefix(m.Subject, prefix)
+		cmd, ok := cmds[name]
+		if !ok {
+			reply(map[string]any{"err": "unknown command: " + name})
+			return
+		}
+
+		result, err := cmd(ctx, s, req)
+		if err != nil {
+			reply(map[string]any{"err": err.Error()})
+			return
+		}
+		reply(map[string]any{"result": result})
+	}))
+}
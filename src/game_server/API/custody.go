@@ -0,0 +1,155 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- MODO SEM CUSTÓDIA ---
+
+// Prazo para o cliente assinar uma transação enviada em player.<id>.sign.
+const signTimeout = 60 * time.Second
+
+// ErrSelfCustody é devolvido pelas cobranças que o servidor assinaria
+// com a chave da carteira principal depois que ela foi entregue ao cliente.
+var ErrSelfCustody = errors.New("modo sem custódia: a chave da carteira principal está só no cliente (desative o modo para pagar pelo servidor)")
+
+// SetSelfCustody liga ou desliga o modo em que o próprio cliente assina
+// as transferências de cartas da carteira principal do jogador.
+//
+// Ao ligar, a chave sai do servidor: ela é devolvida uma única vez
+// (handover) e apagada do estado, do snapshot e das credenciais. Ligar
+// de novo não devolve nada. Para desligar, o cliente reenvia a chave,
+// que precisa corresponder ao endereço da carteira.
+func (s *Store) SetSelfCustody(id int, enabled bool, secret string) (handover string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[id]
+	if !exists {
+		return "", fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		return "", ErrBanned
+	}
+
+	switch {
+	case enabled && !player.SelfCustody:
+		// A fila da troca cega guarda uma cópia da carteira com a chave,
+		// e a aposta e a carta ante ainda dependem da assinatura do servidor.
+		for _, r := range s.BlindTradeQueue {
+			if r.PlayerID == id {
+				return "", fmt.Errorf("saia da fila de troca cega antes de ativar o modo sem custódia")
+			}
+		}
+		for _, w := range s.wagerQueue {
+			if w.PlayerID == id {
+				return "", fmt.Errorf("saia da fila de apostas antes de ativar o modo sem custódia")
+			}
+		}
+		for _, owner := range s.anteLocks {
+			if owner == id {
+				return "", fmt.Errorf("termine a partida ante antes de ativar o modo sem custódia")
+			}
+		}
+		handover = player.Wallet.Secret
+		player.Wallet.Secret = ""
+	case !enabled && player.SelfCustody:
+		address, err := addressFromSecret(secret)
+		if err != nil {
+			return "", fmt.Errorf("chave da carteira principal inválida: %v", err)
+		}
+		if address != player.Wallet.Address {
+			return "", fmt.Errorf("a chave não corresponde à carteira %s", player.Wallet.Address)
+		}
+		player.Wallet.Secret = strings.TrimSpace(secret)
+	}
+	player.SelfCustody = enabled
+	s.players[id] = player
+	return handover, nil
+}
+
+// spendable devolve a carteira principal para uma cobrança assinada pelo
+// servidor, ou ErrSelfCustody se a chave já foi entregue ao cliente.
+func spendable(p Player) (Wallet, error) {
+	if p.SelfCustody {
+		return Wallet{}, ErrSelfCustody
+	}
+	return p.Wallet, nil
+}
+
+// locateCard descobre qual carteira do jogador (a principal ou uma
+// externa vinculada) detém a carta e se a transferência precisa ser
+// assinada pelo cliente.
+func (s *Store) locateCard(ctx context.Context, player Player, cardHex string) (BlindTradeRequest, error) {
+	req := BlindTradeRequest{PlayerID: player.Id, CardHex: cardHex, Wallet: player.Wallet}
+
+	for _, addr := range append([]string{player.Wallet.Address}, player.LinkedWallets...) {
+		err := s.bridge.ValidateOwnership(ctx, addr, cardHex)
+		if errors.Is(err, ErrNotOwner) {
+			continue
+		}
+		if err != nil {
+			return req, err
+		}
+		req.Owner = addr
+		req.SelfSigned = player.SelfCustody || addr != player.Wallet.Address
+		return req, nil
+	}
+	return req, fmt.Errorf("você não é dono desta carta na blockchain")
+}
+
+// transferCard envia a carta para recipient. Cartas custodiadas usam a
+// chave guardada no servidor; nas demais o worker monta a transação,
+// o cliente assina e o servidor apenas a submete.
+func (s *Store) transferCard(ctx context.Context, card BlindTradeRequest, recipient string) (string, error) {
+	if !card.SelfSigned {
+		return s.bridge.TransferCard(ctx, card.Wallet.Secret, card.CardHex, recipient)
+	}
+
+	txBytes, err := s.bridge.BuildTransferCard(ctx, card.Owner, card.CardHex, recipient)
+	if err != nil {
+		return "", err
+	}
+
+	desc := fmt.Sprintf("Transferir carta %s para %s", card.CardHex, recipient)
+	signature, err := s.requestSignature(ctx, card.PlayerID, card.Owner, txBytes, desc)
+	if err != nil {
+		return "", err
+	}
+	return s.bridge.ExecuteSigned(ctx, txBytes, signature)
+}
+
+// requestSignature envia a transação ao cliente em player.<id>.sign e
+// aguarda a assinatura feita com a chave de address.
+func (s *Store) requestSignature(ctx context.Context, id int, address, txBytes, desc string) (string, error) {
+	data, _ := json.Marshal(map[string]any{
+		"address":     address,
+		"tx_bytes":    txBytes,
+		"description": desc,
+	})
+
+	reqCtx, cancel := context.WithTimeout(ctx, signTimeout)
+	defer cancel()
+
+	msg, err := s.pub.RequestWithContext(reqCtx, playerSubject(id, "sign"), data)
+	if err != nil {
+		return "", fmt.Errorf("jogador %d não assinou a transação: %w", id, err)
+	}
+
+	var resp struct {
+		Signature string `json:"signature"`
+		Err       string `json:"err"`
+	}
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return "", err
+	}
+	if resp.Err != "" {
+		return "", fmt.Errorf("assinatura recusada: %s", resp.Err)
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,103 @@
+package API
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey gera uma chave Ed25519 de verdade (base64 de flag || semente).
+func testKey(seed byte) string {
+	raw := append([]byte{ed25519Flag}, make([]byte, ed25519.SeedSize)...)
+	raw[1] = seed
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// realWallet troca a carteira do jogador por uma com chave de verdade,
+// para que o endereço possa ser derivado da chave devolvida pelo cliente.
+func realWallet(t *testing.T, s *Store, id int, seed byte) Wallet {
+	t.Helper()
+	secret := testKey(seed)
+	address, err := addressFromSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := Wallet{Address: address, Secret: secret}
+	s.mu.Lock()
+	p := s.players[id]
+	p.Wallet = w
+	s.players[id] = p
+	s.mu.Unlock()
+	return w
+}
+
+// Ligar o modo entrega a chave uma única vez e a apaga do servidor; as
+// cobranças assinadas pelo servidor passam a falhar. Para desligar, só
+// a chave da própria carteira é aceita.
+func TestSelfCustodyHandsKeyOver(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	ctx := context.Background()
+	id, _ := s.CreatePlayer(ctx)
+	w := realWallet(t, s, id, 1)
+
+	handover, err := s.SetSelfCustody(id, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handover != w.Secret {
+		t.Fatalf("handover = %q, want the wallet key", handover)
+	}
+	if again, err := s.SetSelfCustody(id, true, ""); err != nil || again != "" {
+		t.Errorf("second enable returned %q, %v; want no key", again, err)
+	}
+	p, _ := s.getPlayer(id)
+	if p.Wallet.Secret != "" {
+		t.Error("server kept the wallet key after the handover")
+	}
+	if _, err := s.StartOpenPack(ctx, id, "seed", nil); !errors.Is(err, ErrSelfCustody) {
+		t.Errorf("StartOpenPack in self-custody: %v, want ErrSelfCustody", err)
+	}
+	if _, err := s.SendTokens(ctx, id, 0, "0x"+strings.Repeat("ab", 32), 10); !errors.Is(err, ErrSelfCustody) {
+		t.Errorf("SendTokens in self-custody: %v, want ErrSelfCustody", err)
+	}
+
+	if _, err := s.SetSelfCustody(id, false, testKey(2)); err == nil {
+		t.Error("disable accepted the key of another wallet")
+	}
+	if _, err := s.SetSelfCustody(id, false, w.Secret); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.getPlayer(id); p.SelfCustody || p.Wallet.Secret != w.Secret {
+		t.Errorf("after disable: self-custody %v, key restored %v", p.SelfCustody, p.Wallet.Secret == w.Secret)
+	}
+}
+
+func TestSetSelfCustodyRejectsBanned(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	id, _ := s.CreatePlayer(context.Background())
+	if err := s.BanPlayer(id, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetSelfCustody(id, true, ""); !errors.Is(err, ErrBanned) {
+		t.Errorf("SetSelfCustody for a banned player: %v, want ErrBanned", err)
+	}
+}
+
+// Com a carta presa em uma partida ante, a chave ainda é necessária no
+// servidor para entregar a carta a quem vencer.
+func TestSetSelfCustodyWaitsForAnte(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	id, _ := s.CreatePlayer(context.Background())
+	s.mu.Lock()
+	s.anteLocks["0xcard"] = id
+	s.mu.Unlock()
+	if _, err := s.SetSelfCustody(id, true, ""); err == nil {
+		t.Error("self-custody enabled with a staked ante card")
+	}
+	if p, _ := s.getPlayer(id); p.SelfCustody || p.Wallet.Secret == "" {
+		t.Error("refused enable changed the player")
+	}
+}
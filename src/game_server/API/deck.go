@@ -0,0 +1,312 @@
+package API
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- DECKS ---
+
+// Tamanho padrão de um deck (todas as cartas são obrigatórias).
+const defaultDeckSize = 3
+
+// Tamanho máximo do nome de um deck.
+const maxDeckName = 32
+
+// Deck é um conjunto nomeado de cartas (ObjectIDs) do jogador. Apenas
+// cartas do deck ativo podem ser jogadas em partidas.
+type Deck struct {
+	Name  string   `json:"name"`
+	Cards []string `json:"cards"`
+}
+
+// Carta de um deck com a força conhecida no cache do jogador.
+type DeckCard struct {
+	ID    string `json:"id"`
+	Power int    `json:"power"`
+}
+
+// DeckInfo descreve um deck para o cliente, indicando se ele ainda é
+// válido (cartas possuídas, tamanho e limite de força).
+type DeckInfo struct {
+	Name       string     `json:"name"`
+	Cards      []DeckCard `json:"cards"`
+	TotalPower int        `json:"total_power"`
+	Active     bool       `json:"active"`
+	Valid      bool       `json:"valid"`
+	Err        string     `json:"err,omitempty"`
+}
+
+// Regras atuais de montagem, enviadas junto da lista de decks.
+type DeckRules struct {
+	Size     int `json:"size"`
+	PowerCap int `json:"power_cap,omitempty"`
+}
+
+func (s *Store) DeckRules() DeckRules {
+	return DeckRules{Size: s.DeckSize, PowerCap: s.DeckPowerCap}
+}
+
+// checkDeckLocked valida o deck contra as regras e o cache de cartas do
+// jogador. Retorna a força total. Exige s.mu travado.
+func (s *Store) checkDeckLocked(p Player, cards []string) (int, error) {
+	if len(cards) != s.DeckSize {
+		return 0, fmt.Errorf("o deck deve ter exatamente %d cartas", s.DeckSize)
+	}
+
+	total := 0
+	seen := map[string]bool{}
+	for _, id := range cards {
+		if seen[id] {
+			return 0, fmt.Errorf("carta repetida no deck: %s", id)
+		}
+		seen[id] = true
+
+		power, owned := p.Cards[id]
+		if !owned {
+			return 0, fmt.Errorf("carta %s não está na sua coleção", id)
+		}
+		total += power
+	}
+
+	if s.DeckPowerCap > 0 && total > s.DeckPowerCap {
+		return 0, fmt.Errorf("força total %d excede o limite de %d", total, s.DeckPowerCap)
+	}
+	return total, nil
+}
+
+// validateDeck confere o deck; se alguma carta não está no cache, o
+// cache é ressincronizado com a blockchain antes de recusar.
+func (s *Store) validateDeck(ctx context.Context, id int, cards []string) error {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	_, err = s.checkDeckLocked(player, cards)
+	s.mu.Unlock()
+	if err == nil {
+		return nil
+	}
+
+	if _, syncErr := s.SyncCards(ctx, id); syncErr != nil {
+		return err
+	}
+	if player, err = s.getPlayer(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.checkDeckLocked(player, cards)
+	return err
+}
+
+func deckName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxDeckName {
+		return "", fmt.Errorf("nome do deck deve ter de 1 a %d caracteres", maxDeckName)
+	}
+	return name, nil
+}
+
+// SaveDeck cria (create=true) ou altera um deck. Em uma edição, newName
+// (opcional) renomeia o deck, mantendo-o ativo se ele era o ativo.
+func (s *Store) SaveDeck(ctx context.Context, id int, name, newName string, cards []string, create bool) error {
+	name, err := deckName(name)
+	if err != nil {
+		return err
+	}
+	target := name
+	if !create && newName != "" {
+		if target, err = deckName(newName); err != nil {
+			return err
+		}
+	}
+
+	if err := s.validateDeck(ctx, id, cards); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.players[id]
+	if !ok {
+		return fmt.Errorf("player not found")
+	}
+	_, exists := p.Decks[name]
+	switch {
+	case create && exists:
+		return fmt.Errorf("já existe um deck chamado %q", name)
+	case !create && !exists:
+		return fmt.Errorf("deck %q não encontrado", name)
+	}
+	if _, taken := p.Decks[target]; target != name && taken {
+		return fmt.Errorf("já existe um deck chamado %q", target)
+	}
+
+	decks := make(map[string]Deck, len(p.Decks)+1)
+	for k, v := range p.Decks {
+		if k != name {
+			decks[k] = v
+		}
+	}
+	decks[target] = Deck{Name: target, Cards: append([]string(nil), cards...)}
+	p.Decks = decks
+	if p.ActiveDeck == name {
+		p.ActiveDeck = target
+	}
+	s.players[id] = p
+	return nil
+}
+
+// DeleteDeck remove um deck; se era o ativo, o jogador fica sem deck ativo.
+func (s *Store) DeleteDeck(id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.players[id]
+	if !ok {
+		return fmt.Errorf("player not found")
+	}
+	if _, exists := p.Decks[name]; !exists {
+		return fmt.Errorf("deck %q não encontrado", name)
+	}
+
+	decks := make(map[string]Deck, len(p.Decks))
+	for k, v := range p.Decks {
+		if k != name {
+			decks[k] = v
+		}
+	}
+	p.Decks = decks
+	if p.ActiveDeck == name {
+		p.ActiveDeck = ""
+	}
+	s.players[id] = p
+	return nil
+}
+
+// SelectDeck define o deck ativo usado no matchmaking, após validá-lo.
+func (s *Store) SelectDeck(ctx context.Context, id int, name string) error {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return err
+	}
+	deck, exists := player.Decks[name]
+	if !exists {
+		return fmt.Errorf("deck %q não encontrado", name)
+	}
+	if err := s.validateDeck(ctx, id, deck.Cards); err != nil {
+		return fmt.Errorf("deck inválido: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.players[id]
+	p.ActiveDeck = name
+	s.players[id] = p
+	return nil
+}
+
+// Decks lista os decks do jogador, ordenados por nome, com a validade
+// de cada um frente ao cache atual de cartas.
+func (s *Store) Decks(id int) ([]DeckInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.players[id]
+	if !ok {
+		return nil, fmt.Errorf("player not found")
+	}
+
+	out := make([]DeckInfo, 0, len(p.Decks))
+	for _, d := range p.Decks {
+		out = append(out, s.deckInfoLocked(p, d))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *Store) deckInfoLocked(p Player, d Deck) DeckInfo {
+	info := DeckInfo{Name: d.Name, Active: p.ActiveDeck == d.Name, Cards: []DeckCard{}}
+	for _, id := range d.Cards {
+		info.Cards = append(info.Cards, DeckCard{ID: id, Power: p.Cards[id]})
+	}
+
+	total, err := s.checkDeckLocked(p, d.Cards)
+	info.TotalPower = total
+	info.Valid = err == nil
+	if err != nil {
+		info.Err = err.Error()
+	}
+	return info
+}
+
+// activeDeckLocked retorna o deck ativo do jogador, exigindo que ele
+// continue válido (as cartas podem ter sido trocadas ou presenteadas).
+// Exige s.mu travado.
+func (s *Store) activeDeckLocked(id int) (Deck, error) {
+	p, ok := s.players[id]
+	if !ok {
+		return Deck{}, fmt.Errorf("player not found")
+	}
+	deck, exists := p.Decks[p.ActiveDeck]
+	if p.ActiveDeck == "" || !exists {
+		return Deck{}, fmt.Errorf("selecione um deck ativo antes de buscar partida")
+	}
+	if _, err := s.checkDeckLocked(p, deck.Cards); err != nil {
+		return Deck{}, fmt.Errorf("deck %q inválido: %v", deck.Name, err)
+	}
+	return deck, nil
+}
+
+// --- TÓPICOS topic.deck.* ---
+
+type deckRequest struct {
+	ClientID int      `json:"client_id"`
+	Name     string   `json:"name"`
+	NewName  string   `json:"new_name"`
+	Cards    []string `json:"cards"`
+}
+
+type deckCommand func(ctx context.Context, s *Store, req deckRequest) (any, error)
+
+// Comandos disponíveis, pelo sufixo do tópico (topic.deck.<comando>).
+var deckCommands = map[string]deckCommand{
+	"list": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		decks, err := s.Decks(req.ClientID)
+		return map[string]any{"decks": decks, "rules": s.DeckRules()}, err
+	},
+	"create": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		return map[string]any{"name": req.Name}, s.SaveDeck(ctx, req.ClientID, req.Name, "", req.Cards, true)
+	},
+	"edit": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		return map[string]any{"name": req.Name}, s.SaveDeck(ctx, req.ClientID, req.Name, req.NewName, req.Cards, false)
+	},
+	"delete": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		return map[string]any{"name": req.Name}, s.DeleteDeck(req.ClientID, req.Name)
+	},
+	"select": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		return map[string]any{"active": req.Name}, s.SelectDeck(ctx, req.ClientID, req.Name)
+	},
+	"validate": func(ctx context.Context, s *Store, req deckRequest) (any, error) {
+		err := s.validateDeck(ctx, req.ClientID, req.Cards)
+		if err != nil {
+			return map[string]any{"valid": false, "err": err.Error()}, nil
+		}
+		return map[string]any{"valid": true}, nil
+	},
+}
+
+// ClientDecks atende topic.deck.<comando> (list, create, edit, delete,
+// select, validate). A resposta é {"result": ...} ou {"err": ...}.
+func ClientDecks(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	return serveCommands(nc, s, "topic.deck.", deckCommands, nil)
+}
@@ -0,0 +1,320 @@
+package API
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// --- SORTEIO VERIFICÁVEL (PROVABLY FAIR) ---
+//
+// A cada época o servidor sorteia uma semente secreta e publica apenas
+// o compromisso sha256(semente). Cada pacote é derivado de
+// HMAC-SHA256(semente, "<jogador>:<semente do cliente>:<nonce>"), onde a
+// semente do cliente é escolhida pelo jogador e o nonce é o contador de
+// pacotes do próprio jogador, que ele consulta (com o compromisso) antes
+// de escolher a semente. O sorteio acontece no pedido, antes da cobrança,
+// e pode exigir que compromisso e nonce ainda sejam os que o jogador viu:
+// o servidor não escolhe a ordem nem a época do sorteio. Ao fim da época
+// a semente é revelada e qualquer jogador pode conferir o compromisso e
+// recalcular os próprios pacotes.
+
+const (
+	// Força máxima de uma carta (o pool original tinha cartas 1..900).
+	maxCardPower = 900
+
+	// Sorteios por época antes de a semente ser revelada e trocada.
+	fairEpochDraws = 20
+
+	// Épocas reveladas mantidas para consulta.
+	fairRevealedKept = 50
+
+	// Tamanho máximo da semente do cliente.
+	maxClientSeed = 64
+)
+
+// Época corrente; Seed (hex) é secreta até a revelação.
+type fairEpoch struct {
+	Number     int    `json:"number"`
+	Seed       string `json:"seed"`
+	Commitment string `json:"commitment"`
+	Draws      int    `json:"draws"`
+}
+
+// sealedEpoch é a época como vai no snapshot replicado. O bucket de
+// estado pode ser lido por qualquer cliente NATS, então a semente só sai
+// do processo cifrada com a chave de estado (SealedSeed); sem chave ela
+// não é replicada. LegacySeed lê snapshots antigos, gravados em claro.
+type sealedEpoch struct {
+	Number     int    `json:"number"`
+	SealedSeed string `json:"sealed_seed,omitempty"`
+	LegacySeed string `json:"seed,omitempty"`
+	Commitment string `json:"commitment"`
+	Draws      int    `json:"draws"`
+}
+
+// ErrNoStateKey indica que o servidor não tem a chave de estado.
+var ErrNoStateKey = errors.New("chave de estado (STATE_KEY) não configurada")
+
+// SetStateKey define o segredo compartilhado pelos servidores do cluster
+// usado para cifrar a semente e as chaves das carteiras no snapshot
+// (vazio = não replicar esses segredos).
+func (s *Store) SetStateKey(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret == "" {
+		s.stateKey = nil
+		return
+	}
+	key := sha256.Sum256([]byte(secret))
+	s.stateKey = key[:]
+}
+
+// stateAEAD prepara a cifra de um segredo do snapshot. O nonce vem de
+// label, que identifica o segredo (o compromisso da época, o endereço da
+// carteira): o mesmo segredo sempre gera o mesmo texto cifrado e o
+// snapshot só muda quando o segredo muda.
+func stateAEAD(key []byte, label string) (cipher.AEAD, []byte, error) {
+	if key == nil {
+		return nil, nil, ErrNoStateKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := sha256.Sum256([]byte(label))
+	return aead, nonce[:aead.NonceSize()], nil
+}
+
+// sealEpochLocked prepara a época corrente para o snapshot. Exige s.mu travado.
+func (s *Store) sealEpochLocked() sealedEpoch {
+	sealed := sealedEpoch{Number: s.fair.Number, Commitment: s.fair.Commitment, Draws: s.fair.Draws}
+
+	aead, nonce, err := stateAEAD(s.stateKey, "fair-epoch-nonce:"+s.fair.Commitment)
+	if err != nil {
+		return sealed
+	}
+	seed, err := hex.DecodeString(s.fair.Seed)
+	if err != nil {
+		return sealed
+	}
+	sealed.SealedSeed = hex.EncodeToString(aead.Seal(nil, nonce, seed, []byte(s.fair.Commitment)))
+	return sealed
+}
+
+// openEpochLocked recupera a época de um snapshot, conferindo a semente
+// contra o compromisso publicado. Exige s.mu travado.
+func (s *Store) openEpochLocked(sealed sealedEpoch) (fairEpoch, error) {
+	epoch := fairEpoch{Number: sealed.Number, Commitment: sealed.Commitment, Draws: sealed.Draws}
+
+	var seed []byte
+	var err error
+	switch {
+	case sealed.SealedSeed != "":
+		var aead cipher.AEAD
+		var nonce, data []byte
+		if aead, nonce, err = stateAEAD(s.stateKey, "fair-epoch-nonce:"+sealed.Commitment); err != nil {
+			return epoch, err
+		}
+		if data, err = hex.DecodeString(sealed.SealedSeed); err != nil {
+			return epoch, err
+		}
+		if seed, err = aead.Open(nil, nonce, data, []byte(sealed.Commitment)); err != nil {
+			return epoch, fmt.Errorf("semente cifrada com outra chave: %v", err)
+		}
+	case sealed.LegacySeed != "":
+		if seed, err = hex.DecodeString(sealed.LegacySeed); err != nil {
+			return epoch, err
+		}
+	default:
+		return epoch, fmt.Errorf("semente não replicada")
+	}
+
+	if sum := sha256.Sum256(seed); hex.EncodeToString(sum[:]) != sealed.Commitment {
+		return epoch, fmt.Errorf("semente não confere com o compromisso")
+	}
+	epoch.Seed = hex.EncodeToString(seed)
+	return epoch, nil
+}
+
+// FairEpochInfo é a visão pública de uma época: Seed só é preenchida
+// depois que a época foi encerrada.
+type FairEpochInfo struct {
+	Number     int    `json:"number"`
+	Commitment string `json:"commitment"`
+	Seed       string `json:"seed,omitempty"`
+	Draws      int    `json:"draws"`
+}
+
+// FairDraw é o comprovante de um sorteio, enviado ao jogador: com a
+// semente revelada, Pack pode ser recalculado a partir dos demais campos.
+type FairDraw struct {
+	Epoch      int    `json:"epoch"`
+	Commitment string `json:"commitment"`
+	PlayerID   int    `json:"player_id"`
+	ClientSeed string `json:"client_seed"`
+	Nonce      int    `json:"nonce"`
+	Pack       [3]int `json:"pack"`
+}
+
+// FairExpect é o que o jogador viu antes de escolher a semente: o
+// compromisso da época corrente e o próprio nonce. O sorteio é recusado
+// se algum deles mudou.
+type FairExpect struct {
+	Commitment string
+	Nonce      int
+}
+
+func newFairEpoch(number int) fairEpoch {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	commitment := sha256.Sum256(seed)
+	return fairEpoch{
+		Number:     number,
+		Seed:       hex.EncodeToString(seed),
+		Commitment: hex.EncodeToString(commitment[:]),
+	}
+}
+
+// fairPack deriva as três forças do pacote: cada carta usa 8 bytes do
+// HMAC, reduzidos ao intervalo 1..maxCardPower.
+func fairPack(seed []byte, playerID int, clientSeed string, nonce int) [3]int {
+	mac := hmac.New(sha256.New, seed)
+	fmt.Fprintf(mac, "%d:%s:%d", playerID, clientSeed, nonce)
+	sum := mac.Sum(nil)
+
+	var pack [3]int
+	for i := range pack {
+		pack[i] = int(binary.BigEndian.Uint64(sum[i*8:])%maxCardPower) + 1
+	}
+	return pack
+}
+
+func validClientSeed(clientSeed string) error {
+	if clientSeed == "" || len(clientSeed) > maxClientSeed {
+		return fmt.Errorf("semente do cliente deve ter de 1 a %d caracteres", maxClientSeed)
+	}
+	return nil
+}
+
+// drawPackLocked consome um pacote do estoque e sorteia seu conteúdo com
+// o próximo nonce do jogador. Com expect, o sorteio só acontece se a
+// época e o nonce ainda forem os que o jogador viu. Quando a época
+// atinge fairEpochDraws, a semente é revelada e trocada; a época
+// encerrada é retornada para ser anunciada (após liberar o lock).
+// Exige s.mu travado.
+func (s *Store) drawPackLocked(id int, clientSeed string, expect *FairExpect) (FairDraw, *FairEpochInfo, error) {
+	player, exists := s.players[id]
+	if !exists {
+		return FairDraw{}, nil, fmt.Errorf("player not found")
+	}
+	if s.packs <= 0 {
+		return FairDraw{}, nil, fmt.Errorf("no packs available")
+	}
+	if expect != nil {
+		if expect.Commitment != s.fair.Commitment {
+			return FairDraw{}, nil, fmt.Errorf("a época do sorteio mudou: consulte o novo compromisso e tente de novo")
+		}
+		if expect.Nonce != player.FairNonce {
+			return FairDraw{}, nil, fmt.Errorf("nonce %d não confere com o próximo sorteio (%d)", expect.Nonce, player.FairNonce)
+		}
+	}
+
+	seed, err := hex.DecodeString(s.fair.Seed)
+	if err != nil {
+		return FairDraw{}, nil, fmt.Errorf("semente da época corrompida: %v", err)
+	}
+
+	draw := FairDraw{
+		Epoch:      s.fair.Number,
+		Commitment: s.fair.Commitment,
+		PlayerID:   id,
+		ClientSeed: clientSeed,
+		Nonce:      player.FairNonce,
+		Pack:       fairPack(seed, id, clientSeed, player.FairNonce),
+	}
+	player.FairNonce++
+	s.players[id] = player
+	s.fair.Draws++
+	s.packs--
+
+	var revealed *FairEpochInfo
+	if s.fair.Draws >= fairEpochDraws {
+		revealed = s.rotateEpochLocked()
+	}
+	return draw, revealed, nil
+}
+
+// rotateEpochLocked revela a semente da época atual e inicia outra.
+func (s *Store) rotateEpochLocked() *FairEpochInfo {
+	old := FairEpochInfo{
+		Number:     s.fair.Number,
+		Commitment: s.fair.Commitment,
+		Seed:       s.fair.Seed,
+		Draws:      s.fair.Draws,
+	}
+	s.revealedEpochs = append(s.revealedEpochs, old)
+	if extra := len(s.revealedEpochs) - fairRevealedKept; extra > 0 {
+		s.revealedEpochs = s.revealedEpochs[extra:]
+	}
+	s.fair = newFairEpoch(old.Number + 1)
+	return &old
+}
+
+// Anuncia em fair.epoch a semente revelada e o novo compromisso.
+func (s *Store) announceEpoch(revealed *FairEpochInfo) {
+	if revealed == nil {
+		return
+	}
+	current, _ := s.FairEpochs()
+	slog.Info("fairness epoch rotated", "revealed_epoch", revealed.Number, "epoch", current.Number, "commitment", current.Commitment)
+
+	data, _ := json.Marshal(map[string]any{"revealed": revealed, "current": current})
+	s.pub.Publish("fair.epoch", data)
+}
+
+// FairEpochs retorna a época corrente (sem a semente) e as já reveladas.
+func (s *Store) FairEpochs() (FairEpochInfo, []FairEpochInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := FairEpochInfo{Number: s.fair.Number, Commitment: s.fair.Commitment, Draws: s.fair.Draws}
+	return current, append([]FairEpochInfo{}, s.revealedEpochs...)
+}
+
+// FairNonce retorna o nonce do próximo sorteio de pacote do jogador.
+func (s *Store) FairNonce(id int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[id]
+	if !exists {
+		return 0, fmt.Errorf("player not found")
+	}
+	return player.FairNonce, nil
+}
+
+// RotateSeed encerra a época antes do limite de sorteios, revelando a
+// semente (usado pelo administrador).
+func (s *Store) RotateSeed() FairEpochInfo {
+	s.mu.Lock()
+	revealed := s.rotateEpochLocked()
+	s.mu.Unlock()
+
+	s.announceEpoch(revealed)
+	return *revealed
+}
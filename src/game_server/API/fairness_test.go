@@ -0,0 +1,145 @@
+package API
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// recomputeDraw refaz, como um jogador faria, o pacote de um comprovante
+// a partir da semente revelada: confere o compromisso e recalcula
+// HMAC-SHA256(semente, "<jogador>:<semente do cliente>:<nonce>").
+func recomputeDraw(epoch FairEpochInfo, draw FairDraw) ([3]int, error) {
+	seed, err := hex.DecodeString(epoch.Seed)
+	if err != nil {
+		return [3]int{}, err
+	}
+	if sum := sha256.Sum256(seed); hex.EncodeToString(sum[:]) != draw.Commitment {
+		return [3]int{}, fmt.Errorf("revealed seed does not match commitment %s", draw.Commitment)
+	}
+	mac := hmac.New(sha256.New, seed)
+	fmt.Fprintf(mac, "%d:%s:%d", draw.PlayerID, draw.ClientSeed, draw.Nonce)
+	sum := mac.Sum(nil)
+
+	var pack [3]int
+	for i := range pack {
+		pack[i] = int(binary.BigEndian.Uint64(sum[i*8:])%maxCardPower) + 1
+	}
+	return pack, nil
+}
+
+// openWithReceipt abre um pacote e devolve o comprovante do sorteio.
+func openWithReceipt(t *testing.T, s *Store, id int, clientSeed string) FairDraw {
+	t.Helper()
+	var draw *FairDraw
+	_, err := s.OpenPack(context.Background(), id, clientSeed, func(ev PackEvent) {
+		if ev.Draw != nil {
+			draw = ev.Draw
+		}
+	})
+	if err != nil {
+		t.Fatalf("OpenPack(%d): %v", id, err)
+	}
+	if draw == nil {
+		t.Fatal("no drawn event")
+	}
+	return *draw
+}
+
+// Cada jogador usa o próprio contador de nonces, que não depende dos
+// sorteios dos outros, e cada pacote pode ser recalculado com a semente
+// revelada ao fim da época.
+func TestFairDrawsRecomputeFromRevealedSeed(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	a, _ := s.CreatePlayer(context.Background())
+	b, _ := s.CreatePlayer(context.Background())
+
+	var receipts []FairDraw
+	for i := range 3 {
+		if next, _ := s.FairNonce(a); next != i {
+			t.Fatalf("FairNonce before draw %d = %d", i, next)
+		}
+		receipts = append(receipts, openWithReceipt(t, s, a, "seed-a"))
+		// Sorteios de outro jogador entre os de a não mudam os nonces de a.
+		receipts = append(receipts, openWithReceipt(t, s, b, "seed-b"))
+	}
+	for i, d := range receipts {
+		if want := i / 2; d.Nonce != want {
+			t.Errorf("receipt %d (player %d) has nonce %d, want %d", i, d.PlayerID, d.Nonce, want)
+		}
+	}
+
+	revealed := s.RotateSeed()
+	for _, d := range receipts {
+		if d.Epoch != revealed.Number {
+			t.Fatalf("draw in epoch %d, revealed %d", d.Epoch, revealed.Number)
+		}
+		pack, err := recomputeDraw(revealed, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pack != d.Pack {
+			t.Errorf("player %d nonce %d: recomputed %v, received %v", d.PlayerID, d.Nonce, pack, d.Pack)
+		}
+	}
+}
+
+// O sorteio é recusado se a época ou o nonce não forem os que o jogador
+// viu, sem consumir pacote nem nonce.
+func TestStartOpenPackChecksExpectation(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	ctx := context.Background()
+	id, _ := s.CreatePlayer(ctx)
+	current, _ := s.FairEpochs()
+	s.mu.Lock()
+	packs := s.packs
+	s.mu.Unlock()
+
+	for _, expect := range []FairExpect{
+		{Commitment: "stale", Nonce: 0},
+		{Commitment: current.Commitment, Nonce: 1},
+	} {
+		if _, err := s.StartOpenPack(ctx, id, "seed", &expect); err == nil {
+			t.Errorf("StartOpenPack with %+v succeeded", expect)
+		}
+	}
+	s.mu.Lock()
+	left := s.packs
+	s.mu.Unlock()
+	if next, _ := s.FairNonce(id); next != 0 || left != packs {
+		t.Errorf("after refused draws: nonce %d, packs %d (want 0, %d)", next, left, packs)
+	}
+}
+
+// Cobrança recusada: o pacote volta ao estoque e o nonce fica consumido,
+// para que o próximo sorteio não repita o pacote já revelado.
+func TestOpenPackPaymentFailureReturnsPack(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	id, _ := s.CreatePlayer(context.Background())
+	p, _ := s.getPlayer(id)
+	bridge.failTransactionsFrom(p.Wallet.Address, errors.New("insufficient balance"))
+	s.mu.Lock()
+	packs := s.packs
+	s.mu.Unlock()
+
+	if _, err := s.OpenPack(context.Background(), id, "seed", func(PackEvent) {}); err == nil {
+		t.Fatal("OpenPack succeeded without payment")
+	}
+	s.mu.Lock()
+	left := s.packs
+	s.mu.Unlock()
+	if left != packs {
+		t.Errorf("packs = %d after failed payment, want %d", left, packs)
+	}
+	if next, _ := s.FairNonce(id); next != 1 {
+		t.Errorf("FairNonce = %d after failed payment, want 1", next)
+	}
+	if got := bridge.minted(); got != 0 {
+		t.Errorf("minted %d cards for an unpaid pack", got)
+	}
+}
@@ -1,50 +1,93 @@
 package API
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
 	"os"
-	"sync"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	"github.com/nats-io/nats.go"
 )
 
 // --- ESTRUTURAS ---
 
-// IdManager controla IDs de jogadores e armazena seus dados.
-// Usa mutex para permitir acesso concorrente seguro.
-type IdManager struct {
-	Mutex     sync.RWMutex
-	Count     int
-	ClientMap map[int]*Player
-}
-
 // Estrutura básica que representa uma partida ativa.
 // Usada para registrar jogadores, cartas enviadas e o ID único da partida.
+// Em partidas de treino (Practice) P2 é o bot (botID) com a estratégia
+// Bot; elas não são ranqueadas nem registradas na blockchain.
 type matchStruct struct {
-	SelfId string `json:"self_id"`
-	P1     int    `json:"p1"`
-	P2     int    `json:"p2"`
-	Card1  int    `json:"card1"`
-	Card2  int    `json:"card2"`
+	SelfId   string `json:"self_id"`
+	P1       int    `json:"p1"`
+	P2       int    `json:"p2"`
+	Card1    int    `json:"card1"`
+	Card2    int    `json:"card2"`
+	Practice bool   `json:"practice,omitempty"`
+	Bot      string `json:"bot,omitempty"`
+
+	// ID do torneio, quando a partida faz parte de uma chave.
+	Tournament string `json:"tournament,omitempty"`
+
+	// Aposta de cada jogador em IOTA, já retida na carteira da loja
+	// (0 = partida sem aposta).
+	Wager uint64 `json:"wager,omitempty"`
+
+	// Partida ante: a carta travada de cada jogador; a do perdedor vai
+	// para o vencedor.
+	Ante      bool   `json:"ante,omitempty"`
+	AnteCard1 string `json:"ante_card1,omitempty"`
+	AnteCard2 string `json:"ante_card2,omitempty"`
+}
+
+// playerView é a partida como enviada aos jogadores: as cartas jogadas
+// ficam de fora, para ninguém ver a jogada do adversário antes da sua.
+func (m matchStruct) playerView() matchStruct {
+	m.Card1, m.Card2 = 0, 0
+	return m
 }
 
 // Representa um jogador do servidor: ID, carteira blockchain e suas cartas.
 // O mapa Cards armazena "ObjectID da blockchain → poder da carta".
+// LinkedWallets guarda endereços externos cuja posse o jogador provou
+// assinando um desafio; o servidor nunca conhece a chave deles.
+// Com SelfCustody ligado, as transferências de cartas da carteira
+// principal também são assinadas pelo cliente. Jogadores banidos por
+// um administrador (Banned) não podem logar nem operar. Decks guarda os
+// decks montados pelo jogador; só cartas de ActiveDeck entram em partidas.
 type Player struct {
-	Id     int
-	Wallet Wallet
-	Cards  map[string]int 
+	Id            int
+	Wallet        Wallet
+	Cards         map[string]int
+	LinkedWallets []string
+	SelfCustody   bool
+	Banned        bool
+	Decks         map[string]Deck
+	ActiveDeck    string
+	FairNonce     int // Nonce do próximo sorteio de pacote do jogador
 }
 
-// Armazena todos os pacotes de cartas que podem ser sorteados ao abrir packs.
-type PackStorage struct {
-	Mutex sync.Mutex
-	Cards [][3]int
+// clone devolve uma cópia independente do jogador. Cards, Decks e
+// LinkedWallets pertencem à Store e só são alterados com s.mu travado;
+// todo Player que sai da Store deve ser um clone.
+func (p Player) clone() Player {
+	cards := make(map[string]int, len(p.Cards))
+	for k, v := range p.Cards {
+		cards[k] = v
+	}
+	p.Cards = cards
+	p.LinkedWallets = append([]string(nil), p.LinkedWallets...)
+
+	if p.Decks != nil {
+		decks := make(map[string]Deck, len(p.Decks))
+		for k, d := range p.Decks {
+			decks[k] = Deck{Name: d.Name, Cards: append([]string(nil), d.Cards...)}
+		}
+		p.Decks = decks
+	}
+	return p
 }
 
 // Estrutura usada no sistema de troca cega (Blind Trade).
@@ -59,12 +102,6 @@ type TradeProposal struct {
 
 // --- VARIÁVEIS GLOBAIS ---
 
-// Gerenciador de IDs de jogadores
-var IM = IdManager{Count: 0, ClientMap: map[int]*Player{}}
-
-// Armazena todos os packs já pré-gerados
-var STO = PackStorage{Cards: setupPacks(900)}
-
 // Endereço da carteira da loja (carregado via .env)
 var ServerWalletAddress string
 
@@ -76,153 +113,367 @@ func init() {
 
 	// Mensagem de aviso caso o .env não contenha a carteira
 	if ServerWalletAddress == "" {
-		log.Println("⚠️ AVISO: Endereço da carteira não encontrado no .env")
+		slog.Warn("store wallet address not found in .env")
 	} else {
-		fmt.Println("✅ Carteira da Loja Carregada:", ServerWalletAddress)
+		slog.Info("store wallet loaded", "address", ServerWalletAddress)
 	}
 }
 
 // --- CONFIGURAÇÃO DE PACOTES ---
 
-// Gera uma lista de N cartas numeradas e divide em pacotes de 3.
-// Cada pack contém 3 IDs consecutivos, usados ao abrir pacotes.
-func setupPacks(N int) [][3]int {
-	arr := make([]int, N)
-	for i := range N {
-		arr[i] = i + 1
-	}
-	numPacks := (N + 3 - 1) / 3
-	packs := make([][3]int, numPacks)
-
-	for i := range numPacks {
-		start := i * 3
-		end := start + 3
-		sliceEnd := min(end, N)
-		sourceSlice := arr[start:sliceEnd]
-		copy(packs[i][:], sourceSlice)
-	}
-	return packs
-}
+// Pacotes à venda quando o servidor inicia (o antigo pool de 900 cartas).
+const initialPacks = 300
 
 // --- STORE METHODS ---
 
 // Cria um novo jogador no sistema, gera uma carteira blockchain
-// e armazena tudo na Store.
-func (s *Store) CreatePlayer(nc *nats.Conn) (int, error) {
+// e armazena tudo na Store. A criação é transacional: se a carteira
+// não puder ser provisionada, nenhum jogador é criado e nenhum ID é gasto.
+func (s *Store) CreatePlayer(ctx context.Context) (int, error) {
+	done, err := s.beginWork("createPlayer")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	wallet, err := s.provisionWallet(ctx)
+	if err != nil {
+		slog.Error("wallet provisioning failed", "err", err)
+		return 0, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.count += 1
 	newPlayer := Player{
 		Id:     s.count,
-		Wallet: RequestCreateWallet(nc),
+		Wallet: wallet,
 		Cards:  make(map[string]int),
 	}
 
 	s.players[newPlayer.Id] = newPlayer
-	fmt.Println("[Central] Player Created:", newPlayer.Id, newPlayer.Wallet.Address)
+	slog.Info("player created", logPlayer, newPlayer.Id, "address", newPlayer.Wallet.Address)
 
 	return newPlayer.Id, nil
 }
 
-// Abre um pacote de 3 cartas:
-// 1) cobra o jogador via blockchain,
-// 2) sorteia um pack,
-// 3) mint das cartas na blockchain,
-// 4) salva as cartas no cache local do jogador.
-func (s *Store) OpenPack(nc *nats.Conn, id int) (*[3]int, error) {
+// Tentativas de criação de carteira e espera inicial entre elas (dobra a cada falha).
+const (
+	walletAttempts = 3
+	walletBackoff  = 1 * time.Second
+)
+
+// Cria uma carteira no worker, repetindo com backoff em caso de falha.
+// Um timeout pode deixar para trás uma carteira órfã já financiada,
+// o que é aceitável: o pior caso é gastar tokens de teste do faucet.
+func (s *Store) provisionWallet(ctx context.Context) (Wallet, error) {
+	var err error
+	for attempt := 0; attempt < walletAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return Wallet{}, ctx.Err()
+			case <-time.After(walletBackoff << (attempt - 1)):
+			}
+			slog.Warn("retrying wallet creation", "attempt", attempt+1, "max_attempts", walletAttempts, "err", err)
+		}
+
+		var wallet Wallet
+		if wallet, err = s.bridge.CreateWallet(ctx); err == nil {
+			return wallet, nil
+		}
+	}
+	return Wallet{}, err
+}
+
+// RepairWallets cria carteiras para jogadores antigos que ficaram com
+// endereço vazio (contas criadas antes da criação ser transacional).
+// Retorna os IDs reparados e o erro de cada jogador que ainda falhou.
+func (s *Store) RepairWallets(ctx context.Context) ([]int, map[int]error) {
 	s.mu.Lock()
-	player, exists := s.players[id]
+	var broken []int
+	for id, p := range s.players {
+		if p.Wallet.Address == "" {
+			broken = append(broken, id)
+		}
+	}
 	s.mu.Unlock()
+	sort.Ints(broken)
 
-	if !exists {
-		return nil, fmt.Errorf("player not found")
+	repaired := []int{}
+	failed := map[int]error{}
+	for _, id := range broken {
+		wallet, err := s.provisionWallet(ctx)
+		if err != nil {
+			failed[id] = err
+			continue
+		}
+
+		s.mu.Lock()
+		p, ok := s.players[id]
+		if ok && p.Wallet.Address == "" {
+			p.Wallet = wallet
+			s.players[id] = p
+			repaired = append(repaired, id)
+		}
+		s.mu.Unlock()
+		slog.Info("wallet repaired", logPlayer, id, "address", wallet.Address)
 	}
+	return repaired, failed
+}
 
-	// --- ETAPA 1: Cobrança blockchain ---
-	serverWallet := Wallet{Address: ServerWalletAddress}
+// Evento de progresso da abertura de um pacote, publicado em
+// player.<id>.pack. Stage: "paid" (pagamento confirmado), "drawn"
+// (Draw com o comprovante do sorteio), "minted"
+// (carta Card de Total; Err preenchido se o mint falhou), "completed"
+// (Cards com as forças obtidas) ou "failed".
+type PackEvent struct {
+	JobID    string    `json:"job_id"`
+	Stage    string    `json:"stage"`
+	Digest   string    `json:"digest,omitempty"`
+	Card     int       `json:"card,omitempty"`
+	Total    int       `json:"total,omitempty"`
+	Power    int       `json:"power,omitempty"`
+	ObjectID string    `json:"object_id,omitempty"`
+	Cards    []int     `json:"cards,omitempty"`
+	Draw     *FairDraw `json:"draw,omitempty"`
+	Err      string    `json:"err,omitempty"`
+}
+
+// StartOpenPack valida o pedido, sorteia o pacote e dispara a cobrança e
+// o mint em segundo plano, retornando o ID do job. O andamento chega ao
+// jogador pelos eventos PackEvent em player.<id>.pack. clientSeed é a
+// semente do jogador usada no sorteio verificável; expect (opcional) é
+// a época e o nonce que ele viu antes de escolhê-la.
+func (s *Store) StartOpenPack(ctx context.Context, id int, clientSeed string, expect *FairExpect) (string, error) {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return "", err
+	}
+	if _, err := spendable(player); err != nil {
+		return "", err
+	}
+	if err := validClientSeed(clientSeed); err != nil {
+		return "", err
+	}
+
+	jobID := uuid.New().String()
+
+	// Pagamento e mints não podem ser interrompidos pelo encerramento.
+	done, err := s.beginWork(fmt.Sprintf("openPack player=%d job=%s", id, jobID))
+	if err != nil {
+		return "", err
+	}
+
+	// O sorteio acontece já no pedido, com a época e o nonce que o
+	// jogador viu: a demora da cobrança não muda o resultado.
+	draw, err := s.drawPack(id, clientSeed, expect)
+	if err != nil {
+		done()
+		return "", err
+	}
+
+	// O job continua depois que a requisição do jogador foi respondida.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer done()
 
-	fmt.Printf("💰 Cobrando 1000 IOTA de %d...\n", id)
-	sucesso := RequestTransaction(nc, player.Wallet, serverWallet, 1000)
+		progress := func(ev PackEvent) {
+			ev.JobID = jobID
+			s.notify(id, "pack", ev)
+		}
+		if _, err := s.openDrawnPack(ctx, id, draw, progress); err != nil {
+			slog.Error("pack opening failed", logPlayer, id, "job_id", jobID, "err", err)
+			progress(PackEvent{Stage: "failed", Err: err.Error()})
+		}
+	}()
+
+	return jobID, nil
+}
 
-	if !sucesso {
-		return nil, fmt.Errorf("saldo insuficiente ou erro na transação")
+// Abre um pacote de 3 cartas:
+// 1) sorteia o conteúdo do pack (verificável, ver fairness.go),
+// 2) cobra o jogador via blockchain,
+// 3) mint das cartas na blockchain,
+// 4) salva as cartas no cache local do jogador.
+// Cada etapa concluída é informada em progress; o evento "completed"
+// é enviado aqui, o "failed" fica a cargo de quem trata o erro.
+func (s *Store) OpenPack(ctx context.Context, id int, clientSeed string, progress func(PackEvent)) (*[3]int, error) {
+	if _, err := s.getPlayer(id); err != nil {
+		return nil, err
 	}
+	draw, err := s.drawPack(id, clientSeed, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.openDrawnPack(ctx, id, draw, progress)
+}
 
-	// --- ETAPA 2: Sorteio aleatório de pack ---
+// drawPack sorteia o pacote do jogador (etapa 1 de OpenPack) e anuncia a
+// época encerrada, se o sorteio fechou uma.
+func (s *Store) drawPack(id int, clientSeed string, expect *FairExpect) (FairDraw, error) {
 	s.mu.Lock()
-	if len(s.Cards) == 0 {
+	draw, revealed, err := s.drawPackLocked(id, clientSeed, expect)
+	s.mu.Unlock()
+	if err != nil {
+		return FairDraw{}, err
+	}
+
+	slog.Info("pack drawn", logPlayer, id, "epoch", draw.Epoch, "nonce", draw.Nonce, "pack", draw.Pack)
+	s.announceEpoch(revealed)
+	return draw, nil
+}
+
+// openDrawnPack cobra e entrega um pacote já sorteado (etapas 2 a 4 de
+// OpenPack). Se a cobrança falhar, o pacote volta ao estoque; o nonce
+// do jogador fica consumido.
+func (s *Store) openDrawnPack(ctx context.Context, id int, draw FairDraw, progress func(PackEvent)) (*[3]int, error) {
+	unsold := func(err error) (*[3]int, error) {
+		s.mu.Lock()
+		s.packs++
 		s.mu.Unlock()
-		return nil, fmt.Errorf("no packs available")
+		slog.Warn("pack returned to stock", logPlayer, id, "nonce", draw.Nonce, "err", err)
+		return nil, err
 	}
 
-	i := rand.Intn(len(s.Cards))
-	lastIndex := len(s.Cards) - 1
-	pack := s.Cards[i]
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return unsold(err)
+	}
 
-	s.Cards[i] = s.Cards[lastIndex]
-	s.Cards = s.Cards[:lastIndex]
-	s.mu.Unlock()
+	// --- ETAPA 2: Cobrança blockchain ---
+	serverWallet := Wallet{Address: ServerWalletAddress}
+
+	slog.Info("charging pack", logPlayer, id, "amount", PackPrice)
+	wallet, err := spendable(player)
+	if err != nil {
+		return unsold(err)
+	}
+	digest, err := s.bridge.Transaction(ctx, wallet, serverWallet, PackPrice)
+	if err != nil {
+		return unsold(err)
+	}
+	progress(PackEvent{Stage: "paid", Digest: digest})
+
+	pack := draw.Pack
+	progress(PackEvent{Stage: "drawn", Draw: &draw})
 
 	// --- ETAPA 3: Mint das cartas ---
-	fmt.Println("⚡ Minting cards on blockchain...")
-	
+	slog.Debug("minting pack", logPlayer, id, "pack", pack)
+
 	newCards := make(map[string]int)
+	minted := []int{}
 
-	for _, cardVal := range pack {
-		digest, objectId, err := RequestMintCard(nc, player.Wallet.Address, cardVal)
-		
-		if err != nil {
-			log.Println("❌ Falha no Mint:", err)
+	for n, m := range s.mintPack(ctx, player.Wallet.Address, pack) {
+		cardVal := pack[n]
+		ev := PackEvent{Stage: "minted", Card: n + 1, Total: len(pack), Power: cardVal}
+
+		if m.err != nil {
+			slog.Error("mint failed", logPlayer, id, "power", cardVal, "err", m.err)
+			ev.Err = m.err.Error()
 		} else {
-			fmt.Printf("✅ Carta %d criada! ID: %s (Digest: %s)\n", cardVal, objectId, digest)
-			if objectId != "" {
-				newCards[objectId] = cardVal
+			slog.Info("card minted", logPlayer, id, "power", cardVal, logObject, m.objectID, logDigest, m.digest)
+			if m.objectID != "" {
+				newCards[m.objectID] = cardVal
 			}
+			minted = append(minted, cardVal)
+			ev.Digest, ev.ObjectID = m.digest, m.objectID
 		}
+		progress(ev)
 	}
 
 	// --- ETAPA 4: Atualiza cache local do jogador ---
 	s.mu.Lock()
-	p := s.players[id]
-	for k, v := range newCards {
-		p.Cards[k] = v
-	}
-	s.players[id] = p
+	s.updateCardsLocked(id, func(cards map[string]int) {
+		for k, v := range newCards {
+			cards[k] = v
+		}
+	})
 	s.mu.Unlock()
 
+	// Com falha no mint não se sabe quais cartas chegaram à carteira:
+	// a blockchain é a fonte da verdade.
+	if len(minted) < len(pack) {
+		if _, err := s.SyncCards(ctx, id); err != nil {
+			slog.Warn("card sync after mint failure failed", logPlayer, id, "err", err)
+		}
+	}
+
+	progress(PackEvent{Stage: "completed", Cards: minted})
 	return &pack, nil
 }
 
+// Resultado do mint de uma carta do pacote.
+type mintResult struct {
+	digest   string
+	objectID string
+	err      error
+}
+
+// mintPack cunha as cartas do pacote em uma única transação (mintBatch).
+// Se o lote com certeza não foi executado (worker sem suporte ou
+// ErrNotExecuted), recorre ao mint carta a carta. Em qualquer outro erro
+// (timeout, resposta inválida, IDs faltando) o lote pode ter sido
+// executado, então não há fallback para não duplicar: o erro volta a
+// quem chamou, que ressincroniza as cartas com a blockchain.
+func (s *Store) mintPack(ctx context.Context, address string, pack [3]int) []mintResult {
+	results := make([]mintResult, len(pack))
+
+	req := make([]MintReq, len(pack))
+	for i, v := range pack {
+		req[i] = MintReq{Address: address, Value: uint64(v)}
+	}
+
+	digest, ids, err := s.bridge.MintBatch(ctx, req)
+	if err == nil {
+		for i, objectID := range ids {
+			results[i] = mintResult{digest: digest, objectID: objectID}
+		}
+		return results
+	}
+
+	if !errors.Is(err, ErrBridgeUnavailable) && !errors.Is(err, ErrNotExecuted) {
+		for i := range results {
+			results[i].err = err
+		}
+		return results
+	}
+
+	slog.Warn("batch mint failed, minting cards one by one", "address", address, "err", err)
+	for i, v := range pack {
+		digest, objectID, err := s.bridge.MintCard(ctx, address, v)
+		results[i] = mintResult{digest: digest, objectID: objectID, err: err}
+	}
+	return results
+}
+
 // --- LÓGICA DE TROCA CEGRA (BLIND TRADE) ---
 // Jogador entra na fila de troca: valida propriedade via blockchain,
 // remove a carta do cache, e aguarda Pareamento.
-func (s *Store) JoinBlindTrade(nc *nats.Conn, playerID int, cardHex string) error {
-	s.mu.Lock()
-	player, exists := s.players[playerID]
-	s.mu.Unlock()
-
-	if !exists {
-		return fmt.Errorf("jogador não encontrado")
+func (s *Store) JoinBlindTrade(ctx context.Context, playerID int, cardHex string) error {
+	player, err := s.getPlayer(playerID)
+	if err != nil {
+		return err
+	}
+	if s.isClosing() {
+		return ErrShuttingDown
 	}
 	
 	// Verifica se a carta está no cache (só aviso; validação real é blockchain)
 	if _, hasLocal := player.Cards[cardHex]; !hasLocal {
-		fmt.Println("⚠️ Carta não encontrada no cache local (pode ser nova), verificando blockchain...")
+		slog.Debug("card not in local cache, checking chain", logPlayer, playerID, logObject, cardHex)
 	}
 
-	fmt.Printf("🔍 [BlindTrade] Validando propriedade: %s tem %s?\n", player.Wallet.Address, cardHex)
-	owns := RequestValidateOwnership(nc, player.Wallet.Address, cardHex)
-
-	if !owns {
-		return fmt.Errorf("você não é dono desta carta na blockchain")
+	req, err := s.locateCard(ctx, player, cardHex)
+	if err != nil {
+		return err
 	}
-
-	// Cria requisição
-	req := BlindTradeRequest{
-		PlayerID: playerID,
-		CardHex:  cardHex,
-		Wallet:   player.Wallet,
+	// A troca precisa ser um AtomicSwap: com uma carta assinada pelo
+	// cliente seriam duas transferências, e a segunda pode falhar depois
+	// de a primeira já ter entregado a carta.
+	if req.SelfSigned {
+		return fmt.Errorf("só cartas sob custódia do servidor podem ir para a troca cega")
 	}
 
 	s.mu.Lock()
@@ -232,62 +483,224 @@ func (s *Store) JoinBlindTrade(nc *nats.Conn, playerID int, cardHex string) erro
 			return fmt.Errorf("você já está na fila de troca")
 		}
 	}
+	if s.anteLockedLocked(cardHex) {
+		s.mu.Unlock()
+		return fmt.Errorf("carta apostada em uma partida ante")
+	}
 
 	// Remove carta para impedir reutilização
-	delete(s.players[playerID].Cards, cardHex)
+	s.updateCardsLocked(playerID, func(cards map[string]int) {
+		delete(cards, cardHex)
+	})
 
 	s.BlindTradeQueue = append(s.BlindTradeQueue, req)
 	queueLen := len(s.BlindTradeQueue)
 	s.mu.Unlock()
 
-	fmt.Printf("📥 [BlindTrade] Jogador %d entrou na fila. (Total: %d)\n", playerID, queueLen)
+	slog.Info("blind trade queued", logPlayer, playerID, logObject, cardHex, "queue_len", queueLen)
 
-	go s.ProcessBlindQueue(nc)
+	// A troca continua mesmo depois que a requisição do jogador foi respondida.
+	go s.ProcessBlindQueue(context.WithoutCancel(ctx))
 
 	return nil
 }
 
 // Processa pares da fila de forma FIFO, realiza Atomic Swap
 // e envia resposta individual para cada jogador via NATS.
-func (s *Store) ProcessBlindQueue(nc *nats.Conn) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Store) ProcessBlindQueue(ctx context.Context) {
+	done, err := s.beginWork("blindTrade atomicSwap")
+	if err != nil {
+		return
+	}
+	defer done()
 
-	for len(s.BlindTradeQueue) >= 2 {
+	for {
+		// O lock só protege a retirada do par da fila: a troca on-chain
+		// (até dezenas de segundos) acontece com a Store liberada.
+		s.mu.Lock()
+		if len(s.BlindTradeQueue) < 2 {
+			s.mu.Unlock()
+			return
+		}
 		userA := s.BlindTradeQueue[0]
 		userB := s.BlindTradeQueue[1]
 		s.BlindTradeQueue = s.BlindTradeQueue[2:]
+		s.mu.Unlock()
 
-		fmt.Printf("⚡ [BlindTrade] Match! %d <-> %d\n", userA.PlayerID, userB.PlayerID)
+		slog.Info("blind trade matched", logPlayerA, userA.PlayerID, logPlayerB, userB.PlayerID)
 
-		err := RequestAtomicSwap(nc, 
-			userA.Wallet, userA.CardHex, 
-			userB.Wallet, userB.CardHex, 
-		)
+		var err error
+		if userA.SelfSigned || userB.SelfSigned {
+			// Pedido herdado de um estado anterior à regra de JoinBlindTrade.
+			err = fmt.Errorf("carta fora da custódia do servidor")
+		} else {
+			err = s.bridge.AtomicSwap(ctx,
+				userA.Wallet, userA.CardHex,
+				userB.Wallet, userB.CardHex,
+			)
+		}
 
 		var msgA, msgB string
 		
 		if err != nil {
 			msgA = fmt.Sprintf(`{"status": "error", "msg": "Falha na blockchain: %v"}`, err)
 			msgB = msgA
-			fmt.Println("❌ Falha no BlindTrade:", err)
+			slog.Error("blind trade failed", logPlayerA, userA.PlayerID, logPlayerB, userB.PlayerID, "err", err)
 		} else {
 			msgA = fmt.Sprintf(`{"status": "success", "received_card": "%s"}`, userB.CardHex)
 			msgB = fmt.Sprintf(`{"status": "success", "received_card": "%s"}`, userA.CardHex)
-			fmt.Println("✅ BlindTrade Concluído!")
+			slog.Info("blind trade completed", logPlayerA, userA.PlayerID, logPlayerB, userB.PlayerID)
 		}
 
-		nc.Publish(fmt.Sprintf("trade.result.%d", userA.PlayerID), []byte(msgA))
-		nc.Publish(fmt.Sprintf("trade.result.%d", userB.PlayerID), []byte(msgB))
+		s.pub.Publish(fmt.Sprintf("trade.result.%d", userA.PlayerID), []byte(msgA))
+		s.pub.Publish(fmt.Sprintf("trade.result.%d", userB.PlayerID), []byte(msgB))
+	}
+}
+
+// --- PRESENTES (GIFT) ---
+
+// GiftCard transfere uma carta do jogador para outro jogador:
+// valida a posse on-chain, transfere o NFT, atualiza o cache dos dois
+// e avisa o destinatário no seu tópico exclusivo. Retorna o digest.
+func (s *Store) GiftCard(ctx context.Context, fromID, toID int, cardHex string) (string, error) {
+	if fromID == toID {
+		return "", fmt.Errorf("não é possível presentear a si mesmo")
+	}
+
+	s.mu.Lock()
+	sender, okFrom := s.players[fromID]
+	recipient, okTo := s.players[toID]
+	reserved := false
+	for _, r := range s.BlindTradeQueue {
+		if r.PlayerID == fromID && r.CardHex == cardHex {
+			reserved = true
+		}
+	}
+	anted := s.anteLockedLocked(cardHex)
+	s.mu.Unlock()
+
+	if !okFrom {
+		return "", fmt.Errorf("jogador não encontrado")
+	}
+	if sender.Banned {
+		return "", ErrBanned
+	}
+	if !okTo || recipient.Wallet.Address == "" {
+		return "", fmt.Errorf("destinatário não encontrado")
+	}
+	if reserved {
+		return "", fmt.Errorf("carta reservada na fila de troca")
+	}
+	if anted {
+		return "", fmt.Errorf("carta apostada em uma partida ante")
+	}
+
+	done, err := s.beginWork(fmt.Sprintf("giftCard from=%d to=%d card=%s", fromID, toID, cardHex))
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	card, err := s.locateCard(ctx, sender, cardHex)
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := s.transferCard(ctx, card, recipient.Wallet.Address)
+	if err != nil {
+		return "", err
+	}
+
+	// Move a carta entre os caches; o poder vem do cache do remetente.
+	s.mu.Lock()
+	power, known := s.players[fromID].Cards[cardHex]
+	s.updateCardsLocked(fromID, func(cards map[string]int) {
+		delete(cards, cardHex)
+	})
+	if known {
+		s.updateCardsLocked(toID, func(cards map[string]int) {
+			cards[cardHex] = power
+		})
 	}
+	s.mu.Unlock()
+
+	slog.Info("card gifted", logPlayer, fromID, "to_player_id", toID, logObject, cardHex, logDigest, digest)
+
+	s.notify(toID, "gift", map[string]any{
+		"from_id": fromID,
+		"card_id": cardHex,
+		"power":   power,
+		"digest":  digest,
+	})
+	return digest, nil
+}
+
+// SyncCards consulta na blockchain as cartas do jogador (carteira do
+// servidor e carteiras externas vinculadas) e substitui o cache local
+// pela verdade on-chain.
+func (s *Store) SyncCards(ctx context.Context, id int) ([]CardDTO, error) {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("syncing cards from chain", logPlayer, id, "address", player.Wallet.Address)
+
+	var chainCards []CardDTO
+	for _, addr := range append([]string{player.Wallet.Address}, player.LinkedWallets...) {
+		cards, err := s.bridge.GetCards(ctx, addr)
+		if err != nil {
+			return nil, fmt.Errorf("Falha ao consultar blockchain: %v", err)
+		}
+		for _, c := range cards {
+			c.Owner = addr
+			chainCards = append(chainCards, c)
+		}
+	}
+
+	s.mu.Lock()
+	p, ok := s.players[id]
+	if ok {
+		newMap := make(map[string]int)
+		for _, c := range chainCards {
+			newMap[c.ID] = c.Power
+		}
+		p.Cards = newMap
+		s.players[id] = p
+	}
+	s.mu.Unlock()
+
+	return chainCards, nil
+}
+
+// updateCardsLocked altera o cache de cartas de um jogador existente e
+// grava o jogador de volta no mapa. Exige s.mu travado.
+func (s *Store) updateCardsLocked(id int, update func(cards map[string]int)) {
+	p, ok := s.players[id]
+	if !ok {
+		return
+	}
+	if p.Cards == nil {
+		p.Cards = make(map[string]int)
+	}
+	update(p.Cards)
+	s.players[id] = p
 }
 
 // --- GAME LOGIC ---
 
-// Coloca jogador na fila de matchmaking
+// Coloca jogador na fila de matchmaking. É preciso ter um deck ativo
+// e válido (só as cartas dele poderão ser jogadas) e não estar em outra
+// fila, para não ser pareado duas vezes.
 func (s *Store) JoinQueue(id int) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.closing {
+		return 0, ErrShuttingDown
+	}
+	if err := s.canDuelLocked(id); err != nil {
+		return 0, err
+	}
 	s.gameQueue = append(s.gameQueue, id)
 	return id, nil
 }
@@ -302,8 +715,14 @@ func (s *Store) CreateMatch() (matchStruct, error) {
 		return matchStruct{}, fmt.Errorf("not enough players")
 	}
 
-	p1 := s.gameQueue[0]
-	p2 := s.gameQueue[1]
+	x := s.newMatchLocked(s.gameQueue[0], s.gameQueue[1])
+	s.gameQueue = s.gameQueue[2:]
+	return x, nil
+}
+
+// newMatchLocked registra uma partida nova entre p1 e p2 (usada pela
+// fila pública e pelos desafios privados). Exige s.mu travado.
+func (s *Store) newMatchLocked(p1, p2 int) matchStruct {
 	gameId := uuid.New().String()
 
 	x := matchStruct{
@@ -311,55 +730,89 @@ func (s *Store) CreateMatch() (matchStruct, error) {
 	}
 
 	s.matchHistory[gameId] = x
-	s.gameQueue = s.gameQueue[2:]
 
-	fmt.Println("[Central] Match Created:", gameId, p1, "vs", p2)
-	return x, nil
+	slog.Info("match created", logGame, gameId, "p1", p1, "p2", p2)
+	matchesCreated.Inc()
+	return x
 }
 
 // Registra a carta jogada pelo jogador e, quando ambas estiverem presentes,
 // chama ResolveMatch para decidir o vencedor.
-func (s *Store) PlayCard(nc *nats.Conn, gameId string, id int, cardVal int) (Player, int, Player, int, string, error) {
+func (s *Store) PlayCard(ctx context.Context, gameId string, id int, cardVal int) (Player, int, Player, int, string, error) {
 	s.mu.Lock()
 
 	game, exists := s.matchHistory[gameId]
-	if !exists { return Player{}, 0, Player{}, 0, "", fmt.Errorf("game not found") }
+	if !exists {
+		s.mu.Unlock()
+		return Player{}, 0, Player{}, 0, "", fmt.Errorf("game not found")
+	}
 
-	// Verifica se jogador realmente tem carta com o valor informado
 	player := s.players[id]
 	hasCard := false
-	
-	for _, v := range player.Cards {
-		if v == cardVal {
+
+	if game.Ante {
+		// Em partidas ante só vale a carta travada na entrada da fila.
+		if card := game.anteCard(id); card != "" && player.Cards[card] == cardVal {
 			hasCard = true
-			break
 		}
-	}
+		if !hasCard {
+			s.mu.Unlock()
+			return Player{}, 0, Player{}, 0, "", fmt.Errorf("ante card has no value %d", cardVal)
+		}
+	} else {
+		// Verifica se o deck ativo do jogador tem carta com o valor informado
+		deck, err := s.activeDeckLocked(id)
+		if err != nil {
+			s.mu.Unlock()
+			return Player{}, 0, Player{}, 0, "", err
+		}
 
-	if !hasCard {
-		s.mu.Unlock()
-		return Player{}, 0, Player{}, 0, "", fmt.Errorf("player does not have card with value %d", cardVal)
+		for _, cardID := range deck.Cards {
+			if player.Cards[cardID] == cardVal {
+				hasCard = true
+				break
+			}
+		}
+
+		if !hasCard {
+			s.mu.Unlock()
+			return Player{}, 0, Player{}, 0, "", fmt.Errorf("active deck has no card with value %d", cardVal)
+		}
 	}
 
-	// Salva a jogada na estrutura da partida
+	// Salva a jogada na estrutura da partida. Cada jogador joga uma vez:
+	// uma segunda jogada resolveria a partida (e pagaria a aposta) de novo.
+	var played *int
 	if game.P1 == id {
-		game.Card1 = cardVal
+		played = &game.Card1
 	} else if game.P2 == id {
-		game.Card2 = cardVal
+		played = &game.Card2
 	} else {
 		s.mu.Unlock()
 		return Player{}, 0, Player{}, 0, "", fmt.Errorf("player not in match")
 	}
+	if *played != 0 {
+		s.mu.Unlock()
+		return Player{}, 0, Player{}, 0, "", fmt.Errorf("card already played")
+	}
+	*played = cardVal
+
+	// O bot só escolhe depois da jogada (e sem vê-la): enquanto o jogador
+	// decide, a carta do bot ainda não existe.
+	if game.Practice {
+		game.Card2 = botStrategies[game.Bot](botHand(s.DeckSize), s.playHistory[id])
+	}
 
 	s.matchHistory[gameId] = game
-	fmt.Println("[Central] Card Played:", cardVal, "in game", gameId)
+	s.recordPlayLocked(id, cardVal)
+	slog.Info("card played", logGame, gameId, logPlayer, id, "power", cardVal)
 	
 	s.mu.Unlock()
 
 	// Se ambos jogaram, resolve partida
 	if game.Card1 != 0 && game.Card2 != 0 {
-		fmt.Println("Resolving Game")
-		return s.ResolveMatch(nc, game)
+		slog.Debug("resolving match", logGame, gameId)
+		return s.ResolveMatch(ctx, game)
 	}
 
 	return Player{}, 0, Player{}, 0, "", fmt.Errorf("just one player")
@@ -367,9 +820,10 @@ func (s *Store) PlayCard(nc *nats.Conn, gameId string, id int, cardVal int) (Pla
 
 // Compara valores das cartas, define vencedor, cria log da partida
 // na blockchain e retorna resultado para o servidor de jogo.
-func (s *Store) ResolveMatch(nc *nats.Conn, game matchStruct) (Player, int, Player, int, string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Store) ResolveMatch(ctx context.Context, game matchStruct) (Player, int, Player, int, string, error) {
+	// O log on-chain é concluído mesmo durante o encerramento, pois
+	// ambas as cartas já foram jogadas.
+	defer s.trackWork(fmt.Sprintf("resolveMatch game=%s p1=%d p2=%d", game.SelfId, game.P1, game.P2))()
 
 	var winnerID, loserID int
 	var winVal, loseVal int
@@ -381,20 +835,59 @@ func (s *Store) ResolveMatch(nc *nats.Conn, game matchStruct) (Player, int, Play
 		winnerID, loserID = game.P2, game.P1
 		winVal, loseVal = game.Card2, game.Card1
 	} else {
-		fmt.Println("Empate! Ninguém ganha.")
+		slog.Info("match drawn", logGame, game.SelfId)
+		matchesResolved.WithLabelValues("draw").Inc()
+		if game.Tournament != "" {
+			s.tournamentRematch(game.Tournament, game.SelfId)
+		}
+		if game.Wager > 0 {
+			s.refundStakes(ctx, game.SelfId, game.Wager, game.P1, game.P2)
+		}
+		if game.Ante {
+			s.releaseAnte(game)
+		}
 		return Player{}, 0, Player{}, 0, "", fmt.Errorf("unexpected draw")
 	}
 
-	pWin := s.players[winnerID]
-	pLose := s.players[loserID]
+	// Treino: resultado só para o jogador, sem log on-chain.
+	if game.Practice {
+		s.mu.Lock()
+		human := s.players[game.P1].clone()
+		s.mu.Unlock()
+		bot := Player{Id: botID}
+
+		slog.Info("practice match resolved", logGame, game.SelfId, logPlayer, game.P1, "strategy", game.Bot, "won", winnerID == game.P1)
+		matchesResolved.WithLabelValues("practice").Inc()
+		if winnerID == game.P1 {
+			return human, winVal, bot, loseVal, "", nil
+		}
+		return bot, winVal, human, loseVal, "", nil
+	}
 
-	fmt.Printf("🏆 Vencedor: Player %d (Carta %d)\n", winnerID, winVal)
+	// Copia os jogadores e libera a Store antes do log on-chain.
+	s.mu.Lock()
+	pWin := s.players[winnerID].clone()
+	pLose := s.players[loserID].clone()
+	s.mu.Unlock()
+
+	slog.Info("match resolved", logGame, game.SelfId, "winner_id", winnerID, "loser_id", loserID, "win_power", winVal, "lose_power", loseVal)
+	if game.Tournament != "" {
+		s.tournamentResult(ctx, game.Tournament, game.SelfId, winnerID)
+	}
+	if game.Wager > 0 {
+		s.settleWager(ctx, game, winnerID, loserID)
+	}
+	if game.Ante {
+		s.settleAnte(ctx, game, winnerID, loserID)
+	}
 
-	digest, objectId, err := RequestLogMatch(nc, pWin.Wallet.Address, pLose.Wallet.Address, winVal, loseVal)
+	digest, objectId, err := s.bridge.LogMatch(ctx, pWin.Wallet.Address, pLose.Wallet.Address, winVal, loseVal)
 	if err != nil {
-		log.Println("❌ Falha no log:", err)
+		slog.Error("match log failed", logGame, game.SelfId, "err", err)
+		matchesResolved.WithLabelValues("log_failed").Inc()
 	} else {
-		fmt.Printf("✅ Log criado! ID: %s (Digest: %s)\n", objectId, digest)
+		slog.Info("match logged on-chain", logGame, game.SelfId, logObject, objectId, logDigest, digest)
+		matchesResolved.WithLabelValues("logged").Inc()
 		if objectId != "" {
 			return pWin, winVal, pLose, loseVal, objectId, nil
 		}
@@ -0,0 +1,137 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// --- SAÚDE DO NÓ ---
+
+// Prazo total das verificações de um health check.
+const healthTimeout = 3 * time.Second
+
+// Uma replicação mais antiga que isso indica que o estado parou de ser
+// gravado (o líder replica a cada leaseInterval).
+const persistenceStale = 10 * leaseInterval
+
+// HealthCheck é o resultado de uma verificação individual.
+type HealthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport resume o estado do nó. Ready indica que todas as
+// dependências necessárias para atender jogadores estão disponíveis.
+type HealthReport struct {
+	NodeID string                 `json:"node_id"`
+	Leader bool                   `json:"leader"`
+	Ready  bool                   `json:"ready"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// Health verifica a conexão NATS, o worker blockchain, a carteira da
+// loja configurada no .env e a persistência do estado no KV.
+func (srv *Server) Health(ctx context.Context) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	report := HealthReport{
+		NodeID: srv.store.NodeID,
+		Leader: srv.cluster == nil || srv.cluster.IsLeader(),
+		Checks: map[string]HealthCheck{},
+	}
+
+	if srv.nc.IsConnected() {
+		report.Checks["nats"] = HealthCheck{OK: true, Detail: srv.nc.ConnectedUrl()}
+	} else {
+		report.Checks["nats"] = HealthCheck{Detail: srv.nc.Status().String()}
+	}
+
+	start := time.Now()
+	if err := srv.store.bridge.Ping(ctx); err != nil {
+		report.Checks["worker"] = HealthCheck{Detail: err.Error()}
+	} else {
+		report.Checks["worker"] = HealthCheck{OK: true, Detail: time.Since(start).Round(time.Millisecond).String()}
+	}
+
+	if addressPattern.MatchString(ServerWalletAddress) {
+		report.Checks["store_wallet"] = HealthCheck{OK: true, Detail: ServerWalletAddress}
+	} else {
+		report.Checks["store_wallet"] = HealthCheck{Detail: "ADDRESS ausente ou inválido no .env"}
+	}
+
+	report.Checks["persistence"] = srv.persistenceCheck()
+
+	report.Ready = true
+	for _, c := range report.Checks {
+		report.Ready = report.Ready && c.OK
+	}
+	return report
+}
+
+// Sem JetStream (modo standalone) não há o que replicar: o estado só
+// existe em memória, o que é esperado e não impede o nó de atender.
+// Seguidores não gravam, então a verificação só vale para o líder.
+func (srv *Server) persistenceCheck() HealthCheck {
+	if srv.cluster == nil {
+		return HealthCheck{OK: true, Detail: "standalone: estado apenas em memória"}
+	}
+	if !srv.cluster.IsLeader() {
+		return HealthCheck{OK: true, Detail: "seguidor (líder: " + srv.cluster.LeaderID() + ")"}
+	}
+
+	last, err := srv.cluster.PersistenceStatus()
+	switch {
+	case err != nil:
+		return HealthCheck{Detail: err.Error()}
+	case last.IsZero() || time.Since(last) > persistenceStale:
+		return HealthCheck{Detail: "estado não replicado recentemente"}
+	}
+	return HealthCheck{OK: true, Detail: "replicado há " + time.Since(last).Round(time.Millisecond).String()}
+}
+
+// Responde topic.health (qualquer nó) e topic.health.<NodeID> (um nó específico).
+func (srv *Server) replyHealth() error {
+	handler := instrument(nil, "topic.health", func(ctx context.Context, m *nats.Msg) {
+		data, _ := json.Marshal(srv.Health(ctx))
+		srv.nc.Publish(m.Reply, data)
+	})
+	for _, subject := range []string{"topic.health", "topic.health." + srv.store.NodeID} {
+		if _, err := srv.nc.Subscribe(subject, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// /healthz: o processo está vivo e conectado ao NATS (liveness).
+func (srv *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if !srv.nc.IsConnected() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{
+		"node_id": srv.store.NodeID,
+		"nats":    srv.nc.Status().String(),
+	})
+}
+
+// /readyz: todas as dependências estão disponíveis (readiness).
+func (srv *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := srv.Health(r.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,12 @@
+package API
+
+import "testing"
+
+// Sem JetStream o nó atende só com estado em memória: /readyz não pode
+// ficar em 503 para sempre por causa disso.
+func TestPersistenceCheckStandalone(t *testing.T) {
+	srv := &Server{}
+	if c := srv.persistenceCheck(); !c.OK || c.Detail == "" {
+		t.Errorf("standalone persistence check = %+v, want OK with detail", c)
+	}
+}
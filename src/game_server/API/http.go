@@ -0,0 +1,22 @@
+package API
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// --- ENDPOINTS HTTP ---
+
+// HTTPHandler expõe os endpoints operacionais do nó:
+//
+//	GET /metrics  métricas no formato Prometheus
+//	GET /healthz  liveness: processo vivo e conectado ao NATS
+//	GET /readyz   readiness: NATS, worker, carteira da loja e persistência
+func (srv *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /healthz", srv.handleHealthz)
+	mux.HandleFunc("GET /readyz", srv.handleReadyz)
+	return mux
+}
@@ -0,0 +1,100 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Server reúne a conexão NATS, os handlers do líder e o cluster,
+// permitindo encerrar o nó de forma ordenada.
+type Server struct {
+	nc       *nats.Conn
+	store    *Store
+	handlers *leaderHandlers
+	cluster  *Cluster // nil quando rodando em modo standalone
+}
+
+// Conn retorna a conexão NATS usada pelo servidor.
+func (srv *Server) Conn() *nats.Conn {
+	return srv.nc
+}
+
+// Shutdown encerra o servidor de forma graciosa:
+//  1. bloqueia novas entradas nas filas e novas operações on-chain;
+//  2. avisa os jogadores que estavam aguardando partida ou troca;
+//  3. drena as assinaturas do jogo, terminando as mensagens já recebidas;
+//  4. aguarda OpenPack/ResolveMatch em andamento (até o timeout do ctx);
+//  5. grava o estado final (com o journal do que ficou pendente) e
+//     libera a liderança;
+//  6. drena e fecha a conexão NATS.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.notifyQueued(srv.store.BeginShutdown())
+
+	if err := srv.handlers.drain(ctx); err != nil {
+		slog.Warn("handlers did not drain in time", "err", err)
+	}
+
+	idleErr := srv.store.WaitIdle(ctx)
+	if idleErr != nil {
+		// O que não terminou fica registrado no estado replicado para
+		// que o próximo líder (ou um operador) possa reconciliar.
+		for _, desc := range srv.store.Journal() {
+			slog.Warn("operation pending at shutdown", "operation", desc)
+		}
+	}
+
+	if srv.cluster != nil {
+		srv.cluster.Close()
+	}
+
+	if err := srv.closeConn(ctx); err != nil {
+		return err
+	}
+	return idleErr
+}
+
+// Avisa quem estava nas filas que o servidor está encerrando, usando
+// os mesmos tópicos em que os clientes já aguardam a resposta.
+func (srv *Server) notifyQueued(q drainedQueues) {
+	// As filas comum, de apostas e ante aguardam em topic.matchmaking.
+	matchmaking := append([]int{}, q.Game...)
+	for _, w := range q.Wager {
+		matchmaking = append(matchmaking, w.PlayerID)
+	}
+	for _, a := range q.Ante {
+		matchmaking = append(matchmaking, a.PlayerID)
+	}
+	for _, id := range matchmaking {
+		resp := map[string]any{"client_id": id, "err": ErrShuttingDown.Error()}
+		data, _ := json.Marshal(resp)
+		srv.nc.Publish("topic.matchmaking", data)
+	}
+	for _, req := range q.Blind {
+		resp := map[string]any{"status": "error", "msg": ErrShuttingDown.Error()}
+		data, _ := json.Marshal(resp)
+		srv.nc.Publish(fmt.Sprintf("trade.result.%d", req.PlayerID), data)
+	}
+	srv.nc.Flush()
+}
+
+// Drena a conexão (envia o que estiver pendente) e aguarda o fechamento.
+func (srv *Server) closeConn(ctx context.Context) error {
+	if err := srv.nc.Drain(); err != nil {
+		srv.nc.Close()
+		return err
+	}
+	for !srv.nc.IsClosed() {
+		select {
+		case <-ctx.Done():
+			srv.nc.Close()
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return nil
+}
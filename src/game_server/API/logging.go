@@ -0,0 +1,41 @@
+package API
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// --- LOGS ESTRUTURADOS ---
+
+// Campos padronizados nos logs, para filtrar e correlacionar eventos
+// (ex.: todos os eventos de um jogador ou de uma partida).
+const (
+	logPlayer   = "player_id"
+	logGame     = "game_id"
+	logPlayerA  = "player_a" // Par de uma troca às cegas
+	logPlayerB  = "player_b"
+	logDigest   = "digest"
+	logObject   = "object_id"
+	logSubject  = "subject"
+	logNode     = "node_id"
+	logBridgeOp = "op"
+)
+
+// SetupLogging configura o logger padrão (slog e o pacote log) com o
+// nível (debug, info, warn, error) e o formato (text ou json) informados.
+func SetupLogging(w io.Writer, level, format string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
@@ -0,0 +1,171 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// --- MÉTRICAS (PROMETHEUS) ---
+
+// Jogadores que enviaram alguma mensagem dentro desta janela contam
+// como online (o cliente não avisa quando sai).
+const onlineWindow = 2 * time.Minute
+
+var (
+	handlerRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_handler_requests_total",
+		Help: "Mensagens recebidas por tópico NATS.",
+	}, []string{"subject"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "game_handler_duration_seconds",
+		Help:    "Tempo de processamento de cada mensagem, por tópico NATS.",
+		Buckets: []float64{.005, .01, .05, .1, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"subject"})
+
+	bridgeCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_bridge_calls_total",
+		Help: "Chamadas ao worker blockchain (internalServer.*), por operação e resultado.",
+	}, []string{"op", "result"})
+
+	bridgeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "game_bridge_duration_seconds",
+		Help:    "Latência de cada tentativa de chamada ao worker blockchain.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"op"})
+
+	matchesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_matches_created_total",
+		Help: "Partidas criadas pelo matchmaking.",
+	})
+
+	matchesResolved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_matches_resolved_total",
+		Help: "Partidas resolvidas, por resultado (logged, draw, log_failed, practice).",
+	}, []string{"result"})
+)
+
+// Handler de tópico que recebe o contexto da requisição (com o trace
+// do cliente), repassado às chamadas da Store e do worker.
+type ctxHandler func(ctx context.Context, m *nats.Msg)
+
+// instrument envolve o handler de um tópico abrindo o span da mensagem,
+// contando as mensagens e o tempo de processamento e registrando a
+// atividade do jogador (client_id).
+// s pode ser nil em tópicos atendidos por todos os nós (ex.: ping).
+func instrument(s *Store, subject string, h ctxHandler) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		ctx, span := startHandlerSpan(m, subject)
+		start := time.Now()
+		defer func() {
+			span.End()
+			handlerRequests.WithLabelValues(subject).Inc()
+			handlerDuration.WithLabelValues(subject).Observe(time.Since(start).Seconds())
+		}()
+
+		var payload struct {
+			ClientID int `json:"client_id"`
+		}
+		if s != nil && json.Unmarshal(m.Data, &payload) == nil && payload.ClientID > 0 {
+			s.markSeen(payload.ClientID)
+			span.SetAttributes(attribute.Int(logPlayer, payload.ClientID))
+		}
+		h(ctx, m)
+		if s != nil {
+			s.touch()
+		}
+	}
+}
+
+// Classifica o resultado de uma chamada ao worker para a métrica.
+func bridgeResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrBridgeTimeout):
+		return "timeout"
+	case errors.Is(err, ErrBridgeUnavailable):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
+
+func (s *Store) markSeen(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.players[id]; exists {
+		s.lastSeen[id] = time.Now()
+	}
+}
+
+// storeCollector lê o estado da Store a cada coleta: filas, partidas
+// ativas, pacotes restantes e jogadores online.
+type storeCollector struct {
+	store *Store
+
+	gameQueue     *prometheus.Desc
+	blindQueue    *prometheus.Desc
+	activeMatches *prometheus.Desc
+	packs         *prometheus.Desc
+	players       *prometheus.Desc
+	online        *prometheus.Desc
+}
+
+// RegisterStoreMetrics publica os indicadores da Store no registro padrão.
+func RegisterStoreMetrics(s *Store) error {
+	return prometheus.Register(&storeCollector{
+		store:         s,
+		gameQueue:     prometheus.NewDesc("game_queue_depth", "Jogadores aguardando partida.", nil, nil),
+		blindQueue:    prometheus.NewDesc("game_blind_trade_queue_depth", "Jogadores aguardando troca às cegas.", nil, nil),
+		activeMatches: prometheus.NewDesc("game_active_matches", "Partidas criadas e ainda não resolvidas.", nil, nil),
+		packs:         prometheus.NewDesc("game_packs_remaining", "Pacotes disponíveis no pool.", nil, nil),
+		players:       prometheus.NewDesc("game_players", "Jogadores cadastrados.", nil, nil),
+		online:        prometheus.NewDesc("game_players_online", "Jogadores com atividade recente.", nil, nil),
+	})
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.gameQueue
+	ch <- c.blindQueue
+	ch <- c.activeMatches
+	ch <- c.packs
+	ch <- c.players
+	ch <- c.online
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.store
+	s.mu.Lock()
+	gameQueue := len(s.gameQueue)
+	blindQueue := len(s.BlindTradeQueue)
+	packs := s.packs
+	players := len(s.players)
+	active := 0
+	for _, m := range s.matchHistory {
+		if m.Card1 == 0 || m.Card2 == 0 {
+			active++
+		}
+	}
+	online := 0
+	for _, seen := range s.lastSeen {
+		if time.Since(seen) < onlineWindow {
+			online++
+		}
+	}
+	s.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.gameQueue, prometheus.GaugeValue, float64(gameQueue))
+	ch <- prometheus.MustNewConstMetric(c.blindQueue, prometheus.GaugeValue, float64(blindQueue))
+	ch <- prometheus.MustNewConstMetric(c.activeMatches, prometheus.GaugeValue, float64(active))
+	ch <- prometheus.MustNewConstMetric(c.packs, prometheus.GaugeValue, float64(packs))
+	ch <- prometheus.MustNewConstMetric(c.players, prometheus.GaugeValue, float64(players))
+	ch <- prometheus.MustNewConstMetric(c.online, prometheus.GaugeValue, float64(online))
+}
@@ -0,0 +1,143 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// --- TREINO CONTRA O BOT ---
+
+// ID do oponente controlado pelo servidor nas partidas de treino.
+const botID = -1
+
+// Jogadas recentes guardadas por jogador (usadas pela estratégia adaptativa).
+const playHistoryKept = 20
+
+// botStrategy escolhe a carta do bot a partir da mão dele e das forças
+// jogadas recentemente pelo adversário. A escolha acontece em PlayCard,
+// logo depois da jogada, mas só com o histórico anterior: o bot nunca vê
+// a carta da rodada.
+type botStrategy func(hand []int, history []int) int
+
+var botStrategies = map[string]botStrategy{
+	// Carta qualquer da mão.
+	"random": func(hand []int, history []int) int {
+		return hand[rand.IntN(len(hand))]
+	},
+	// Sempre a carta mais forte.
+	"greedy": func(hand []int, history []int) int {
+		return hand[len(hand)-1]
+	},
+	// Estima a próxima jogada pela média das jogadas anteriores e usa a
+	// menor carta que a supera; sem chance de vencer, descarta a mais fraca.
+	"adaptive": func(hand []int, history []int) int {
+		if len(history) == 0 {
+			return hand[len(hand)-1]
+		}
+		sum := 0
+		for _, v := range history {
+			sum += v
+		}
+		predicted := sum / len(history)
+		for _, v := range hand {
+			if v > predicted {
+				return v
+			}
+		}
+		return hand[0]
+	},
+}
+
+// Mão do bot: cartas sorteadas como as de um pacote, em ordem crescente.
+func botHand(size int) []int {
+	hand := make([]int, size)
+	for i := range hand {
+		hand[i] = rand.IntN(maxCardPower) + 1
+	}
+	sort.Ints(hand)
+	return hand
+}
+
+// recordPlayLocked guarda a força jogada pelo jogador. Exige s.mu travado.
+func (s *Store) recordPlayLocked(id, power int) {
+	history := append(s.playHistory[id], power)
+	if extra := len(history) - playHistoryKept; extra > 0 {
+		history = history[extra:]
+	}
+	s.playHistory[id] = history
+}
+
+// CreatePracticeMatch cria uma partida de treino entre o jogador e o bot
+// (estratégia vazia = BotStrategy). A partida segue o fluxo normal de
+// PlayCard/ResolveMatch, mas não é ranqueada nem registrada on-chain.
+func (s *Store) CreatePracticeMatch(id int, strategy string) (matchStruct, error) {
+	if strategy == "" {
+		strategy = s.BotStrategy
+	}
+	if _, ok := botStrategies[strategy]; !ok {
+		return matchStruct{}, fmt.Errorf("estratégia desconhecida: %s (use random, greedy ou adaptive)", strategy)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return matchStruct{}, ErrShuttingDown
+	}
+	p, exists := s.players[id]
+	if !exists {
+		return matchStruct{}, fmt.Errorf("player not found")
+	}
+	if p.Banned {
+		return matchStruct{}, ErrBanned
+	}
+	if _, err := s.activeDeckLocked(id); err != nil {
+		return matchStruct{}, err
+	}
+	for _, q := range s.gameQueue {
+		if q == id {
+			return matchStruct{}, fmt.Errorf("você já está na fila de partidas")
+		}
+	}
+
+	match := matchStruct{
+		SelfId:   uuid.New().String(),
+		P1:       id,
+		P2:       botID,
+		Practice: true,
+		Bot:      strategy,
+	}
+	s.matchHistory[match.SelfId] = match
+
+	slog.Info("practice match created", logGame, match.SelfId, logPlayer, id, "strategy", strategy)
+	return match, nil
+}
+
+// ClientPractice inicia uma partida de treino contra o bot. A resposta
+// traz a partida; a jogada segue pelo game.client como nas demais.
+func ClientPractice(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	return nc.Subscribe("topic.practice", instrument(s, "topic.practice", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID int    `json:"client_id"`
+			Strategy string `json:"strategy"`
+		}
+		json.Unmarshal(m.Data, &payload)
+
+		match, err := s.CreatePracticeMatch(payload.ClientID, payload.Strategy)
+		if err != nil {
+			data, _ := json.Marshal(map[string]any{"err": err.Error()})
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		data, _ := json.Marshal(map[string]any{"status": "Practice match created", "match": match.playerView(), "is_leader": true})
+		nc.Publish(m.Reply, data)
+	}))
+}
@@ -1,80 +1,231 @@
 package API
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
-func SetupPS(s *Store) {
-	nc, err := BrokerConnect()
+// SetupPS registra os handlers na conexão e entra na eleição de
+// líder. O Server retornado é usado para o encerramento gracioso.
+func SetupPS(nc *nats.Conn, s *Store) (*Server, error) {
+	// O ping é respondido por todos os nós, inclusive seguidores.
+	if _, err := ReplyPing(nc); err != nil {
+		return nil, err
+	}
+
+	srv := &Server{
+		nc:       nc,
+		store:    s,
+		handlers: &leaderHandlers{nc: nc, store: s},
+	}
+
+	cluster, err := NewCluster(nc, s)
 	if err != nil {
-		log.Println("NATS Connect Error:", err)
+		// Sem JetStream não há eleição: o nó roda sozinho como líder.
+		slog.Warn("JetStream unavailable, running standalone", "err", err)
+		if err := srv.replyHealth(); err != nil {
+			return nil, err
+		}
+		srv.handlers.start()
+		return srv, nil
+	}
+
+	srv.cluster = cluster
+	if err := srv.replyHealth(); err != nil {
+		return nil, err
+	}
+	cluster.OnElected = srv.handlers.start
+	cluster.OnDemoted = srv.handlers.stop
+	go cluster.Run()
+	return srv, nil
+}
+
+// leaderHandlers agrupa as assinaturas que só o líder mantém ativas.
+// Seguidores não assinam os tópicos do jogo, então toda escrita chega
+// ao líder; na troca de liderança o conjunto é desfeito e refeito.
+type leaderHandlers struct {
+	nc    *nats.Conn
+	store *Store
+
+	mu     sync.Mutex
+	subs   []*nats.Subscription
+	quit   chan struct{}
+	closed bool // Após o encerramento, não volta a assinar
+}
+
+func (h *leaderHandlers) start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.quit != nil || h.closed {
 		return
 	}
+	h.quit = make(chan struct{})
+	go Heartbeat(h.nc, h.quit)
+	h.store.setLeading(true)
 
-	// Loop contínuo que envia um heartbeat para os clientes,
-	// garantindo que quem estiver conectado saiba que o servidor está ativo.
-	// A pausa de 1 segundo evita que o NATS marque o cliente como slow consumer.
-	go func() {
-		for {
-			htb := map[string]int64{"server_ping": time.Now().UnixMilli()}
-			htb_json, _ := json.Marshal(htb)
-			nc.Publish("topic.heartbeat", htb_json)
-			time.Sleep(1 * time.Second)
+	// Registro de todos os handlers que tratam as operações do jogo.
+	nc, s := h.nc, h.store
+	for _, register := range []func(*nats.Conn, *Store) (*nats.Subscription, error){
+		CreateAccount,
+		ClientLogin,
+		ClientOpenPack,
+		ClientSeeCards,
+		ClientJoinGameQueue,
+		ClientPlayCards,
+		ClientJoinBlindTrade,
+		ClientGetCredentials,
+		ClientBalance,
+		ClientFairEpochs,
+		ClientDecks,
+		ClientPractice,
+		ClientChallenges,
+		ClientTournaments,
+		ClientFaucet,
+		ClientSendTokens,
+		ClientGiftCard,
+		ClientPlayerAddress,
+		ClientWalletChallenge,
+		ClientWalletLink,
+		ClientCustody,
+		AdminCommands,
+	} {
+		sub, err := register(nc, s)
+		if err != nil {
+			slog.Error("subscribe failed", "err", err)
+			continue
 		}
-	}()
+		h.subs = append(h.subs, sub)
+	}
+}
 
-	// Registro de todos os handlers que tratam as operações do jogo.
-	ReplyPing(nc)
-	CreateAccount(nc, s)
-	ClientLogin(nc, s)
-	ClientOpenPack(nc, s)
-	ClientSeeCards(nc, s)
-	ClientJoinGameQueue(nc, s)
-	ClientPlayCards(nc, s)
-	ClientJoinBlindTrade(nc, s)
-	ClientGetCredentials(nc, s)
+func (h *leaderHandlers) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.quit == nil {
+		return
+	}
+	close(h.quit)
+	h.quit = nil
+	h.store.setLeading(false)
+	for _, sub := range h.subs {
+		sub.Unsubscribe()
+	}
+	h.subs = nil
 }
 
-func ReplyPing(nc *nats.Conn) {
+// drain para de aceitar mensagens novas, deixa os handlers terminarem
+// as que já chegaram e aguarda até o contexto expirar.
+func (h *leaderHandlers) drain(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	if h.quit != nil {
+		close(h.quit)
+		h.quit = nil
+		h.store.setLeading(false)
+	}
+	subs := h.subs
+	h.subs = nil
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Drain()
+	}
+	for _, sub := range subs {
+		for sub.IsValid() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+// Heartbeat envia periodicamente um sinal para os clientes,
+// garantindo que quem estiver conectado saiba que o servidor está ativo.
+// A pausa de 1 segundo evita que o NATS marque o cliente como slow consumer.
+func Heartbeat(nc *nats.Conn, quit <-chan struct{}) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		htb := map[string]int64{"server_ping": time.Now().UnixMilli()}
+		htb_json, _ := json.Marshal(htb)
+		nc.Publish("topic.heartbeat", htb_json)
+
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func ReplyPing(nc *nats.Conn) (*nats.Subscription, error) {
 	// Responde automaticamente qualquer ping enviado por um cliente,
 	// retornando o timestamp do servidor.
-	nc.Subscribe("topic.ping", func(m *nats.Msg) {
+	return nc.Subscribe("topic.ping", instrument(nil, "topic.ping", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(m.Data, &payload)
 		payload["server_ping"] = time.Now().UnixMilli()
 		data, _ := json.Marshal(payload)
 		nc.Publish(m.Reply, data)
-	})
+	}))
 }
 
-func BrokerConnect() (*nats.Conn, error) {
-	// Define a URL do servidor NATS; caso não exista no ambiente, usa localhost.
-	url := os.Getenv("NATS_URL")
-	if url == "" {
-		url = "nats://localhost:4222"
+// BrokerConnect conecta a um dos servidores NATS da lista. Se a conexão
+// cair, o cliente NATS tenta os demais servidores indefinidamente com
+// backoff exponencial e refaz as assinaturas sozinho ao reconectar.
+func BrokerConnect(servers []string) (*nats.Conn, error) {
+	// Caso nenhuma URL seja informada, usa localhost.
+	if len(servers) == 0 {
+		servers = []string{nats.DefaultURL}
 	}
 	// Configura opções de timeout, nome, e tentativas de reconexão.
 	opts := []nats.Option{
 		nats.Name("Central-Server"),
 		nats.Timeout(10 * time.Second),
-		nats.ReconnectWait(2 * time.Second),
-		nats.MaxReconnects(5),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(reconnectBackoff),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				slog.Warn("NATS disconnected", "err", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			slog.Info("NATS reconnected", "url", nc.ConnectedUrl())
+		}),
 	}
-	return nats.Connect(url, opts...)
+	return nats.Connect(strings.Join(servers, ","), opts...)
+}
+
+// Espera entre tentativas de reconexão: dobra a cada tentativa
+// (250ms, 500ms, 1s...) até o limite de 10s, com um pouco de jitter
+// para que vários nós não reconectem todos no mesmo instante.
+func reconnectBackoff(attempts int) time.Duration {
+	delay := 250 * time.Millisecond << min(attempts, 6)
+	delay = min(delay, 10*time.Second)
+	return delay + rand.N(delay/4+1)
 }
 
-func CreateAccount(nc *nats.Conn, s *Store) {
+func CreateAccount(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Cria um jogador novo e envia o ID ao cliente.
-	nc.Subscribe("topic.createAccount", func(m *nats.Msg) {
-		playerID, err := s.CreatePlayer(nc)
+	return nc.Subscribe("topic.createAccount", instrument(s, "topic.createAccount", func(ctx context.Context, m *nats.Msg) {
+		playerID, err := s.CreatePlayer(ctx)
 		if err != nil {
-			nc.Publish(m.Reply, []byte(`{"err":"ERROR_CREATING"}`))
+			resp := map[string]any{"err": "ERROR_CREATING", "msg": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
 			return
 		}
 		payload := map[string]any{
@@ -84,20 +235,20 @@ func CreateAccount(nc *nats.Conn, s *Store) {
 		}
 		data, _ := json.Marshal(payload)
 		nc.Publish(m.Reply, data)
-		fmt.Println("user id: ", playerID)
-	})
+		slog.Info("account created", logSubject, m.Subject, logPlayer, playerID)
+	}))
 }
 
-func ClientLogin(nc *nats.Conn, s *Store) {
+func ClientLogin(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Verifica se um ID enviado pelo cliente corresponde a um jogador existente.
-	nc.Subscribe("topic.login", func(msg *nats.Msg) {
+	return nc.Subscribe("topic.login", instrument(s, "topic.login", func(ctx context.Context, msg *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(msg.Data, &payload)
 
 		s.mu.Lock()
 		maxCount := s.count
 		id := int(payload["client_id"].(float64))
-		_, exists := s.players[id]
+		player, exists := s.players[id]
 		s.mu.Unlock()
 
 		if id > maxCount || !exists {
@@ -106,21 +257,34 @@ func ClientLogin(nc *nats.Conn, s *Store) {
 			nc.Publish(msg.Reply, data)
 			return
 		}
+		if player.Banned {
+			payload["err"] = ErrBanned.Error()
+			data, _ := json.Marshal(payload)
+			nc.Publish(msg.Reply, data)
+			return
+		}
 
 		resp := map[string]any{"result": true, "client_id": id}
 		data, _ := json.Marshal(resp)
 		nc.Publish(msg.Reply, data)
-	})
+	}))
 }
 
-func ClientOpenPack(nc *nats.Conn, s *Store) {
-	// Solicita ao Store que abra um pacote de cartas para o jogador,
-	// enviando o resultado ao cliente.
-	nc.Subscribe("topic.openPack", func(m *nats.Msg) {
+func ClientOpenPack(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Dispara a abertura de um pacote e responde na hora com o ID do job;
+	// o andamento é publicado em player.<id>.pack.
+	return nc.Subscribe("topic.openPack", instrument(s, "topic.openPack", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(m.Data, &payload)
 
-		cards, err := s.OpenPack(nc, int(payload["client_id"].(float64)))
+		clientSeed, _ := payload["client_seed"].(string)
+		// Compromisso e nonce que o jogador viu antes de escolher a semente.
+		var expect *FairExpect
+		commitment, _ := payload["commitment"].(string)
+		if nonce, ok := payload["nonce"].(float64); ok && commitment != "" {
+			expect = &FairExpect{Commitment: commitment, Nonce: int(nonce)}
+		}
+		jobID, err := s.StartOpenPack(ctx, int(payload["client_id"].(float64)), clientSeed, expect)
 		if err != nil {
 			resp := map[string]any{"err": err.Error()}
 			data, _ := json.Marshal(resp)
@@ -129,74 +293,85 @@ func ClientOpenPack(nc *nats.Conn, s *Store) {
 		}
 
 		response := map[string]any{
-			"status":    "Pack opened",
-			"result":    *cards,
+			"status":    "Pack opening",
+			"job_id":    jobID,
 			"is_leader": true,
 		}
 		data, _ := json.Marshal(response)
 		nc.Publish(m.Reply, data)
-	})
+	}))
 }
 
-func ClientSeeCards(nc *nats.Conn, s *Store) {
+func ClientSeeCards(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Recupera as cartas do jogador diretamente da blockchain,
 	// garantindo consistência entre on-chain e cache local.
-	nc.Subscribe("topic.seeCards", func(m *nats.Msg) {
+	return nc.Subscribe("topic.seeCards", instrument(s, "topic.seeCards", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(m.Data, &payload)
 		clientID := int(payload["client_id"].(float64))
 
-		s.mu.Lock()
-		player, exists := s.players[clientID]
-		s.mu.Unlock()
-
-		if !exists {
-			nc.Publish(m.Reply, []byte(`{"err":"player not found"}`))
-			return
-		}
-
-		fmt.Printf("🌐 Consultando cartas on-chain para Jogador %d (%s)...\n", clientID, player.Wallet.Address)
-
-		// Consulta o indexer para buscar as cartas reais registradas na blockchain.
-		chainCards, err := RequestGetCardsFromChain(nc, player.Wallet.Address)
-
+		chainCards, err := s.SyncCards(ctx, clientID)
 		if err != nil {
-			errMsg := fmt.Sprintf(`{"err":"Falha ao consultar blockchain: %v"}`, err)
-			nc.Publish(m.Reply, []byte(errMsg))
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
 			return
 		}
 
-		// Atualiza o cache local de cartas baseado na verdade on-chain.
-		s.mu.Lock()
-		p, ok := s.players[clientID]
-		if ok {
-			newMap := make(map[string]int)
-			for _, c := range chainCards {
-				newMap[c.ID] = c.Power
-			}
-			p.Cards = newMap
-			s.players[clientID] = p
-		}
-		s.mu.Unlock()
-
 		resp := map[string]any{
 			"result":    chainCards,
 			"is_leader": true,
 		}
 		data, _ := json.Marshal(resp)
 		nc.Publish(m.Reply, data)
-	})
+	}))
 }
 
-func ClientJoinGameQueue(nc *nats.Conn, s *Store) {
+func ClientJoinGameQueue(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Adiciona o jogador à fila de matchmaking. Quando houver 2 players, inicia o duelo.
-	nc.Subscribe("topic.findMatch", func(m *nats.Msg) {
+	// Com "wager" > 0 o jogador entra na fila de apostas (ver wager.go) e
+	// com "ante_card" na fila ante, apostando a carta (ver ante.go).
+	return nc.Subscribe("topic.findMatch", instrument(s, "topic.findMatch", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(m.Data, &payload)
 
-		_, err := s.JoinQueue(int(payload["client_id"].(float64)))
+		clientID := int(payload["client_id"].(float64))
+		if wager, _ := payload["wager"].(float64); wager > 0 {
+			if err := s.JoinWagerQueue(clientID, uint64(wager)); err != nil {
+				data, _ := json.Marshal(map[string]any{"err": err.Error()})
+				nc.Publish(m.Reply, data)
+				return
+			}
+			data, _ := json.Marshal(map[string]any{"status": "Added to wager queue", "rake": s.WagerRake, "is_leader": true})
+			nc.Publish(m.Reply, data)
+
+			// A cobrança das apostas continua depois da resposta.
+			go s.ProcessWagerQueue(context.WithoutCancel(ctx))
+			return
+		}
+		if card, _ := payload["ante_card"].(string); card != "" {
+			if err := s.JoinAnteQueue(ctx, clientID, card); err != nil {
+				data, _ := json.Marshal(map[string]any{"err": err.Error()})
+				nc.Publish(m.Reply, data)
+				return
+			}
+			data, _ := json.Marshal(map[string]any{"status": "Added to ante queue", "is_leader": true})
+			nc.Publish(m.Reply, data)
+
+			match, err := s.CreateAnteMatch()
+			if err != nil {
+				slog.Debug("waiting for ante opponent", logSubject, m.Subject)
+				return
+			}
+			slog.Info("ante match started", logSubject, m.Subject, logGame, match.SelfId)
+			s.announceMatch(map[string]any{"match": match.playerView()}, match.P1, match.P2)
+			return
+		}
+
+		_, err := s.JoinQueue(clientID)
 		if err != nil {
-			nc.Publish(m.Reply, []byte(`{"err":"ERROR_JOINING"}`))
+			data, _ := json.Marshal(map[string]any{"err": err.Error()})
+			nc.Publish(m.Reply, data)
 			return
 		}
 
@@ -206,19 +381,19 @@ func ClientJoinGameQueue(nc *nats.Conn, s *Store) {
 
 		match, err := s.CreateMatch()
 		if err != nil {
-			fmt.Println("Match not started.")
+			slog.Debug("waiting for opponent", logSubject, m.Subject)
 			return
 		}
 
-		fmt.Println("Match started:", match.SelfId)
+		slog.Info("match started", logSubject, m.Subject, logGame, match.SelfId)
 
 		// Notifica ambos os players envolvidos.
 		for _, p := range []int{match.P1, match.P2} {
-			resp := map[string]any{"client_id": p, "match": match}
+			resp := map[string]any{"client_id": p, "match": match.playerView()}
 			data, _ = json.Marshal(resp)
 			nc.Publish("topic.matchmaking", data)
 		}
-	})
+	}))
 }
 
 func SendingGameResult(payload map[string]any, nc *nats.Conn) {
@@ -226,16 +401,16 @@ func SendingGameResult(payload map[string]any, nc *nats.Conn) {
 	data, _ := json.Marshal(payload)
 	if nc != nil {
 		nc.Publish("game.server", data)
-		fmt.Println("Result sent:", payload)
+		slog.Debug("game result sent", logSubject, "game.server", logPlayer, payload["client_id"], "result", payload["result"])
 	}
 }
 
-func ClientPlayCards(nc *nats.Conn, s *Store) {
+func ClientPlayCards(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Recebe jogadas dos clientes e usa o Store para resolver a rodada.
-	nc.Subscribe("game.client", func(m *nats.Msg) {
+	return nc.Subscribe("game.client", instrument(s, "game.client", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		if err := json.Unmarshal(m.Data, &payload); err != nil {
-			log.Println("Error unmarshalling payload:", err)
+			slog.Warn("invalid payload", logSubject, m.Subject, "err", err)
 			return
 		}
 
@@ -244,9 +419,9 @@ func ClientPlayCards(nc *nats.Conn, s *Store) {
 		card := int(payload["card"].(float64))
 
 		// Resolve o duelo entre os jogadores.
-		pWin, cardWin, pLose, cardLose, objectId, err := s.PlayCard(nc, gameID, clientID, card)
+		pWin, cardWin, pLose, cardLose, objectId, err := s.PlayCard(ctx, gameID, clientID, card)
 		if err != nil {
-			log.Println("Error executing PlayCard:", err)
+			slog.Debug("play not resolved", logSubject, m.Subject, logGame, gameID, logPlayer, clientID, "reason", err)
 			return
 		}
 
@@ -254,14 +429,18 @@ func ClientPlayCards(nc *nats.Conn, s *Store) {
 		response1 := map[string]any{"client_id": pWin.Id, "result": "win", "card": cardLose, "object": objectId}
 		response2 := map[string]any{"client_id": pLose.Id, "result": "lose", "card": cardWin, "object": objectId}
 
-		SendingGameResult(response1, nc)
-		SendingGameResult(response2, nc)
-	})
+		// Em partidas de treino um dos lados é o bot, que não recebe resultado.
+		for _, resp := range []map[string]any{response1, response2} {
+			if resp["client_id"] != botID {
+				SendingGameResult(resp, nc)
+			}
+		}
+	}))
 }
 
-func ClientJoinBlindTrade(nc *nats.Conn, s *Store) {
+func ClientJoinBlindTrade(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Jogador entra na fila para uma troca às cegas (dois players trocam cartas aleatórias).
-	nc.Subscribe("topic.trade.joinBlind", func(m *nats.Msg) {
+	return nc.Subscribe("topic.trade.joinBlind", instrument(s, "topic.trade.joinBlind", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		if err := json.Unmarshal(m.Data, &payload); err != nil {
 			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
@@ -271,7 +450,7 @@ func ClientJoinBlindTrade(nc *nats.Conn, s *Store) {
 		clientID := int(payload["client_id"].(float64))
 		cardHex := payload["card_id"].(string)
 
-		err := s.JoinBlindTrade(nc, clientID, cardHex)
+		err := s.JoinBlindTrade(ctx, clientID, cardHex)
 
 		if err != nil {
 			resp := map[string]any{"err": err.Error()}
@@ -280,12 +459,12 @@ func ClientJoinBlindTrade(nc *nats.Conn, s *Store) {
 		} else {
 			nc.Publish(m.Reply, []byte(`{"status":"queued", "msg":"Você está na fila. Aguarde notificação."}`))
 		}
-	})
+	}))
 }
 
-func ClientGetCredentials(nc *nats.Conn, s *Store) {
+func ClientGetCredentials(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
 	// Entrega ao cliente os dados da carteira blockchain armazenados no Store.
-	nc.Subscribe("topic.getCredentials", func(m *nats.Msg) {
+	return nc.Subscribe("topic.getCredentials", instrument(s, "topic.getCredentials", func(ctx context.Context, m *nats.Msg) {
 		var payload map[string]any
 		json.Unmarshal(m.Data, &payload)
 		clientID := int(payload["client_id"].(float64))
@@ -300,11 +479,244 @@ func ClientGetCredentials(nc *nats.Conn, s *Store) {
 		}
 
 		// Envia endereço e chave secreta do jogador para operações on-chain.
-		resp := map[string]string{
-			"address": player.Wallet.Address,
-			"secret":  player.Wallet.Secret,
+		// No modo sem custódia o servidor não tem mais a chave.
+		resp := map[string]any{
+			"address":      player.Wallet.Address,
+			"self_custody": player.SelfCustody,
+		}
+		if !player.SelfCustody {
+			resp["secret"] = player.Wallet.Secret
+		}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientBalance(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Informa o saldo on-chain do jogador e o preço do pacote,
+	// para o cliente saber se a compra é possível.
+	return nc.Subscribe("topic.balance", instrument(s, "topic.balance", func(ctx context.Context, m *nats.Msg) {
+		var payload map[string]any
+		json.Unmarshal(m.Data, &payload)
+		clientID := int(payload["client_id"].(float64))
+
+		balance, err := s.Balance(ctx, clientID)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"balance": balance, "pack_price": PackPrice}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientFairEpochs(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Publica o compromisso da época corrente e as sementes já reveladas,
+	// usadas pelo cliente para verificar os sorteios de pacotes.
+	// Com client_id, inclui o nonce do próximo sorteio do jogador.
+	return nc.Subscribe("topic.fair.epochs", instrument(s, "topic.fair.epochs", func(ctx context.Context, m *nats.Msg) {
+		var payload map[string]any
+		json.Unmarshal(m.Data, &payload)
+
+		current, revealed := s.FairEpochs()
+		resp := map[string]any{"current": current, "revealed": revealed}
+		if id, ok := payload["client_id"].(float64); ok {
+			if nonce, err := s.FairNonce(int(id)); err == nil {
+				resp["next_nonce"] = nonce
+			}
+		}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientFaucet(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Pede tokens de teste para a carteira do jogador (com limite de uso).
+	return nc.Subscribe("topic.faucet", instrument(s, "topic.faucet", func(ctx context.Context, m *nats.Msg) {
+		var payload map[string]any
+		json.Unmarshal(m.Data, &payload)
+		clientID := int(payload["client_id"].(float64))
+
+		balance, err := s.Faucet(ctx, clientID)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"status": "funded", "balance": balance}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientSendTokens(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Transfere IOTA do jogador para outro jogador (to_id) ou endereço (to_address).
+	return nc.Subscribe("topic.sendTokens", instrument(s, "topic.sendTokens", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID  int    `json:"client_id"`
+			ToID      int    `json:"to_id"`
+			ToAddress string `json:"to_address"`
+			Amount    uint64 `json:"amount"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		digest, err := s.SendTokens(ctx, payload.ClientID, payload.ToID, payload.ToAddress, payload.Amount)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"status": "sent", "digest": digest, "amount": payload.Amount}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientGiftCard(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Transfere uma carta do jogador para outro jogador (presente).
+	return nc.Subscribe("topic.giftCard", instrument(s, "topic.giftCard", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID int    `json:"client_id"`
+			ToID     int    `json:"to_id"`
+			CardID   string `json:"card_id"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		digest, err := s.GiftCard(ctx, payload.ClientID, payload.ToID, payload.CardID)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"status": "gifted", "digest": digest}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientPlayerAddress(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Informa o endereço da carteira principal de um jogador, para o
+	// cliente conferir o destinatário antes de assinar uma transferência.
+	return nc.Subscribe("topic.playerAddress", instrument(s, "topic.playerAddress", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID int `json:"client_id"`
+			PlayerID int `json:"player_id"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		s.mu.Lock()
+		player, exists := s.players[payload.PlayerID]
+		s.mu.Unlock()
+		if !exists {
+			nc.Publish(m.Reply, []byte(`{"err":"player not found"}`))
+			return
+		}
+
+		resp := map[string]any{"address": player.Wallet.Address}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientWalletChallenge(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Gera o desafio que o jogador deve assinar com a chave da carteira externa.
+	return nc.Subscribe("topic.wallet.challenge", instrument(s, "topic.wallet.challenge", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID int    `json:"client_id"`
+			Address  string `json:"address"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		challenge, err := s.WalletChallenge(payload.ClientID, payload.Address)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"challenge": challenge}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientWalletLink(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Recebe a assinatura do desafio e vincula a carteira externa ao jogador.
+	return nc.Subscribe("topic.wallet.link", instrument(s, "topic.wallet.link", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID  int    `json:"client_id"`
+			Signature string `json:"signature"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		address, err := s.LinkWallet(payload.ClientID, payload.Signature)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"status": "linked", "address": address}
+		data, _ := json.Marshal(resp)
+		nc.Publish(m.Reply, data)
+	}))
+}
+
+func ClientCustody(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	// Liga/desliga o modo sem custódia (o cliente assina as próprias
+	// transferências). Ao ligar, a chave da carteira principal vai na
+	// resposta pela última vez; para desligar, o cliente a devolve em secret.
+	return nc.Subscribe("topic.custody", instrument(s, "topic.custody", func(ctx context.Context, m *nats.Msg) {
+		var payload struct {
+			ClientID    int    `json:"client_id"`
+			SelfCustody bool   `json:"self_custody"`
+			Secret      string `json:"secret"`
+		}
+		if err := json.Unmarshal(m.Data, &payload); err != nil {
+			nc.Publish(m.Reply, []byte(`{"err":"invalid payload"}`))
+			return
+		}
+
+		handover, err := s.SetSelfCustody(payload.ClientID, payload.SelfCustody, payload.Secret)
+		if err != nil {
+			resp := map[string]any{"err": err.Error()}
+			data, _ := json.Marshal(resp)
+			nc.Publish(m.Reply, data)
+			return
+		}
+
+		resp := map[string]any{"status": "ok", "self_custody": payload.SelfCustody}
+		if handover != "" {
+			resp["secret"] = handover
 		}
 		data, _ := json.Marshal(resp)
 		nc.Publish(m.Reply, data)
-	})
+	}))
 }
@@ -1,11 +1,17 @@
 package API
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //
@@ -55,6 +61,12 @@ type MintReq struct {
 	Value   uint64 `json:"value"`   // Valor em tokens da carta
 }
 
+// Requisição para criar várias cartas em uma única transação
+// (as cartas podem ter donos diferentes)
+type MintBatchReq struct {
+	Cards []MintReq `json:"cards"`
+}
+
 // Registrar o resultado de um duelo da partida
 type LogMatchReq struct {
 	Winner  string `json:"winner"`
@@ -63,6 +75,20 @@ type LogMatchReq struct {
 	ValLose uint64 `json:"val_lose"`
 }
 
+// Pagamento feito pela carteira da loja (prêmios e reembolsos)
+type PayoutReq struct {
+	Recipient string `json:"recipient"`
+	Amount    uint64 `json:"amount"`
+}
+
+// Registrar a classificação final de um torneio; Standings e Prizes
+// seguem a ordem das colocações
+type LogTournamentReq struct {
+	Tournament string   `json:"tournament"`
+	Standings  []string `json:"standings"`
+	Prizes     []uint64 `json:"prizes"`
+}
+
 // Estrutura usada para transferência de cartas (simples)
 type TransferReq struct {
 	OwnerSecret  string `json:"ownerSecret"`  // Chave secreta do remetente
@@ -70,10 +96,25 @@ type TransferReq struct {
 	Recipient    string `json:"recipient"`    // Endereço de destino
 }
 
+// Construção de uma transferência de carta a ser assinada pelo próprio
+// jogador (modo sem custódia): o worker devolve os bytes da transação.
+type BuildTransferReq struct {
+	Sender       string `json:"sender"`       // Endereço que detém a carta e paga o gás
+	CardObjectId string `json:"cardObjectId"` // ID do NFT
+	Recipient    string `json:"recipient"`    // Endereço de destino
+}
+
+// Submissão de uma transação já assinada pelo cliente
+type ExecuteSignedReq struct {
+	TxBytes   string `json:"txBytes"`   // Bytes BCS da transação, em base64
+	Signature string `json:"signature"` // Assinatura serializada IOTA, em base64
+}
+
 // DTO representando cartas retornadas pela blockchain
 type CardDTO struct {
 	ID    string `json:"id"`
 	Power int    `json:"power"`
+	Owner string `json:"owner,omitempty"` // Preenchido pelo servidor: carteira que detém a carta
 }
 
 // Resposta padrão da função GetCards
@@ -81,81 +122,297 @@ type GetCardsResponse struct {
 	Ok    bool      `json:"ok"`
 	Cards []CardDTO `json:"cards"`
 	Error string    `json:"error"`
+	Code  string    `json:"code"`
+}
+
+// Resposta genérica do worker para operações on-chain
+type chainResponse struct {
+	Ok       bool   `json:"ok"`
+	Digest   string `json:"digest"`
+	ObjectId string `json:"objectId"`
+	Error    string `json:"error"`
+	Code     string `json:"code"` // Código estável do erro (ex.: INSUFFICIENT_FUNDS)
 }
 
 //
 // ------------------------------
-//   FUNÇÕES DE CARTEIRA E ECONOMIA
+//        ERROS TIPADOS
 // ------------------------------
 //
 
-// Solicita ao servidor a criação de uma nova carteira blockchain
-func RequestCreateWallet(nc *nats.Conn) Wallet {
-	// Envia request ao servidor interno sem payload
-	response, err := nc.Request("internalServer.wallet", nil, 10*time.Second)
-	if err != nil {
-		fmt.Println(err.Error())
-		return Wallet{}
+var (
+	// O worker não respondeu dentro do prazo configurado.
+	ErrBridgeTimeout = errors.New("blockchain: tempo esgotado aguardando o worker")
+	// Nenhum worker está assinando o tópico (serviço fora do ar).
+	ErrBridgeUnavailable = errors.New("blockchain: worker indisponível")
+	// A carteira de origem não tem saldo para a operação.
+	ErrInsufficientFunds = errors.New("blockchain: saldo insuficiente")
+	// O endereço informado não é dono do objeto on-chain.
+	ErrNotOwner = errors.New("blockchain: carta não pertence ao jogador")
+	// O worker garante que a transação não foi executada (rejeitada ou
+	// abortada sem efeitos); repetir a operação não duplica nada.
+	ErrNotExecuted = errors.New("blockchain: transação não executada")
+)
+
+// ChainError representa uma falha reportada pelo worker ou pela rede IOTA.
+type ChainError struct {
+	Op  string // Tópico interno chamado (ex.: "mintCard")
+	Msg string
+}
+
+func (e *ChainError) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("blockchain: falha em %s", e.Op)
 	}
+	return fmt.Sprintf("blockchain: falha em %s: %s", e.Op, e.Msg)
+}
 
-	// Decodifica a resposta
-	msg := IotaRequest{}
-	json.Unmarshal(response.Data, &msg)
-	return msg.ClientID
+// Converte o código de erro do worker no erro tipado correspondente.
+func chainErr(op string, resp chainResponse) error {
+	switch resp.Code {
+	case "INSUFFICIENT_FUNDS":
+		return ErrInsufficientFunds
+	case "NOT_OWNER":
+		return ErrNotOwner
+	case "NOT_EXECUTED":
+		return fmt.Errorf("%w: %s", ErrNotExecuted, resp.Error)
+	}
+	return &ChainError{Op: op, Msg: resp.Error}
 }
 
-// Obtém saldo de uma carteira
-func RequestBalance(nc *nats.Conn, wallet Wallet) uint64 {
-	requestData := IotaRequest{ClientID: wallet}
-	data, _ := json.Marshal(requestData)
 
-	response, err := nc.Request("internalServer.balance", data, 20*time.Second)
-	if err != nil {
-		fmt.Println(err.Error())
-		return 0
+//
+// ------------------------------
+//      CLIENTE DA BLOCKCHAIN
+// ------------------------------
+//
+
+// Bridge é o contrato usado pela Store para operar na blockchain.
+// BlockchainClient é a implementação real (via NATS); testes podem
+// fornecer uma implementação falsa.
+type Bridge interface {
+	CreateWallet(ctx context.Context) (Wallet, error)
+	Balance(ctx context.Context, wallet Wallet) (uint64, error)
+	Faucet(ctx context.Context, wallet Wallet) (uint64, error)
+	Transaction(ctx context.Context, source, destination Wallet, value uint64) (digest string, err error)
+	MintCard(ctx context.Context, address string, value int) (digest, objectId string, err error)
+	MintBatch(ctx context.Context, cards []MintReq) (digest string, objectIds []string, err error)
+	LogMatch(ctx context.Context, winnerAddr, loserAddr string, valWin, valLose int) (digest, objectId string, err error)
+	LogTournament(ctx context.Context, tournamentID string, standings []string, prizes []uint64) (digest, objectId string, err error)
+	Payout(ctx context.Context, recipient string, amount uint64) (digest string, err error)
+	TransferCard(ctx context.Context, ownerSecret, cardObjectID, recipientAddr string) (digest string, err error)
+	ValidateOwnership(ctx context.Context, address, objectId string) error
+	AtomicSwap(ctx context.Context, userA Wallet, cardA string, userB Wallet, cardB string) error
+	GetCards(ctx context.Context, address string) ([]CardDTO, error)
+
+	// Modo sem custódia: o servidor só monta e submete a transação;
+	// quem assina é o cliente, com a própria chave.
+	BuildTransferCard(ctx context.Context, senderAddr, cardObjectID, recipientAddr string) (txBytes string, err error)
+	ExecuteSigned(ctx context.Context, txBytes, signature string) (digest string, err error)
+
+	// Ping verifica se o worker está respondendo (usado nos health checks).
+	Ping(ctx context.Context) error
+}
+
+// BridgeConfig define prazos e retentativas das chamadas ao worker.
+type BridgeConfig struct {
+	// Prazo de cada tentativa, por tópico; tópicos ausentes usam Timeout.
+	Timeouts map[string]time.Duration
+	Timeout  time.Duration
+
+	// Retentativas extras em timeout, aplicadas apenas a operações
+	// idempotentes (consultas e criação de carteira nunca cobram duas vezes).
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// DefaultBridgeConfig mantém os prazos históricos de cada operação:
+// mint e swap envolvem transações na rede e precisam de mais tempo.
+func DefaultBridgeConfig() BridgeConfig {
+	return BridgeConfig{
+		Timeouts: map[string]time.Duration{
+			"wallet":            10 * time.Second,
+			"balance":           20 * time.Second,
+			"faucet":            20 * time.Second,
+			"transaction":       20 * time.Second,
+			"mintCard":          10 * time.Second,
+			"mintBatch":         20 * time.Second,
+			"logMatch":          10 * time.Second,
+			"logTournament":     10 * time.Second,
+			"payout":            20 * time.Second,
+			"transferCard":      10 * time.Second,
+			"validateOwnership": 5 * time.Second,
+			"atomicSwap":        20 * time.Second,
+			"getCards":          5 * time.Second,
+			"buildTransferCard": 10 * time.Second,
+			"executeSigned":     20 * time.Second,
+			"ping":              2 * time.Second,
+		},
+		Timeout:      10 * time.Second,
+		Retries:      2,
+		RetryBackoff: 500 * time.Millisecond,
 	}
+}
 
-	msg := IotaRequest{}
-	json.Unmarshal(response.Data, &msg)
-	return msg.IotaValue
+// BlockchainClient fala com o worker TypeScript pelos tópicos internalServer.*.
+type BlockchainClient struct {
+	nc  *nats.Conn
+	cfg BridgeConfig
 }
 
-// Solicita tokens no faucet (teste)
-func RequestFaucet(nc *nats.Conn, wallet Wallet) uint64 {
-	requestData := IotaRequest{ClientID: wallet}
-	data, _ := json.Marshal(requestData)
+func NewBlockchainClient(nc *nats.Conn, cfg BridgeConfig) *BlockchainClient {
+	return &BlockchainClient{nc: nc, cfg: cfg}
+}
+
+func (c *BlockchainClient) timeout(op string) time.Duration {
+	if t, ok := c.cfg.Timeouts[op]; ok {
+		return t
+	}
+	return c.cfg.Timeout
+}
+
+// call envia req para internalServer.<op> e decodifica a resposta em resp.
+// Operações idempotentes são repetidas em caso de timeout.
+func (c *BlockchainClient) call(ctx context.Context, op string, idempotent bool, req, resp any) error {
+	var data []byte
+	if req != nil {
+		var err error
+		if data, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts += c.cfg.Retries
+	}
 
-	response, err := nc.Request("internalServer.faucet", data, 20*time.Second)
-	if err != nil {
-		fmt.Println(err.Error())
-		return 0
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			slog.Warn("bridge timeout, retrying", logBridgeOp, op, "attempt", i+1, "max_attempts", attempts)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.cfg.RetryBackoff * time.Duration(i)):
+			}
+		}
+
+		start := time.Now()
+		err = c.request(ctx, op, data, resp)
+		bridgeDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		bridgeCalls.WithLabelValues(op, bridgeResult(err)).Inc()
+		slog.Debug("bridge call", logBridgeOp, op, logSubject, "internalServer."+op, "duration", time.Since(start), "err", err)
+		if !errors.Is(err, ErrBridgeTimeout) {
+			return err
+		}
 	}
+	slog.Error("bridge call failed", logBridgeOp, op, "err", err)
+	return err
+}
 
-	msg := IotaRequest{}
-	json.Unmarshal(response.Data, &msg)
-	return msg.IotaValue
+func (c *BlockchainClient) request(ctx context.Context, op string, data []byte, resp any) (err error) {
+	subject := "internalServer." + op
+
+	// Cada tentativa vira um span filho do handler; o contexto segue nos
+	// headers para que o worker possa continuar o mesmo trace.
+	ctx, span := tracer.Start(ctx, subject, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("messaging.destination.name", subject)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	reqCtx, cancel := context.WithTimeout(ctx, c.timeout(op))
+	defer cancel()
+
+	out := nats.NewMsg(subject)
+	out.Data = data
+	injectTrace(ctx, out)
+
+	msg, err := c.nc.RequestMsgWithContext(reqCtx, out)
+	switch {
+	case errors.Is(err, nats.ErrNoResponders):
+		return ErrBridgeUnavailable
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, nats.ErrTimeout):
+		// Se o prazo estourado foi o do chamador, não adianta repetir.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w (%s)", ErrBridgeTimeout, op)
+	case err != nil:
+		return err
+	}
+
+	if err := json.Unmarshal(msg.Data, resp); err != nil {
+		return &ChainError{Op: op, Msg: "resposta inválida: " + err.Error()}
+	}
+	return nil
+}
+
+//
+// ------------------------------
+//   FUNÇÕES DE CARTEIRA E ECONOMIA
+// ------------------------------
+//
+
+// Solicita ao servidor a criação de uma nova carteira blockchain
+func (c *BlockchainClient) CreateWallet(ctx context.Context) (Wallet, error) {
+	// Envia request ao servidor interno sem payload
+	var msg IotaRequest
+	if err := c.call(ctx, "wallet", false, nil, &msg); err != nil {
+		return Wallet{}, err
+	}
+	if !msg.Ok || msg.ClientID.Address == "" {
+		return Wallet{}, &ChainError{Op: "wallet", Msg: "carteira não criada"}
+	}
+	return msg.ClientID, nil
+}
+
+// Obtém saldo de uma carteira
+func (c *BlockchainClient) Balance(ctx context.Context, wallet Wallet) (uint64, error) {
+	var msg IotaRequest
+	if err := c.call(ctx, "balance", true, IotaRequest{ClientID: wallet}, &msg); err != nil {
+		return 0, err
+	}
+	if !msg.Ok {
+		return 0, &ChainError{Op: "balance"}
+	}
+	return msg.IotaValue, nil
+}
+
+// Solicita tokens no faucet (teste)
+func (c *BlockchainClient) Faucet(ctx context.Context, wallet Wallet) (uint64, error) {
+	var msg IotaRequest
+	if err := c.call(ctx, "faucet", false, IotaRequest{ClientID: wallet}, &msg); err != nil {
+		return 0, err
+	}
+	if !msg.Ok {
+		return 0, &ChainError{Op: "faucet"}
+	}
+	return msg.IotaValue, nil
 }
 
-// Realiza uma transação entre dois usuários
-func RequestTransaction(nc *nats.Conn, source Wallet, destination Wallet, value int) bool {
+// Realiza uma transação entre dois usuários e retorna o digest
+func (c *BlockchainClient) Transaction(ctx context.Context, source, destination Wallet, value uint64) (string, error) {
 	// Monta payload da transação
 	requestData := IotaRequest{
 		ClientID:       source,
 		SecondClientID: destination,
-		IotaValue:      uint64(value),
+		IotaValue:      value,
 	}
 
-	data, _ := json.Marshal(requestData)
-
-	response, err := nc.Request("internalServer.transaction", data, 20*time.Second)
-	if err != nil {
-		fmt.Println(err.Error())
-		return false
+	var resp chainResponse
+	if err := c.call(ctx, "transaction", false, requestData, &resp); err != nil {
+		return "", err
 	}
-
-	msg := IotaRequest{}
-	json.Unmarshal(response.Data, &msg)
-	return msg.Ok
+	if !resp.Ok {
+		return "", chainErr("transaction", resp)
+	}
+	return resp.Digest, nil
 }
 
 //
@@ -165,31 +422,43 @@ func RequestTransaction(nc *nats.Conn, source Wallet, destination Wallet, value
 //
 
 // Solicita a criação de uma carta NFT no blockchain
-func RequestMintCard(nc *nats.Conn, address string, value int) (string, string, error) {
+func (c *BlockchainClient) MintCard(ctx context.Context, address string, value int) (string, string, error) {
 	req := MintReq{Address: address, Value: uint64(value)}
-	data, _ := json.Marshal(req)
 
-	// Mint é lento → timeout maior
-	msg, err := nc.Request("internalServer.mintCard", data, 10*time.Second)
-	if err != nil {
+	var resp chainResponse
+	if err := c.call(ctx, "mintCard", false, req, &resp); err != nil {
 		return "", "", err
 	}
+	if !resp.Ok {
+		return "", "", chainErr("mintCard", resp)
+	}
+	return resp.Digest, resp.ObjectId, nil
+}
 
-	var resp map[string]any
-	json.Unmarshal(msg.Data, &resp)
+// Solicita a criação de várias cartas em uma única transação. Os IDs
+// retornados seguem a ordem de cards ("" se o worker não identificou
+// o objeto criado para aquela carta).
+func (c *BlockchainClient) MintBatch(ctx context.Context, cards []MintReq) (string, []string, error) {
+	req := MintBatchReq{Cards: cards}
 
-	// Verifica sucesso
-	if ok, _ := resp["ok"].(bool); ok {
-		digest := resp["digest"].(string)
-		objectId := resp["objectId"].(string)
-		return digest, objectId, nil
+	var resp struct {
+		chainResponse
+		ObjectIds []string `json:"objectIds"`
 	}
-
-	return "", "", fmt.Errorf("erro no mint: %v", resp["error"])
+	if err := c.call(ctx, "mintBatch", false, req, &resp); err != nil {
+		return "", nil, err
+	}
+	if !resp.Ok {
+		return "", nil, chainErr("mintBatch", resp.chainResponse)
+	}
+	if len(resp.ObjectIds) != len(cards) {
+		return "", nil, &ChainError{Op: "mintBatch", Msg: fmt.Sprintf("%d IDs para %d cartas", len(resp.ObjectIds), len(cards))}
+	}
+	return resp.Digest, resp.ObjectIds, nil
 }
 
 // Registra uma partida na blockchain
-func RequestLogMatch(nc *nats.Conn, winnerAddr, loserAddr string, valWin, valLose int) (string, string, error) {
+func (c *BlockchainClient) LogMatch(ctx context.Context, winnerAddr, loserAddr string, valWin, valLose int) (string, string, error) {
 	req := LogMatchReq{
 		Winner:  winnerAddr,
 		Loser:   loserAddr,
@@ -197,21 +466,47 @@ func RequestLogMatch(nc *nats.Conn, winnerAddr, loserAddr string, valWin, valLos
 		ValLose: uint64(valLose),
 	}
 
-	data, _ := json.Marshal(req)
-
-	msg, err := nc.Request("internalServer.logMatch", data, 10*time.Second)
-	if err != nil {
+	var resp chainResponse
+	if err := c.call(ctx, "logMatch", false, req, &resp); err != nil {
 		return "", "", err
 	}
+	if !resp.Ok {
+		return "", "", chainErr("logMatch", resp)
+	}
+	return resp.Digest, resp.ObjectId, nil
+}
 
-	var resp map[string]any
-	json.Unmarshal(msg.Data, &resp)
+// Registra a classificação final de um torneio na blockchain
+func (c *BlockchainClient) LogTournament(ctx context.Context, tournamentID string, standings []string, prizes []uint64) (string, string, error) {
+	req := LogTournamentReq{
+		Tournament: tournamentID,
+		Standings:  standings,
+		Prizes:     prizes,
+	}
 
-	if ok, _ := resp["ok"].(bool); ok {
-		return resp["digest"].(string), resp["objectId"].(string), nil
+	var resp chainResponse
+	if err := c.call(ctx, "logTournament", false, req, &resp); err != nil {
+		return "", "", err
 	}
+	if !resp.Ok {
+		return "", "", chainErr("logTournament", resp)
+	}
+	return resp.Digest, resp.ObjectId, nil
+}
 
-	return "", "", fmt.Errorf("erro no mint: %v", resp["error"])
+// Envia IOTA da carteira da loja para um jogador (prêmios e reembolsos).
+// A chave da loja fica apenas no worker.
+func (c *BlockchainClient) Payout(ctx context.Context, recipient string, amount uint64) (string, error) {
+	req := PayoutReq{Recipient: recipient, Amount: amount}
+
+	var resp chainResponse
+	if err := c.call(ctx, "payout", false, req, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Ok {
+		return "", chainErr("payout", resp)
+	}
+	return resp.Digest, nil
 }
 
 //
@@ -221,52 +516,39 @@ func RequestLogMatch(nc *nats.Conn, winnerAddr, loserAddr string, valWin, valLos
 //
 
 // Transferência simples de NFT (não atômica, unidirecional)
-func RequestTransferCard(nc *nats.Conn, ownerSecret, cardObjectID, recipientAddr string) error {
+func (c *BlockchainClient) TransferCard(ctx context.Context, ownerSecret, cardObjectID, recipientAddr string) (string, error) {
 	req := TransferReq{
 		OwnerSecret:  ownerSecret,
 		CardObjectId: cardObjectID,
 		Recipient:    recipientAddr,
 	}
 
-	data, _ := json.Marshal(req)
-
-	msg, err := nc.Request("internalServer.transferCard", data, 10*time.Second)
-	if err != nil {
-		return err
+	var resp chainResponse
+	if err := c.call(ctx, "transferCard", false, req, &resp); err != nil {
+		return "", err
 	}
-
-	var resp map[string]any
-	json.Unmarshal(msg.Data, &resp)
-
-	if ok, _ := resp["ok"].(bool); !ok {
-		return fmt.Errorf("falha na transferência")
+	if !resp.Ok {
+		return "", chainErr("transferCard", resp)
 	}
-	return nil
+	return resp.Digest, nil
 }
 
-// Valida se um NFT pertence a um usuário
-func RequestValidateOwnership(nc *nats.Conn, address, objectId string) bool {
+// Valida se um NFT pertence a um usuário; retorna ErrNotOwner caso não pertença.
+func (c *BlockchainClient) ValidateOwnership(ctx context.Context, address, objectId string) error {
 	req := ValidateReq{Address: address, ObjectId: objectId}
-	data, _ := json.Marshal(req)
 
-	msg, err := nc.Request("internalServer.validateOwnership", data, 5*time.Second)
-	if err != nil {
-		fmt.Println("Erro validando ownership:", err)
-		return false
+	var resp chainResponse
+	if err := c.call(ctx, "validateOwnership", true, req, &resp); err != nil {
+		return err
 	}
-
-	var resp map[string]any
-	json.Unmarshal(msg.Data, &resp)
-
-	// Se existir "ok", retorna
-	if ok, exists := resp["ok"].(bool); exists {
-		return ok
+	if !resp.Ok {
+		return ErrNotOwner
 	}
-	return false
+	return nil
 }
 
 // Execução de uma troca atômica entre dois usuários
-func RequestAtomicSwap(nc *nats.Conn, userA Wallet, cardA string, userB Wallet, cardB string) error {
+func (c *BlockchainClient) AtomicSwap(ctx context.Context, userA Wallet, cardA string, userB Wallet, cardB string) error {
 	req := AtomicSwapReq{
 		UserA_Addr:   userA.Address,
 		UserA_Secret: userA.Secret,
@@ -277,39 +559,80 @@ func RequestAtomicSwap(nc *nats.Conn, userA Wallet, cardA string, userB Wallet,
 		CardB_ID:     cardB,
 	}
 
-	data, _ := json.Marshal(req)
-
-	// Atomic swap envolve múltiplas transações → timeout maior
-	msg, err := nc.Request("internalServer.atomicSwap", data, 20*time.Second)
-	if err != nil {
+	var resp chainResponse
+	if err := c.call(ctx, "atomicSwap", false, req, &resp); err != nil {
 		return err
 	}
-
-	var resp map[string]any
-	json.Unmarshal(msg.Data, &resp)
-
-	if ok, _ := resp["ok"].(bool); !ok {
-		return fmt.Errorf("falha na troca: %v", resp["error"])
+	if !resp.Ok {
+		return chainErr("atomicSwap", resp)
 	}
 	return nil
 }
 
 // Obtém todas as cartas pertencentes a um usuário na blockchain
-func RequestGetCardsFromChain(nc *nats.Conn, address string) ([]CardDTO, error) {
+func (c *BlockchainClient) GetCards(ctx context.Context, address string) ([]CardDTO, error) {
 	req := map[string]string{"address": address}
-	data, _ := json.Marshal(req)
 
-	msg, err := nc.Request("internalServer.getCards", data, 5*time.Second)
-	if err != nil {
+	var resp GetCardsResponse
+	if err := c.call(ctx, "getCards", true, req, &resp); err != nil {
 		return nil, err
 	}
+	if !resp.Ok {
+		return nil, &ChainError{Op: "getCards", Msg: resp.Error}
+	}
+	return resp.Cards, nil
+}
 
-	var resp GetCardsResponse
-	json.Unmarshal(msg.Data, &resp)
+//
+// ------------------------------
+//     MODO SEM CUSTÓDIA
+// ------------------------------
+//
 
+// Monta (sem assinar) a transferência de uma carta de senderAddr para
+// recipientAddr; retorna ErrNotOwner se a carta não pertencer ao remetente.
+func (c *BlockchainClient) BuildTransferCard(ctx context.Context, senderAddr, cardObjectID, recipientAddr string) (string, error) {
+	req := BuildTransferReq{
+		Sender:       senderAddr,
+		CardObjectId: cardObjectID,
+		Recipient:    recipientAddr,
+	}
+
+	var resp struct {
+		chainResponse
+		TxBytes string `json:"txBytes"`
+	}
+	if err := c.call(ctx, "buildTransferCard", true, req, &resp); err != nil {
+		return "", err
+	}
 	if !resp.Ok {
-		return nil, fmt.Errorf("erro blockchain: %s", resp.Error)
+		return "", chainErr("buildTransferCard", resp.chainResponse)
 	}
+	return resp.TxBytes, nil
+}
 
-	return resp.Cards, nil
+// Submete uma transação assinada pelo cliente e retorna o digest.
+func (c *BlockchainClient) ExecuteSigned(ctx context.Context, txBytes, signature string) (string, error) {
+	req := ExecuteSignedReq{TxBytes: txBytes, Signature: signature}
+
+	var resp chainResponse
+	if err := c.call(ctx, "executeSigned", false, req, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Ok {
+		return "", chainErr("executeSigned", resp)
+	}
+	return resp.Digest, nil
+}
+
+// Consulta leve ao worker, sem tocar na blockchain.
+func (c *BlockchainClient) Ping(ctx context.Context) error {
+	var resp chainResponse
+	if err := c.call(ctx, "ping", false, nil, &resp); err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return chainErr("ping", resp)
+	}
+	return nil
 }
@@ -0,0 +1,108 @@
+package API
+
+import (
+	"bytes"
+	"testing"
+)
+
+// snapshotStore cria uma Store com dois jogadores e uma troca cega na
+// fila, para conferir o que vai (e o que não vai) no snapshot.
+func snapshotStore(t *testing.T, key string) (*Store, []Player) {
+	t.Helper()
+	s, _ := newTestStore(t, 0)
+	s.SetStateKey(key)
+	var players []Player
+	for range 2 {
+		id, spare, err := newTestPlayer(s, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _ := s.getPlayer(id)
+		players = append(players, p)
+		s.mu.Lock()
+		s.BlindTradeQueue = append(s.BlindTradeQueue, BlindTradeRequest{PlayerID: id, CardHex: spare[0], Wallet: p.Wallet})
+		s.mu.Unlock()
+	}
+	return s, players
+}
+
+func TestSnapshotSealsWalletSecrets(t *testing.T) {
+	s, players := snapshotStore(t, "cluster-secret")
+	data, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range players {
+		if bytes.Contains(data, []byte(p.Wallet.Secret)) {
+			t.Fatalf("snapshot contains the plaintext key of player %d", p.Id)
+		}
+	}
+	// Cifra determinística: o snapshot não muda sem mudança de estado.
+	if again, _ := s.Snapshot(); !bytes.Equal(data, again) {
+		t.Error("two snapshots of the same state differ")
+	}
+
+	restored, _ := newTestStore(t, 0)
+	restored.SetStateKey("cluster-secret")
+	if err := restored.Restore(data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	for _, p := range players {
+		if got := restored.players[p.Id].Wallet.Secret; got != p.Wallet.Secret {
+			t.Errorf("player %d restored with key %q, want %q", p.Id, got, p.Wallet.Secret)
+		}
+	}
+	if len(restored.BlindTradeQueue) != len(players) {
+		t.Fatalf("restored %d blind trades, want %d", len(restored.BlindTradeQueue), len(players))
+	}
+	for _, r := range restored.BlindTradeQueue {
+		if r.Wallet.Secret != restored.players[r.PlayerID].Wallet.Secret {
+			t.Errorf("blind trade of player %d restored without the wallet key", r.PlayerID)
+		}
+	}
+
+	// Com outra chave (ou nenhuma) o nó não pode assumir.
+	for _, key := range []string{"other-secret", ""} {
+		wrong, _ := newTestStore(t, 0)
+		wrong.SetStateKey(key)
+		if err := wrong.Restore(data); err == nil {
+			t.Errorf("Restore with key %q succeeded, want error", key)
+		}
+	}
+}
+
+func TestSnapshotWithoutStateKeyOmitsSecrets(t *testing.T) {
+	s, players := snapshotStore(t, "")
+	data, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range players {
+		if bytes.Contains(data, []byte(p.Wallet.Secret)) {
+			t.Fatalf("snapshot contains the plaintext key of player %d", p.Id)
+		}
+	}
+	restored, _ := newTestStore(t, 0)
+	if err := restored.Restore(data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(restored.players) != len(players) {
+		t.Errorf("restored %d players, want %d", len(restored.players), len(players))
+	}
+}
+
+// Snapshots gravados antes da cifragem trazem a chave em claro.
+func TestRestoreLegacyPlaintextSecret(t *testing.T) {
+	legacy := []byte(`{"players":{"7":{"Id":7,"Wallet":{"address":"0xa7","secret":"legacy-key"},"Cards":null}}}`)
+	s, _ := newTestStore(t, 0)
+	s.SetStateKey("cluster-secret")
+	if err := s.Restore(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.players[7].Wallet.Secret; got != "legacy-key" {
+		t.Errorf("legacy key restored as %q", got)
+	}
+	if s.players[7].Cards == nil {
+		t.Error("restored player has nil Cards")
+	}
+}
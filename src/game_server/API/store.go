@@ -1,33 +1,474 @@
 package API
 
-import "sync"
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
 
 // Estrutura para quem está esperando na fila
+// Owner é a carteira que detém a carta (a do servidor ou uma externa
+// vinculada); SelfSigned indica que a transferência precisa ser
+// assinada pelo próprio jogador.
 type BlindTradeRequest struct {
-	PlayerID int
-	CardHex  string
-	Wallet   Wallet
+	PlayerID   int
+	CardHex    string
+	Wallet     Wallet
+	Owner      string
+	SelfSigned bool
 }
 
 type Store struct {
-	mu           sync.Mutex
-	players      map[int]Player
-	matchHistory map[string]matchStruct
-	gameQueue    []int
-	Cards        [][3]int
-	count        int
-	NodeID       string 	
+	mu              sync.Mutex
+	players         map[int]Player
+	matchHistory    map[string]matchStruct
+	gameQueue       []int
+	packs           int // Pacotes ainda à venda
+	count           int
+	NodeID          string
 	BlindTradeQueue []BlindTradeRequest
+
+	bridge Bridge    // Acesso à blockchain (worker via NATS)
+	pub    Publisher // Notificações assíncronas para os jogadores
+
+	// Faucet de testes: pode ser desligado em produção e tem um
+	// intervalo mínimo entre pedidos do mesmo jogador.
+	FaucetEnabled  bool
+	FaucetCooldown time.Duration
+	lastFaucet     map[int]time.Time
+
+	// Última mensagem recebida de cada jogador (métrica de jogadores online).
+	lastSeen map[int]time.Time
+
+	// Limite de IOTA por transferência entre jogadores (0 = sem limite).
+	MaxTransfer uint64
+
+	// Regras de montagem de decks: número exato de cartas e limite da
+	// soma das forças (0 = sem limite).
+	DeckSize     int
+	DeckPowerCap int
+
+	// Estratégia padrão do bot nas partidas de treino e as últimas
+	// forças jogadas por cada jogador (usadas pela estratégia adaptativa).
+	BotStrategy string
+	playHistory map[int][]int
+
+	// Torneios por ID e o prazo de cada partida antes do W.O.
+	// (0 = defaultTournamentMatchTimeout).
+	tournaments            map[string]*Tournament
+	TournamentMatchTimeout time.Duration
+
+	// Prazos de W.O. armados neste nó. Só o líder arma timers; os prazos
+	// ficam nos confrontos e são rearmados ao assumir (ver setLeading).
+	leading   bool
+	walkovers map[string]*time.Timer
+
+	// Fila de partidas apostadas e a taxa da casa sobre o pote, em
+	// porcentagem (0 = sem taxa).
+	wagerQueue []wagerEntry
+	WagerRake  uint64
+
+	// Fila de partidas ante e as cartas travadas (carta → jogador).
+	anteQueue []anteEntry
+	anteLocks map[string]int
+
+	// Desafios de partida privada pendentes, por código.
+	challenges map[string]Challenge
+
+	// Desafios pendentes de vínculo de carteira externa, por jogador.
+	linkChallenges map[int]linkChallenge
+
+	// Sorteio verificável: época corrente, épocas já reveladas e a chave
+	// que cifra a semente no snapshot (ver SetStateKey).
+	fair           fairEpoch
+	revealedEpochs []FairEpochInfo
+	stateKey       []byte
+
+	// Controle de encerramento: operações em andamento (com descrição,
+	// para o journal) e a flag que bloqueia novos trabalhos. idle é
+	// fechado quando inflight esvazia com alguém aguardando em WaitIdle.
+	closing  bool
+	idle     chan struct{}
+	workSeq  int
+	inflight map[int]string
+	journal  []string // Operações interrompidas herdadas de um líder anterior
+
+	// Sinaliza ao cluster que o estado mudou (ver touch).
+	changed chan struct{}
 }
 
-func NewStore() *Store {
+// ErrBanned é retornado quando um jogador banido tenta operar.
+var ErrBanned = errors.New("jogador banido")
+
+// Publisher é o subconjunto do *nats.Conn usado para falar com os
+// jogadores: notificações e pedidos de assinatura (modo sem custódia).
+type Publisher interface {
+	Publish(subject string, data []byte) error
+	RequestWithContext(ctx context.Context, subject string, data []byte) (*nats.Msg, error)
+}
+
+func NewStore(nodeID string, bridge Bridge, pub Publisher) *Store {
 	return &Store{
 		players:         make(map[int]Player),
 		matchHistory:    make(map[string]matchStruct),
 		gameQueue:       make([]int, 0),		
 		count:           0,
-		Cards:           setupPacks(900),
-		NodeID:          "server-central",
+		packs:           initialPacks,
+		NodeID:          nodeID,
 		BlindTradeQueue: make([]BlindTradeRequest, 0),
+		inflight:        make(map[int]string),
+		bridge:          bridge,
+		pub:             pub,
+		FaucetEnabled:   true,
+		FaucetCooldown:  10 * time.Minute,
+		lastFaucet:      make(map[int]time.Time),
+		MaxTransfer:     10_000_000_000,
+		DeckSize:        defaultDeckSize,
+		BotStrategy:     "adaptive",
+		playHistory:     make(map[int][]int),
+		challenges:      make(map[string]Challenge),
+		tournaments:     make(map[string]*Tournament),
+		anteLocks:       make(map[string]int),
+		walkovers:       make(map[string]*time.Timer),
+		linkChallenges:  make(map[int]linkChallenge),
+		lastSeen:        make(map[int]time.Time),
+		fair:            newFairEpoch(1),
+		changed:         make(chan struct{}, 1),
+	}
+}
+
+// touch avisa o cluster que o estado mudou, para replicar logo em vez de
+// esperar a próxima renovação do lease. Nunca bloqueia: avisos seguidos
+// se acumulam em um só.
+func (s *Store) touch() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Tópico exclusivo de um jogador para notificações push
+// (ex.: player.7.gift). O cliente assina player.<id>.> ao logar.
+func playerSubject(id int, event string) string {
+	return fmt.Sprintf("player.%d.%s", id, event)
+}
+
+// notify publica um evento JSON no tópico exclusivo do jogador.
+func (s *Store) notify(id int, event string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	s.pub.Publish(playerSubject(id, event), data)
+}
+
+// Estado serializável da Store, replicado entre os nós do cluster
+// para que um novo líder assuma exatamente de onde o anterior parou.
+// O bucket de estado pode ser lido por qualquer cliente NATS: a semente
+// da época corrente e as chaves das carteiras só vão cifradas com a
+// chave de estado (STATE_KEY), para que o novo líder honre o compromisso
+// já publicado e continue assinando pelos jogadores sem que ninguém
+// possa prever os sorteios ou mover as cartas. Sem a chave, esses
+// segredos não são replicados: o novo líder abre outra época e não
+// consegue transferir as cartas custodiadas.
+type storeSnapshot struct {
+	Players         map[int]snapshotPlayer `json:"players"`
+	MatchHistory    map[string]matchStruct `json:"match_history"`
+	GameQueue       []int                  `json:"game_queue"`
+	Packs           int                    `json:"packs"`
+	Count           int                    `json:"count"`
+	BlindTradeQueue []BlindTradeRequest    `json:"blind_trade_queue"`
+	Journal         []string               `json:"journal,omitempty"`
+	Fair            sealedEpoch            `json:"fair"`
+	RevealedEpochs  []FairEpochInfo        `json:"revealed_epochs,omitempty"`
+	Tournaments     map[string]*Tournament `json:"tournaments,omitempty"`
+	WagerQueue      []wagerEntry           `json:"wager_queue,omitempty"`
+	AnteQueue       []anteEntry            `json:"ante_queue,omitempty"`
+	AnteLocks       map[string]int         `json:"ante_locks,omitempty"`
+
+	// Snapshots antigos guardavam o pool de pacotes em vez do total.
+	LegacyCards [][3]int `json:"cards,omitempty"`
+}
+
+// snapshotPlayer é o jogador como vai no snapshot: Wallet.Secret fica
+// vazio e a chave vai em SealedSecret, cifrada com a chave de estado.
+// Snapshots antigos trazem a chave em claro em Wallet.Secret.
+type snapshotPlayer struct {
+	Player
+	SealedSecret string `json:",omitempty"`
+}
+
+// sealPlayersLocked prepara os jogadores para o snapshot, cifrando a
+// chave de cada carteira (omitida sem chave de estado). Exige s.mu travado.
+func (s *Store) sealPlayersLocked() map[int]snapshotPlayer {
+	out := make(map[int]snapshotPlayer, len(s.players))
+	for id, p := range s.players {
+		sp := snapshotPlayer{Player: p}
+		sp.Wallet.Secret = ""
+		if aead, nonce, err := stateAEAD(s.stateKey, "wallet-secret-nonce:"+p.Wallet.Address); err == nil && p.Wallet.Secret != "" {
+			sp.SealedSecret = hex.EncodeToString(aead.Seal(nil, nonce, []byte(p.Wallet.Secret), []byte(p.Wallet.Address)))
+		}
+		out[id] = sp
+	}
+	return out
+}
+
+// openPlayersLocked recupera os jogadores de um snapshot. Uma chave que
+// não abre (STATE_KEY diferente ou ausente) é erro: assumir sem ela e
+// replicar o estado apagaria a chave de vez. Exige s.mu travado.
+func (s *Store) openPlayersLocked(sealed map[int]snapshotPlayer) (map[int]Player, error) {
+	players := make(map[int]Player, len(sealed))
+	missing := 0
+	for id, sp := range sealed {
+		p := sp.Player
+		if sp.SealedSecret != "" {
+			aead, nonce, err := stateAEAD(s.stateKey, "wallet-secret-nonce:"+p.Wallet.Address)
+			if err != nil {
+				return nil, fmt.Errorf("carteira do jogador %d: %w", id, err)
+			}
+			data, err := hex.DecodeString(sp.SealedSecret)
+			if err != nil {
+				return nil, fmt.Errorf("carteira do jogador %d: %v", id, err)
+			}
+			secret, err := aead.Open(nil, nonce, data, []byte(p.Wallet.Address))
+			if err != nil {
+				return nil, fmt.Errorf("carteira do jogador %d cifrada com outra chave: %v", id, err)
+			}
+			p.Wallet.Secret = string(secret)
+		}
+		if p.Wallet.Secret == "" && !p.SelfCustody {
+			missing++
+		}
+		if p.Cards == nil {
+			p.Cards = make(map[string]int)
+		}
+		players[id] = p
+	}
+	if missing > 0 {
+		slog.Warn("wallet keys not replicated, custodial cards cannot be moved", "players", missing)
+	}
+	return players, nil
+}
+
+// Snapshot serializa o estado atual da Store em JSON.
+func (s *Store) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A fila de trocas guarda a carteira principal do jogador: a chave é
+	// reposta a partir dos jogadores no Restore.
+	trades := make([]BlindTradeRequest, len(s.BlindTradeQueue))
+	for i, r := range s.BlindTradeQueue {
+		r.Wallet.Secret = ""
+		trades[i] = r
+	}
+
+	return json.Marshal(storeSnapshot{
+		Players:         s.sealPlayersLocked(),
+		MatchHistory:    s.matchHistory,
+		GameQueue:       s.gameQueue,
+		Packs:           s.packs,
+		Count:           s.count,
+		BlindTradeQueue: trades,
+		Journal:         s.journalLocked(),
+		Fair:            s.sealEpochLocked(),
+		RevealedEpochs:  s.revealedEpochs,
+		Tournaments:     s.tournaments,
+		WagerQueue:      s.wagerQueue,
+		AnteQueue:       s.anteQueue,
+		AnteLocks:       s.anteLocks,
+	})
+}
+
+// Restore substitui o estado da Store pelo conteúdo de um snapshot.
+func (s *Store) Restore(data []byte) error {
+	var snap storeSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	players, err := s.openPlayersLocked(snap.Players)
+	if err != nil {
+		return err
+	}
+	s.players = players
+	s.matchHistory = snap.MatchHistory
+	if s.matchHistory == nil {
+		s.matchHistory = make(map[string]matchStruct)
+	}
+	s.gameQueue = snap.GameQueue
+	s.packs = snap.Packs
+	if snap.LegacyCards != nil {
+		s.packs = len(snap.LegacyCards)
+	}
+	s.count = snap.Count
+	s.BlindTradeQueue = snap.BlindTradeQueue
+	for i, r := range s.BlindTradeQueue {
+		if p := s.players[r.PlayerID]; r.Wallet.Secret == "" && r.Wallet.Address == p.Wallet.Address {
+			s.BlindTradeQueue[i].Wallet.Secret = p.Wallet.Secret
+		}
+	}
+	s.journal = snap.Journal
+	s.revealedEpochs = snap.RevealedEpochs
+	s.tournaments = snap.Tournaments
+	if s.tournaments == nil {
+		s.tournaments = make(map[string]*Tournament)
+	}
+	s.wagerQueue = snap.WagerQueue
+	s.anteQueue = snap.AnteQueue
+	s.anteLocks = snap.AnteLocks
+	if s.anteLocks == nil {
+		s.anteLocks = make(map[string]int)
+	}
+	fair, err := s.openEpochLocked(snap.Fair)
+	if err != nil {
+		// Sem a semente o compromisso publicado não pode ser honrado:
+		// a época é abandonada (sem revelação) e outra começa.
+		next := snap.Fair.Number + 1
+		if n := len(s.revealedEpochs); n > 0 && s.revealedEpochs[n-1].Number >= next {
+			next = s.revealedEpochs[n-1].Number + 1
+		}
+		if snap.Fair.Commitment != "" {
+			slog.Warn("fairness epoch abandoned after restore", "epoch", snap.Fair.Number, "next_epoch", next, "err", err)
+		}
+		fair = newFairEpoch(next)
+	}
+	s.fair = fair
+	return nil
+}
+
+// --- CICLO DE VIDA ---
+
+var ErrShuttingDown = errors.New("servidor em manutenção, tente novamente em instantes")
+
+// beginWork registra uma operação longa (pagamento, mint, log on-chain)
+// para que o encerramento aguarde sua conclusão. Retorna a função que
+// marca o fim da operação, ou erro se o servidor já está encerrando.
+func (s *Store) beginWork(desc string) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return nil, ErrShuttingDown
+	}
+	return s.trackWorkLocked(desc), nil
+}
+
+// trackWork registra a operação mesmo durante o encerramento; usado
+// quando interromper deixaria o jogo inconsistente (ex.: ResolveMatch).
+func (s *Store) trackWork(desc string) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trackWorkLocked(desc)
+}
+
+func (s *Store) trackWorkLocked(desc string) func() {
+	s.workSeq++
+	id := s.workSeq
+	s.inflight[id] = desc
+	s.touch()
+
+	return func() {
+		defer s.touch()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.inflight, id)
+		if len(s.inflight) == 0 && s.idle != nil {
+			close(s.idle)
+			s.idle = nil
+		}
 	}
-}
\ No newline at end of file
+}
+
+func (s *Store) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// Journal lista as operações em andamento e as herdadas de um líder
+// que caiu no meio delas, para reconciliação manual.
+func (s *Store) Journal() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.journalLocked()
+}
+
+func (s *Store) journalLocked() []string {
+	out := append([]string{}, s.journal...)
+	for _, desc := range s.inflight {
+		out = append(out, desc)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Filas esvaziadas no encerramento, com os jogadores a avisar.
+type drainedQueues struct {
+	Game  []int
+	Blind []BlindTradeRequest
+	Wager []wagerEntry
+	Ante  []anteEntry
+}
+
+// BeginShutdown impede novas entradas nas filas e novas operações longas,
+// esvaziando as filas para que os jogadores possam ser avisados.
+// As cartas reservadas na troca cega reaparecem no cache na próxima
+// consulta on-chain (topic.seeCards), pois nunca saíram da carteira.
+// Quem está na fila de apostas ainda não pagou nada (a aposta só é
+// cobrada ao formar o par), e as cartas da fila ante são destravadas.
+func (s *Store) BeginShutdown() drainedQueues {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closing = true
+
+	drained := drainedQueues{
+		Game:  s.gameQueue,
+		Blind: s.BlindTradeQueue,
+		Wager: s.wagerQueue,
+		Ante:  s.anteQueue,
+	}
+	for _, a := range s.anteQueue {
+		delete(s.anteLocks, a.CardID)
+	}
+	s.gameQueue = make([]int, 0)
+	s.BlindTradeQueue = make([]BlindTradeRequest, 0)
+	s.wagerQueue = nil
+	s.anteQueue = nil
+	return drained
+}
+
+// WaitIdle aguarda as operações em andamento terminarem ou o contexto expirar.
+// Operações registradas com trackWork durante a espera também são aguardadas
+// (um sync.WaitGroup não permite Add concorrente com Wait).
+func (s *Store) WaitIdle(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.inflight) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+	}
+	idle := s.idle
+	s.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
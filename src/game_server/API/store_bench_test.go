@@ -0,0 +1,181 @@
+package API
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Latência simulada de uma chamada ao worker nos benchmarks. Como a
+// Store não segura s.mu durante chamadas à blockchain, a vazão cresce
+// com o número de goroutines em vez de ficar presa a 1/benchLatency.
+const benchLatency = 2 * time.Millisecond
+
+// benchPlayers cria n jogadores com deck ativo (sem latência, para não
+// pesar no tempo medido) e liga a latência em seguida.
+func benchPlayers(b *testing.B, n int) (*Store, *fakeBridge, []int) {
+	b.Helper()
+	s, bridge := newTestStore(b, 0)
+	ids := make([]int, n)
+	for i := range ids {
+		id, _, err := newTestPlayer(s, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = id
+	}
+	bridge.latency = benchLatency
+	return s, bridge, ids
+}
+
+// reportThroughput publica a vazão (operações por segundo) do benchmark.
+func reportThroughput(b *testing.B, unit string) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), unit)
+}
+
+// BenchmarkOpenPackParallel mede a compra de pacotes por vários
+// jogadores ao mesmo tempo (cobrança e mint com latência de rede).
+func BenchmarkOpenPackParallel(b *testing.B) {
+	for _, par := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("parallelism=%d", par), func(b *testing.B) {
+			s, _, ids := benchPlayers(b, par)
+			if _, err := s.RefillPacks(b.N); err != nil {
+				b.Fatal(err)
+			}
+			var next atomic.Int32
+
+			b.SetParallelism(par)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				id := ids[int(next.Add(1)-1)%len(ids)]
+				for pb.Next() {
+					if _, err := s.OpenPack(ctx, id, "bench", func(PackEvent) {}); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			reportThroughput(b, "packs/s")
+		})
+	}
+}
+
+// BenchmarkMatchesParallel mede partidas resolvidas por segundo. As
+// goroutines se dividem entre os pares de jogadores, e o log on-chain
+// de uma partida não atrasa as outras.
+func BenchmarkMatchesParallel(b *testing.B) {
+	for _, par := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("parallelism=%d", par), func(b *testing.B) {
+			s, _, ids := benchPlayers(b, 2*par)
+
+			// Força jogada por cada jogador; empate não chega ao log
+			// on-chain, então o segundo do par é desempatado no cache.
+			powers := make([]int, len(ids))
+			for i, id := range ids {
+				v, err := deckPower(s, id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				powers[i] = v
+			}
+			for i := 1; i < len(ids); i += 2 {
+				if powers[i] == powers[i-1] {
+					powers[i]++
+					s.mu.Lock()
+					deck, _ := s.activeDeckLocked(ids[i])
+					s.updateCardsLocked(ids[i], func(cards map[string]int) { cards[deck.Cards[0]] = powers[i] })
+					s.mu.Unlock()
+				}
+			}
+			var next atomic.Int32
+
+			b.SetParallelism(par)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				pair := int(next.Add(1)-1) % par
+				p1, p2 := ids[2*pair], ids[2*pair+1]
+				v1, v2 := powers[2*pair], powers[2*pair+1]
+				for pb.Next() {
+					game := benchMatch(s, p1, p2)
+					s.PlayCard(ctx, game, p1, v1)
+					s.PlayCard(ctx, game, p2, v2)
+				}
+			})
+			reportThroughput(b, "matches/s")
+		})
+	}
+}
+
+// BenchmarkReadsDuringSlowTrades mede leituras da Store (o que login,
+// consulta de decks e matchmaking fazem) enquanto trocas cegas com
+// AtomicSwap lento (20ms) rodam em segundo plano. Com o lock preso
+// durante a troca, cada leitura esperaria pelo swap em andamento.
+func BenchmarkReadsDuringSlowTrades(b *testing.B) {
+	s, bridge, ids := benchPlayers(b, 4)
+	bridge.latency = 20 * time.Millisecond
+
+	// Dois jogadores trocam a mesma dupla de cartas sem parar.
+	a, c := ids[0], ids[1]
+	pa, _ := s.getPlayer(a)
+	pc, _ := s.getPlayer(c)
+	cardA, cardC := firstCard(pa), firstCard(pc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			s.mu.Lock()
+			s.BlindTradeQueue = append(s.BlindTradeQueue,
+				BlindTradeRequest{PlayerID: a, CardHex: cardA, Wallet: pa.Wallet},
+				BlindTradeRequest{PlayerID: c, CardHex: cardC, Wallet: pc.Wallet},
+			)
+			s.mu.Unlock()
+			s.ProcessBlindQueue(ctx)
+			cardA, cardC = cardC, cardA
+		}
+	}()
+	time.Sleep(time.Millisecond) // Deixa a primeira troca começar
+
+	reader := ids[2]
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := s.getPlayer(reader); err != nil {
+				b.Error(err)
+				return
+			}
+			s.Decks(reader)
+		}
+	})
+	reportThroughput(b, "reads/s")
+	b.StopTimer()
+
+	cancel()
+	<-done
+}
+
+// benchMatch registra uma partida entre p1 e p2 sem passar pela fila,
+// para que cada goroutine jogue com o próprio par.
+func benchMatch(s *Store, p1, p2 int) string {
+	id := fmt.Sprintf("bench-%d", benchGames.Add(1))
+	s.mu.Lock()
+	s.matchHistory[id] = matchStruct{P1: p1, P2: p2, SelfId: id}
+	s.mu.Unlock()
+	return id
+}
+
+var benchGames atomic.Int64
+
+// firstCard devolve uma carta qualquer do cache do jogador.
+func firstCard(p Player) string {
+	for card := range p.Cards {
+		return card
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStoreConcurrentOperations roda, ao mesmo tempo, abertura de
+// pacotes, partidas, trocas cegas, presentes, leituras do estado e o
+// encerramento. Deve passar com `go test -race`; ao final confere que o
+// cache de cartas não diverge da blockchain simulada.
+func TestStoreConcurrentOperations(t *testing.T) {
+	s, bridge := newTestStore(t, 100*time.Microsecond)
+	ctx := context.Background()
+
+	const players, packs = 16, 3
+	ids := make([]int, players)
+	spare := make([][]string, players)
+
+	// Criação de jogadores, pacotes e decks em paralelo.
+	var setup sync.WaitGroup
+	for i := range ids {
+		setup.Add(1)
+		go func() {
+			defer setup.Done()
+			var err error
+			if ids[i], spare[i], err = newTestPlayer(s, packs); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	setup.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		resolved atomic.Int32
+		stop     = make(chan struct{})
+	)
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+		}
+		return false
+	}
+
+	// Partidas: cada jogador volta para a fila depois de jogar.
+	play := func(game matchStruct, id int) {
+		defer wg.Done()
+		power, err := deckPower(s, id)
+		if err != nil {
+			t.Errorf("deckPower(%d): %v", id, err)
+			return
+		}
+		if _, _, _, _, _, err := s.PlayCard(ctx, game.SelfId, id, power); err == nil {
+			resolved.Add(1)
+		}
+		s.JoinQueue(id)
+	}
+	for _, id := range ids {
+		if _, err := s.JoinQueue(id); err != nil {
+			t.Fatalf("JoinQueue(%d): %v", id, err)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stopped() {
+			game, err := s.CreateMatch()
+			if err != nil {
+				time.Sleep(50 * time.Microsecond)
+				continue
+			}
+			wg.Add(2)
+			go play(game, game.P1)
+			go play(game, game.P2)
+		}
+	}()
+
+	for i, id := range ids {
+		// Metade das cartas livres vai para a troca cega, a outra metade
+		// é presenteada ao próximo jogador.
+		half := len(spare[i]) / 2
+		trade, gift := spare[i][:half], spare[i][half:]
+		next := ids[(i+1)%players]
+
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for _, card := range trade {
+				// Só uma entrada por jogador: espera a anterior ser pareada.
+				for !stopped() {
+					err := s.JoinBlindTrade(ctx, id, card)
+					if err == nil || errors.Is(err, ErrShuttingDown) || errors.Is(err, ErrNotOwner) {
+						break
+					}
+					time.Sleep(100 * time.Microsecond)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, card := range gift {
+				s.GiftCard(ctx, id, next, card)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			s.OpenPack(ctx, id, "concurrent", func(PackEvent) {})
+		}()
+	}
+
+	// Leituras concorrentes (replicação, métricas, consultas).
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stopped() {
+			if _, err := s.Snapshot(); err != nil {
+				t.Errorf("Snapshot: %v", err)
+				return
+			}
+			s.Decks(ids[0])
+			s.FairEpochs()
+			s.Journal()
+			// Jogadores devolvidos pela Store são lidos sem o lock (ex.: ao
+			// serializar a resposta), enquanto outras goroutines mexem nos
+			// caches.
+			for _, id := range ids {
+				if p, err := s.getPlayer(id); err == nil {
+					json.Marshal(p)
+				}
+			}
+		}
+	}()
+
+	// Encerramento no meio da carga: depois de algumas partidas, ou
+	// por tempo, para não depender da velocidade da máquina.
+	deadline := time.After(5 * time.Second)
+	for resolved.Load() < players/2 {
+		select {
+		case <-deadline:
+		case <-time.After(time.Millisecond):
+			continue
+		}
+		break
+	}
+	s.BeginShutdown()
+	close(stop)
+	wg.Wait()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := s.WaitIdle(waitCtx); err != nil {
+		t.Fatalf("WaitIdle: %v (pending: %v)", err, s.Journal())
+	}
+	if pending := s.Journal(); len(pending) != 0 {
+		t.Errorf("operations still tracked after WaitIdle: %v", pending)
+	}
+
+	// Cartas: nenhuma some nem é duplicada, e o cache só lista cartas que
+	// o jogador de fato detém na blockchain.
+	if got, want := bridge.minted(), players*packs*3; got < want {
+		t.Errorf("minted %d cards, want at least %d", got, want)
+	}
+	seen := map[string]int{}
+	for _, id := range ids {
+		p, err := s.getPlayer(id)
+		if err != nil {
+			t.Fatalf("getPlayer(%d): %v", id, err)
+		}
+		for card := range p.Cards {
+			if prev, dup := seen[card]; dup {
+				t.Errorf("card %s cached by players %d and %d", card, prev, id)
+			}
+			seen[card] = id
+			if owner := bridge.owner(card); owner != p.Wallet.Address {
+				t.Errorf("player %d caches card %s owned by %q", id, card, owner)
+			}
+		}
+	}
+
+	// O estado final continua serializável e restaurável.
+	data, err := s.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	restored, _ := newTestStore(t, 0)
+	if err := restored.Restore(data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(restored.players) != players {
+		t.Errorf("restored %d players, want %d", len(restored.players), players)
+	}
+	bridge.mu.Lock()
+	t.Logf("%d matches resolved, bridge calls: %v", resolved.Load(), bridge.calls)
+	bridge.mu.Unlock()
+}
@@ -0,0 +1,935 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// --- TORNEIOS ---
+//
+// Um jogador cria o torneio com taxa de inscrição e limite de vagas; as
+// taxas vão para a carteira da loja. Quando as vagas acabam (ou o criador
+// inicia antes), a chave é gerada e cada rodada cria partidas normais
+// (PlayCard/ResolveMatch) entre os pareados, avisados em
+// player.<id>.tournament. No fim, a loja paga os prêmios e a classificação
+// é registrada on-chain.
+
+const (
+	formatElimination = "elimination" // Eliminação simples
+	formatSwiss       = "swiss"       // Suíço: todos jogam todas as rodadas
+
+	tournamentOpen      = "open"
+	tournamentRunning   = "running"
+	tournamentFinishing = "finishing" // Pagando prêmios e registrando on-chain
+	tournamentFinished  = "finished"
+	tournamentCanceled  = "canceled"
+
+	maxTournamentPlayers = 64
+	maxTournamentName    = 40
+
+	// Prazo padrão para os dois jogadores jogarem antes do W.O.
+	defaultTournamentMatchTimeout = 5 * time.Minute
+)
+
+// Divisão do prêmio (em %) entre as primeiras colocações.
+var tournamentPrizeShares = []uint64{60, 30, 10}
+
+// Maior taxa de inscrição aceita: o total arrecadado (até
+// maxTournamentPlayers inscrições) vezes a fatia do prêmio, em %,
+// precisa caber em um uint64.
+const maxEntryFee = math.MaxUint64 / (maxTournamentPlayers * 100)
+
+// Pairing é um confronto de uma rodada; P2 == 0 indica folga (bye).
+// Deadline é o prazo da partida corrente antes do W.O.
+type Pairing struct {
+	P1       int       `json:"p1"`
+	P2       int       `json:"p2,omitempty"`
+	Game     string    `json:"game,omitempty"`
+	Winner   int       `json:"winner,omitempty"`
+	Deadline time.Time `json:"deadline,omitzero"`
+}
+
+type TournamentRound struct {
+	Number   int       `json:"number"`
+	Pairings []Pairing `json:"pairings"`
+}
+
+// Standing é a colocação final de um jogador, com o prêmio pago.
+type Standing struct {
+	Place    int    `json:"place"`
+	PlayerID int    `json:"player_id"`
+	Wins     int    `json:"wins"`
+	Prize    uint64 `json:"prize,omitempty"`
+	Digest   string `json:"digest,omitempty"`
+	Err      string `json:"err,omitempty"`
+}
+
+type Tournament struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Format      string            `json:"format"`
+	Creator     int               `json:"creator"`
+	EntryFee    uint64            `json:"entry_fee"`
+	MaxPlayers  int               `json:"max_players"`
+	Status      string            `json:"status"`
+	Players     []int             `json:"players"` // Inscritos, na ordem de seed
+	Fees        map[int]string    `json:"fees"`    // Digest da inscrição paga
+	Joining     int               `json:"-"`       // Pagamentos de inscrição em andamento
+	Rounds      []TournamentRound `json:"rounds"`
+	TotalRounds int               `json:"total_rounds,omitempty"` // Rodadas do suíço
+	Wins        map[int]int       `json:"wins"`
+	Eliminated  map[int]int       `json:"eliminated"` // Rodada em que o jogador caiu
+	Standings   []Standing        `json:"standings,omitempty"`
+	Digest      string            `json:"digest,omitempty"` // Registro on-chain da classificação
+	ObjectID    string            `json:"object_id,omitempty"`
+	Err         string            `json:"err,omitempty"`
+	Created     time.Time         `json:"created"`
+}
+
+// clone copia o torneio para uso fora da Store.
+func (t *Tournament) clone() Tournament {
+	c := *t
+	c.Players = append([]int(nil), t.Players...)
+	c.Fees = make(map[int]string, len(t.Fees))
+	for k, v := range t.Fees {
+		c.Fees[k] = v
+	}
+	c.Wins = make(map[int]int, len(t.Wins))
+	for k, v := range t.Wins {
+		c.Wins[k] = v
+	}
+	c.Eliminated = make(map[int]int, len(t.Eliminated))
+	for k, v := range t.Eliminated {
+		c.Eliminated[k] = v
+	}
+	c.Rounds = make([]TournamentRound, len(t.Rounds))
+	for i, r := range t.Rounds {
+		c.Rounds[i] = TournamentRound{Number: r.Number, Pairings: append([]Pairing(nil), r.Pairings...)}
+	}
+	c.Standings = append([]Standing(nil), t.Standings...)
+	return c
+}
+
+func (t *Tournament) joined(id int) bool {
+	for _, p := range t.Players {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) removePlayer(id int) {
+	players := t.Players[:0]
+	for _, p := range t.Players {
+		if p != id {
+			players = append(players, p)
+		}
+	}
+	t.Players = players
+}
+
+// Aviso a ser publicado em player.<id>.tournament depois de liberar o lock.
+type tournamentNotice struct {
+	to      int
+	payload map[string]any
+}
+
+func (s *Store) sendTournamentNotices(notices []tournamentNotice) {
+	for _, n := range notices {
+		s.notify(n.to, "tournament", n.payload)
+	}
+}
+
+// canEnterLocked confere se o jogador pode se inscrever em torneios.
+// Exige s.mu travado.
+func (s *Store) canEnterLocked(id int) (Player, error) {
+	player, exists := s.players[id]
+	if !exists {
+		return Player{}, fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		return Player{}, ErrBanned
+	}
+	if _, err := s.activeDeckLocked(id); err != nil {
+		return Player{}, err
+	}
+	return player, nil
+}
+
+// Cobra a taxa de inscrição, paga à carteira da loja.
+func (s *Store) payEntryFee(ctx context.Context, player Player, fee uint64) (string, error) {
+	if fee == 0 {
+		return "", nil
+	}
+	wallet, err := spendable(player)
+	if err != nil {
+		return "", err
+	}
+	digest, err := s.bridge.Transaction(ctx, wallet, Wallet{Address: ServerWalletAddress}, fee)
+	if err != nil {
+		return "", fmt.Errorf("falha no pagamento da inscrição: %w", err)
+	}
+	return digest, nil
+}
+
+// CreateTournament abre um torneio já com o criador inscrito: a taxa dele
+// é cobrada antes de o torneio ficar visível.
+func (s *Store) CreateTournament(ctx context.Context, creator int, name, format string, fee uint64, maxPlayers int) (Tournament, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTournamentName {
+		return Tournament{}, fmt.Errorf("nome do torneio deve ter de 1 a %d caracteres", maxTournamentName)
+	}
+	if format == "" {
+		format = formatElimination
+	}
+	if format != formatElimination && format != formatSwiss {
+		return Tournament{}, fmt.Errorf("formato desconhecido: %s (use elimination ou swiss)", format)
+	}
+	if maxPlayers < 2 || maxPlayers > maxTournamentPlayers {
+		return Tournament{}, fmt.Errorf("o torneio deve ter de 2 a %d vagas", maxTournamentPlayers)
+	}
+	if fee > maxEntryFee {
+		return Tournament{}, fmt.Errorf("taxa de inscrição acima do limite de %d IOTA", uint64(maxEntryFee))
+	}
+
+	done, err := s.beginWork(fmt.Sprintf("createTournament player=%d", creator))
+	if err != nil {
+		return Tournament{}, err
+	}
+	defer done()
+
+	s.mu.Lock()
+	player, err := s.canEnterLocked(creator)
+	s.mu.Unlock()
+	if err != nil {
+		return Tournament{}, err
+	}
+	digest, err := s.payEntryFee(ctx, player, fee)
+	if err != nil {
+		return Tournament{}, err
+	}
+
+	t := &Tournament{
+		ID:         uuid.New().String(),
+		Name:       name,
+		Format:     format,
+		Creator:    creator,
+		EntryFee:   fee,
+		MaxPlayers: maxPlayers,
+		Status:     tournamentOpen,
+		Players:    []int{creator},
+		Fees:       map[int]string{creator: digest},
+		Rounds:     []TournamentRound{},
+		Wins:       map[int]int{},
+		Eliminated: map[int]int{},
+		Created:    time.Now(),
+	}
+
+	s.mu.Lock()
+	s.tournaments[t.ID] = t
+	result := t.clone()
+	s.mu.Unlock()
+
+	slog.Info("tournament created", "tournament_id", t.ID, logPlayer, creator, "format", format, "entry_fee", fee, "max_players", maxPlayers, logDigest, digest)
+	return result, nil
+}
+
+// JoinTournament inscreve o jogador: a vaga é reservada, a taxa é paga à
+// loja e, se as vagas acabaram, o torneio começa.
+func (s *Store) JoinTournament(ctx context.Context, id int, tid string) error {
+	done, err := s.beginWork(fmt.Sprintf("joinTournament player=%d tournament=%s", id, tid))
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	s.mu.Lock()
+	t, ok := s.tournaments[tid]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("torneio não encontrado")
+	}
+	switch {
+	case t.Status != tournamentOpen:
+		err = fmt.Errorf("inscrições encerradas")
+	case t.joined(id):
+		err = fmt.Errorf("você já está inscrito")
+	case len(t.Players) >= t.MaxPlayers:
+		err = fmt.Errorf("torneio lotado")
+	}
+	player, enterErr := s.canEnterLocked(id)
+	if err == nil {
+		err = enterErr
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	// A vaga fica reservada enquanto a taxa é paga.
+	t.Players = append(t.Players, id)
+	t.Joining++
+	fee := t.EntryFee
+	s.mu.Unlock()
+
+	digest, err := s.payEntryFee(ctx, player, fee)
+
+	s.mu.Lock()
+	t.Joining--
+	if err != nil {
+		t.removePlayer(id)
+		s.mu.Unlock()
+		return err
+	}
+	t.Fees[id] = digest
+	var notices []tournamentNotice
+	if len(t.Players) == t.MaxPlayers && t.Joining == 0 {
+		notices = s.startTournamentLocked(t)
+	}
+	s.mu.Unlock()
+
+	slog.Info("tournament joined", "tournament_id", tid, logPlayer, id, logDigest, digest)
+	s.sendTournamentNotices(notices)
+	return nil
+}
+
+// StartTournament permite ao criador iniciar antes de lotar (mínimo 2).
+func (s *Store) StartTournament(id int, tid string) error {
+	s.mu.Lock()
+	t, ok := s.tournaments[tid]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("torneio não encontrado")
+	}
+	var err error
+	switch {
+	case t.Creator != id:
+		err = fmt.Errorf("apenas o criador pode iniciar o torneio")
+	case t.Status != tournamentOpen:
+		err = fmt.Errorf("o torneio já começou")
+	case t.Joining > 0:
+		err = fmt.Errorf("há inscrições sendo pagas, tente novamente em instantes")
+	case len(t.Players) < 2:
+		err = fmt.Errorf("são necessários ao menos 2 inscritos")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	notices := s.startTournamentLocked(t)
+	s.mu.Unlock()
+
+	s.sendTournamentNotices(notices)
+	return nil
+}
+
+// startTournamentLocked sorteia os seeds e cria a primeira rodada.
+// Exige s.mu travado.
+func (s *Store) startTournamentLocked(t *Tournament) []tournamentNotice {
+	rand.Shuffle(len(t.Players), func(i, j int) { t.Players[i], t.Players[j] = t.Players[j], t.Players[i] })
+	t.Status = tournamentRunning
+	if t.Format == formatSwiss {
+		// Rodadas suficientes para apontar um único invicto.
+		t.TotalRounds = max(1, bits.Len(uint(len(t.Players)-1)))
+	}
+
+	slog.Info("tournament started", "tournament_id", t.ID, "players", len(t.Players), "format", t.Format)
+	return s.nextRoundLocked(t)
+}
+
+// nextRoundLocked gera os confrontos da próxima rodada e cria as
+// partidas. Folgas contam como vitória. Exige s.mu travado.
+func (s *Store) nextRoundLocked(t *Tournament) []tournamentNotice {
+	var pairs [][2]int
+	if t.Format == formatSwiss {
+		pairs = t.swissPairs()
+	} else {
+		pairs = t.eliminationPairs()
+	}
+
+	round := TournamentRound{Number: len(t.Rounds) + 1}
+	var notices []tournamentNotice
+	for _, pair := range pairs {
+		p := Pairing{P1: pair[0], P2: pair[1]}
+		if p.P2 == 0 {
+			p.Winner = p.P1
+			t.Wins[p.P1]++
+			notices = append(notices, tournamentNotice{p.P1, map[string]any{"event": "bye", "tournament": t.ID, "round": round.Number}})
+		} else {
+			notices = append(notices, s.scheduleTournamentMatchLocked(t, round.Number, &p)...)
+		}
+		round.Pairings = append(round.Pairings, p)
+	}
+	t.Rounds = append(t.Rounds, round)
+
+	slog.Info("tournament round started", "tournament_id", t.ID, "round", round.Number, "pairings", len(round.Pairings))
+	return notices
+}
+
+// scheduleTournamentMatchLocked cria a partida do confronto e arma o
+// prazo para W.O. Exige s.mu travado.
+func (s *Store) scheduleTournamentMatchLocked(t *Tournament, round int, p *Pairing) []tournamentNotice {
+	match := s.newMatchLocked(p.P1, p.P2)
+	match.Tournament = t.ID
+	s.matchHistory[match.SelfId] = match
+	p.Game = match.SelfId
+
+	p.Deadline = time.Now().Add(s.tournamentMatchTimeout())
+	s.armWalkoverLocked(t.ID, p)
+
+	game := match.SelfId
+	return []tournamentNotice{
+		{p.P1, map[string]any{"event": "match", "tournament": t.ID, "round": round, "game": game, "opponent": p.P2}},
+		{p.P2, map[string]any{"event": "match", "tournament": t.ID, "round": round, "game": game, "opponent": p.P1}},
+	}
+}
+
+func (s *Store) tournamentMatchTimeout() time.Duration {
+	if s.TournamentMatchTimeout <= 0 {
+		return defaultTournamentMatchTimeout
+	}
+	return s.TournamentMatchTimeout
+}
+
+// armWalkoverLocked arma o timer de W.O. do confronto até o prazo
+// gravado nele (prazo vencido dispara em seguida). Fora da liderança não
+// arma nada: o prazo fica no estado replicado para o próximo líder.
+// Exige s.mu travado.
+func (s *Store) armWalkoverLocked(tid string, p *Pairing) {
+	if !s.leading || p.Game == "" {
+		return
+	}
+	if old, ok := s.walkovers[p.Game]; ok {
+		old.Stop()
+	}
+	game := p.Game
+	s.walkovers[game] = time.AfterFunc(time.Until(p.Deadline), func() { s.tournamentWalkover(tid, game) })
+}
+
+// setLeading liga ou desliga os prazos de W.O. deste nó. Ao assumir a
+// liderança (inclusive depois de restaurar o estado de outro líder), os
+// confrontos pendentes dos torneios em andamento são rearmados; ao
+// perder, os timers são descartados.
+func (s *Store) setLeading(leading bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leading = leading
+	for game, timer := range s.walkovers {
+		timer.Stop()
+		delete(s.walkovers, game)
+	}
+	if !leading {
+		return
+	}
+	armed := 0
+	for _, t := range s.tournaments {
+		if t.Status != tournamentRunning || len(t.Rounds) == 0 {
+			continue
+		}
+		round := &t.Rounds[len(t.Rounds)-1]
+		for i := range round.Pairings {
+			p := &round.Pairings[i]
+			if p.Game == "" || p.Winner != 0 {
+				continue
+			}
+			if p.Deadline.IsZero() {
+				// Estado gravado antes dos prazos existirem.
+				p.Deadline = time.Now().Add(s.tournamentMatchTimeout())
+			}
+			s.armWalkoverLocked(t.ID, p)
+			armed++
+		}
+	}
+	if armed > 0 {
+		slog.Info("tournament walkovers rearmed", "matches", armed)
+	}
+}
+
+// Jogadores que já folgaram em alguma rodada.
+func (t *Tournament) byes() map[int]bool {
+	byes := map[int]bool{}
+	for _, r := range t.Rounds {
+		for _, p := range r.Pairings {
+			if p.P2 == 0 {
+				byes[p.P1] = true
+			}
+		}
+	}
+	return byes
+}
+
+// Com número ímpar de jogadores, retira da lista quem folga: o último
+// que ainda não folgou (ou o último, se todos já folgaram).
+func (t *Tournament) takeBye(players []int) (rest []int, bye int) {
+	byes := t.byes()
+	i := len(players) - 1
+	for j := len(players) - 1; j >= 0; j-- {
+		if !byes[players[j]] {
+			i = j
+			break
+		}
+	}
+	return append(players[:i:i], players[i+1:]...), players[i]
+}
+
+// Eliminação simples: vivos pareados em ordem de seed; com número ímpar,
+// um deles folga.
+func (t *Tournament) eliminationPairs() [][2]int {
+	var alive []int
+	for _, p := range t.Players {
+		if _, out := t.Eliminated[p]; !out {
+			alive = append(alive, p)
+		}
+	}
+	var pairs [][2]int
+	if len(alive)%2 == 1 {
+		var bye int
+		alive, bye = t.takeBye(alive)
+		pairs = append(pairs, [2]int{bye, 0})
+	}
+	for i := 0; i+1 < len(alive); i += 2 {
+		pairs = append(pairs, [2]int{alive[i], alive[i+1]})
+	}
+	return pairs
+}
+
+// Suíço: jogadores ordenados por vitórias; cada um enfrenta o próximo da
+// lista contra quem ainda não jogou. Com número ímpar, folga o pior
+// colocado que ainda não folgou.
+func (t *Tournament) swissPairs() [][2]int {
+	ranked := t.ranking()
+	played := map[[2]int]bool{}
+	for _, r := range t.Rounds {
+		for _, p := range r.Pairings {
+			if p.P2 != 0 {
+				played[[2]int{p.P1, p.P2}] = true
+				played[[2]int{p.P2, p.P1}] = true
+			}
+		}
+	}
+
+	var pairs [][2]int
+	if len(ranked)%2 == 1 {
+		var bye int
+		ranked, bye = t.takeBye(ranked)
+		pairs = append(pairs, [2]int{bye, 0})
+	}
+
+	paired := make([]bool, len(ranked))
+	for i := range ranked {
+		if paired[i] {
+			continue
+		}
+		opponent := -1
+		for j := i + 1; j < len(ranked); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponent < 0 {
+				opponent = j // Repete confronto só se não houver alternativa
+			}
+			if !played[[2]int{ranked[i], ranked[j]}] {
+				opponent = j
+				break
+			}
+		}
+		paired[i], paired[opponent] = true, true
+		pairs = append(pairs, [2]int{ranked[i], ranked[opponent]})
+	}
+	return pairs
+}
+
+// ranking ordena os inscritos pela classificação atual. Eliminação: quem
+// caiu depois fica na frente; suíço: vitórias e, no empate, a soma das
+// vitórias dos adversários (Buchholz). O seed desempata o resto.
+func (t *Tournament) ranking() []int {
+	seed := map[int]int{}
+	for i, p := range t.Players {
+		seed[p] = i
+	}
+	buchholz := map[int]int{}
+	for _, r := range t.Rounds {
+		for _, p := range r.Pairings {
+			if p.P2 != 0 {
+				buchholz[p.P1] += t.Wins[p.P2]
+				buchholz[p.P2] += t.Wins[p.P1]
+			}
+		}
+	}
+	// Na eliminação, quem não caiu fica à frente de todos.
+	survived := func(p int) int {
+		if r, out := t.Eliminated[p]; out {
+			return r
+		}
+		return len(t.Rounds) + 1
+	}
+
+	ranked := append([]int(nil), t.Players...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if t.Format == formatElimination && survived(a) != survived(b) {
+			return survived(a) > survived(b)
+		}
+		if t.Wins[a] != t.Wins[b] {
+			return t.Wins[a] > t.Wins[b]
+		}
+		if t.Format == formatSwiss && buchholz[a] != buchholz[b] {
+			return buchholz[a] > buchholz[b]
+		}
+		return seed[a] < seed[b]
+	})
+	return ranked
+}
+
+// currentPairing localiza o confronto da rodada corrente pela partida.
+func (t *Tournament) currentPairing(game string) *Pairing {
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	round := &t.Rounds[len(t.Rounds)-1]
+	for i := range round.Pairings {
+		if round.Pairings[i].Game == game {
+			return &round.Pairings[i]
+		}
+	}
+	return nil
+}
+
+// tournamentResult registra o vencedor de uma partida do torneio e, se a
+// rodada acabou, gera a próxima ou encerra o torneio.
+func (s *Store) tournamentResult(ctx context.Context, tid, game string, winner int) {
+	s.mu.Lock()
+	t, ok := s.tournaments[tid]
+	if !ok || t.Status != tournamentRunning {
+		s.mu.Unlock()
+		return
+	}
+	p := t.currentPairing(game)
+	if p == nil || p.Winner != 0 {
+		s.mu.Unlock()
+		return
+	}
+	p.Winner = winner
+	t.Wins[winner]++
+	loser := p.P1
+	if loser == winner {
+		loser = p.P2
+	}
+	round := len(t.Rounds)
+	if t.Format == formatElimination {
+		t.Eliminated[loser] = round
+	}
+
+	slog.Info("tournament match decided", "tournament_id", tid, logGame, game, "winner_id", winner, "loser_id", loser, "round", round)
+
+	var notices []tournamentNotice
+	finished := false
+	for _, q := range t.Rounds[round-1].Pairings {
+		if q.Winner == 0 {
+			s.mu.Unlock()
+			return // Rodada ainda em andamento
+		}
+	}
+	if t.Format == formatSwiss {
+		finished = round >= t.TotalRounds
+	} else {
+		finished = len(t.Players)-len(t.Eliminated) <= 1
+	}
+	if finished {
+		t.Status = tournamentFinishing
+		t.Standings = t.standings()
+	} else {
+		notices = s.nextRoundLocked(t)
+	}
+	s.mu.Unlock()
+
+	s.sendTournamentNotices(notices)
+	if finished {
+		// Os pagamentos seguem mesmo que a requisição original termine.
+		go s.finishTournament(context.WithoutCancel(ctx), tid)
+	}
+}
+
+// standings monta a classificação final e divide o prêmio: o total das
+// inscrições vai para as primeiras colocações segundo
+// tournamentPrizeShares (sempre ao menos um jogador fica sem prêmio).
+func (t *Tournament) standings() []Standing {
+	ranked := t.ranking()
+	places := min(len(tournamentPrizeShares), len(ranked)-1)
+	places = max(places, 1)
+
+	var total uint64
+	for _, share := range tournamentPrizeShares[:places] {
+		total += share
+	}
+	pool := t.EntryFee * uint64(len(t.Fees))
+
+	out := make([]Standing, len(ranked))
+	var paid uint64
+	for i, id := range ranked {
+		out[i] = Standing{Place: i + 1, PlayerID: id, Wins: t.Wins[id]}
+		if i < places {
+			out[i].Prize = pool * tournamentPrizeShares[i] / total
+			paid += out[i].Prize
+		}
+	}
+	out[0].Prize += pool - paid // Sobra da divisão inteira fica com o campeão
+	return out
+}
+
+// finishTournament paga os prêmios pela carteira da loja e registra a
+// classificação on-chain. Falhas ficam anotadas para reconciliação.
+func (s *Store) finishTournament(ctx context.Context, tid string) {
+	defer s.trackWork("finishTournament tournament=" + tid)()
+
+	s.mu.Lock()
+	t := s.tournaments[tid].clone()
+	addresses := make([]string, len(t.Standings))
+	for i, st := range t.Standings {
+		addresses[i] = s.players[st.PlayerID].Wallet.Address
+	}
+	s.mu.Unlock()
+
+	prizes := make([]uint64, len(t.Standings))
+	for i := range t.Standings {
+		st := &t.Standings[i]
+		prizes[i] = st.Prize
+		if st.Prize == 0 {
+			continue
+		}
+		digest, err := s.bridge.Payout(ctx, addresses[i], st.Prize)
+		if err != nil {
+			slog.Error("tournament payout failed", "tournament_id", tid, logPlayer, st.PlayerID, "amount", st.Prize, "err", err)
+			st.Err = err.Error()
+			continue
+		}
+		slog.Info("tournament prize paid", "tournament_id", tid, logPlayer, st.PlayerID, "amount", st.Prize, logDigest, digest)
+		st.Digest = digest
+	}
+
+	digest, objectID, err := s.bridge.LogTournament(ctx, tid, addresses, prizes)
+	if err != nil {
+		slog.Error("tournament log failed", "tournament_id", tid, "err", err)
+	} else {
+		slog.Info("tournament logged on-chain", "tournament_id", tid, logObject, objectID, logDigest, digest)
+	}
+
+	s.mu.Lock()
+	live := s.tournaments[tid]
+	live.Standings = t.Standings
+	live.Digest, live.ObjectID = digest, objectID
+	if err != nil {
+		live.Err = err.Error()
+	}
+	live.Status = tournamentFinished
+	result := live.clone()
+	s.mu.Unlock()
+
+	for _, id := range result.Players {
+		s.notify(id, "tournament", map[string]any{"event": "finished", "tournament": tid, "standings": result.Standings, "object_id": objectID})
+	}
+}
+
+// tournamentRematch repete um confronto que terminou empatado.
+func (s *Store) tournamentRematch(tid, game string) {
+	s.mu.Lock()
+	t, ok := s.tournaments[tid]
+	if !ok || t.Status != tournamentRunning {
+		s.mu.Unlock()
+		return
+	}
+	p := t.currentPairing(game)
+	if p == nil || p.Winner != 0 {
+		s.mu.Unlock()
+		return
+	}
+	notices := s.scheduleTournamentMatchLocked(t, len(t.Rounds), p)
+	s.mu.Unlock()
+
+	slog.Info("tournament rematch", "tournament_id", tid, "previous_game", game, logGame, p.Game)
+	for i := range notices {
+		notices[i].payload["event"] = "rematch"
+	}
+	s.sendTournamentNotices(notices)
+}
+
+// tournamentWalkover encerra por W.O. uma partida que passou do prazo:
+// vence quem jogou; se ninguém jogou, avança o melhor seed (P1).
+func (s *Store) tournamentWalkover(tid, game string) {
+	s.mu.Lock()
+	if !s.leading {
+		// Timer que disparou depois da troca de liderança.
+		s.mu.Unlock()
+		return
+	}
+	delete(s.walkovers, game)
+	match, exists := s.matchHistory[game]
+	if !exists || (match.Card1 != 0 && match.Card2 != 0) {
+		s.mu.Unlock()
+		return
+	}
+	t, ok := s.tournaments[tid]
+	if !ok || t.Status != tournamentRunning {
+		s.mu.Unlock()
+		return
+	}
+	if p := t.currentPairing(game); p == nil || p.Winner != 0 {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.matchHistory, game)
+	s.mu.Unlock()
+
+	winner := match.P1
+	if match.Card1 == 0 && match.Card2 != 0 {
+		winner = match.P2
+	}
+	slog.Info("tournament walkover", "tournament_id", tid, logGame, game, "winner_id", winner)
+
+	// Quem estava aguardando a partida recebe o encerramento.
+	for _, id := range []int{match.P1, match.P2} {
+		resp := map[string]any{"client_id": id, "game": game, "err": "partida encerrada por W.O."}
+		data, _ := json.Marshal(resp)
+		s.pub.Publish("game.server", data)
+		s.notify(id, "tournament", map[string]any{"event": "walkover", "tournament": tid, "game": game, "winner": winner})
+	}
+	s.tournamentResult(context.Background(), tid, game, winner)
+}
+
+// CancelTournament (administrador) encerra o torneio, descarta as
+// partidas pendentes e devolve as inscrições pela carteira da loja.
+func (s *Store) CancelTournament(ctx context.Context, tid string) (Tournament, error) {
+	done := s.trackWork("cancelTournament tournament=" + tid)
+	defer done()
+
+	s.mu.Lock()
+	t, ok := s.tournaments[tid]
+	if !ok {
+		s.mu.Unlock()
+		return Tournament{}, fmt.Errorf("torneio não encontrado")
+	}
+	if t.Status != tournamentOpen && t.Status != tournamentRunning {
+		s.mu.Unlock()
+		return Tournament{}, fmt.Errorf("torneio já encerrado (%s)", t.Status)
+	}
+	if t.Joining > 0 {
+		s.mu.Unlock()
+		return Tournament{}, fmt.Errorf("há inscrições sendo pagas, tente novamente em instantes")
+	}
+	t.Status = tournamentCanceled
+	if n := len(t.Rounds); n > 0 {
+		for _, p := range t.Rounds[n-1].Pairings {
+			if p.Winner == 0 {
+				delete(s.matchHistory, p.Game)
+			}
+		}
+	}
+	refunds := map[int]string{}
+	for id := range t.Fees {
+		refunds[id] = s.players[id].Wallet.Address
+	}
+	s.mu.Unlock()
+
+	slog.Info("tournament canceled", "tournament_id", tid, "refunds", len(refunds))
+
+	var failed []string
+	for id, addr := range refunds {
+		if t.EntryFee > 0 {
+			if _, err := s.bridge.Payout(ctx, addr, t.EntryFee); err != nil {
+				slog.Error("tournament refund failed", "tournament_id", tid, logPlayer, id, "err", err)
+				failed = append(failed, fmt.Sprintf("jogador %d: %v", id, err))
+				continue
+			}
+		}
+		s.notify(id, "tournament", map[string]any{"event": "canceled", "tournament": tid, "refund": t.EntryFee})
+	}
+
+	s.mu.Lock()
+	if len(failed) > 0 {
+		t.Err = "reembolsos pendentes: " + strings.Join(failed, "; ")
+	}
+	result := t.clone()
+	s.mu.Unlock()
+	return result, nil
+}
+
+// Tournament retorna uma cópia do torneio.
+func (s *Store) Tournament(tid string) (Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tid]
+	if !ok {
+		return Tournament{}, fmt.Errorf("torneio não encontrado")
+	}
+	return t.clone(), nil
+}
+
+// Tournaments lista os torneios, dos mais recentes para os mais antigos.
+func (s *Store) Tournaments() []Tournament {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		out = append(out, t.clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out
+}
+
+// --- TÓPICOS topic.tournament.* ---
+
+type tournamentRequest struct {
+	ClientID   int    `json:"client_id"`
+	Tournament string `json:"tournament"`
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	EntryFee   uint64 `json:"entry_fee"`
+	MaxPlayers int    `json:"max_players"`
+}
+
+type tournamentCommand func(ctx context.Context, s *Store, req tournamentRequest) (any, error)
+
+// Comandos disponíveis, pelo sufixo do tópico (topic.tournament.<comando>).
+var tournamentCommands = map[string]tournamentCommand{
+	"create": func(ctx context.Context, s *Store, req tournamentRequest) (any, error) {
+		return s.CreateTournament(ctx, req.ClientID, req.Name, req.Format, req.EntryFee, req.MaxPlayers)
+	},
+	"join": func(ctx context.Context, s *Store, req tournamentRequest) (any, error) {
+		if err := s.JoinTournament(ctx, req.ClientID, req.Tournament); err != nil {
+			return nil, err
+		}
+		return s.Tournament(req.Tournament)
+	},
+	"start": func(ctx context.Context, s *Store, req tournamentRequest) (any, error) {
+		if err := s.StartTournament(req.ClientID, req.Tournament); err != nil {
+			return nil, err
+		}
+		return s.Tournament(req.Tournament)
+	},
+	"get": func(ctx context.Context, s *Store, req tournamentRequest) (any, error) {
+		return s.Tournament(req.Tournament)
+	},
+	"list": func(ctx context.Context, s *Store, req tournamentRequest) (any, error) {
+		return s.Tournaments(), nil
+	},
+}
+
+// ClientTournaments atende topic.tournament.<comando> (create, join,
+// start, get, list). A resposta é {"result": ...} ou {"err": ...}.
+func ClientTournaments(nc *nats.Conn, s *Store) (*nats.Subscription, error) {
+	return serveCommands(nc, s, "topic.tournament.", tournamentCommands, nil)
+}
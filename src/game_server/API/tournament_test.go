@@ -0,0 +1,247 @@
+package API
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// swissTournament monta um suíço com os jogadores 1..n inscritos (nessa
+// ordem de seed) e a taxa paga por todos.
+func swissTournament(n int, fee uint64, wins map[int]int) *Tournament {
+	t := &Tournament{
+		Format:     formatSwiss,
+		EntryFee:   fee,
+		Fees:       map[int]string{},
+		Wins:       wins,
+		Eliminated: map[int]int{},
+	}
+	for id := 1; id <= n; id++ {
+		t.Players = append(t.Players, id)
+		t.Fees[id] = "digest"
+	}
+	return t
+}
+
+func TestTournamentStandingsPrizeSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		players int
+		fee     uint64
+		wins    map[int]int
+		order   []int
+		prizes  []uint64
+	}{
+		{"four players", 4, 100, map[int]int{2: 3, 4: 2, 3: 1}, []int{2, 4, 3, 1}, []uint64{240, 120, 40, 0}},
+		// Com três inscritos só os dois primeiros premiam (60/30 do total).
+		{"three players", 3, 100, map[int]int{3: 2, 1: 1}, []int{3, 1, 2}, []uint64{200, 100, 0}},
+		{"two players", 2, 100, map[int]int{2: 1}, []int{2, 1}, []uint64{200, 0}},
+		// A sobra da divisão inteira fica com o campeão.
+		{"rounding", 4, 1, map[int]int{1: 1}, []int{1, 2, 3, 4}, []uint64{3, 1, 0, 0}},
+		{"free", 4, 0, map[int]int{}, []int{1, 2, 3, 4}, []uint64{0, 0, 0, 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			standings := swissTournament(tc.players, tc.fee, tc.wins).standings()
+			var order []int
+			var prizes []uint64
+			for i, st := range standings {
+				if st.Place != i+1 {
+					t.Errorf("standing %d has place %d", i, st.Place)
+				}
+				order = append(order, st.PlayerID)
+				prizes = append(prizes, st.Prize)
+			}
+			if !reflect.DeepEqual(order, tc.order) {
+				t.Errorf("order = %v, want %v", order, tc.order)
+			}
+			if !reflect.DeepEqual(prizes, tc.prizes) {
+				t.Errorf("prizes = %v, want %v", prizes, tc.prizes)
+			}
+		})
+	}
+}
+
+// Com a maior taxa aceita e o torneio lotado, a divisão do prêmio não
+// transborda: os prêmios somam exatamente o total arrecadado.
+func TestTournamentStandingsMaxEntryFee(t *testing.T) {
+	tour := swissTournament(maxTournamentPlayers, maxEntryFee, map[int]int{})
+	var sum uint64
+	for _, st := range tour.standings() {
+		sum += st.Prize
+	}
+	if pool := uint64(maxEntryFee) * maxTournamentPlayers; sum != pool {
+		t.Errorf("prizes sum to %d, want pool %d", sum, pool)
+	}
+}
+
+func TestCreateTournamentRejectsEntryFeeAboveCap(t *testing.T) {
+	s, _ := newTestStore(t, 0)
+	id, _, err := newTestPlayer(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTournament(context.Background(), id, "cap", formatElimination, maxEntryFee+1, 4); err == nil {
+		t.Error("CreateTournament accepted an entry fee above maxEntryFee")
+	}
+}
+
+func TestEliminationPairsByes(t *testing.T) {
+	tour := &Tournament{Format: formatElimination, Players: []int{1, 2, 3, 4, 5}, Wins: map[int]int{}, Eliminated: map[int]int{}}
+
+	// Número ímpar: folga o último seed.
+	first := tour.eliminationPairs()
+	if want := [][2]int{{5, 0}, {1, 2}, {3, 4}}; !reflect.DeepEqual(first, want) {
+		t.Fatalf("round 1 pairs = %v, want %v", first, want)
+	}
+
+	// Quem já folgou não folga de novo.
+	tour.Rounds = []TournamentRound{{Number: 1, Pairings: []Pairing{
+		{P1: 5, Winner: 5}, {P1: 1, P2: 2, Winner: 1}, {P1: 3, P2: 4, Winner: 3},
+	}}}
+	tour.Eliminated = map[int]int{2: 1, 4: 1}
+	second := tour.eliminationPairs()
+	if want := [][2]int{{3, 0}, {1, 5}}; !reflect.DeepEqual(second, want) {
+		t.Errorf("round 2 pairs = %v, want %v", second, want)
+	}
+}
+
+func TestSwissPairs(t *testing.T) {
+	t.Run("bye", func(t *testing.T) {
+		tour := swissTournament(5, 0, map[int]int{1: 1, 3: 1, 5: 1})
+		tour.Rounds = []TournamentRound{{Number: 1, Pairings: []Pairing{
+			{P1: 5, Winner: 5}, {P1: 1, P2: 2, Winner: 1}, {P1: 3, P2: 4, Winner: 3},
+		}}}
+		// 5 já folgou: a folga vai para o pior colocado que ainda não
+		// folgou (4), e os demais são pareados pela classificação.
+		got := tour.swissPairs()
+		if want := [][2]int{{4, 0}, {1, 3}, {5, 2}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("pairs = %v, want %v", got, want)
+		}
+	})
+	t.Run("no rematch", func(t *testing.T) {
+		tour := swissTournament(4, 0, map[int]int{1: 2, 2: 1, 3: 1})
+		tour.Rounds = []TournamentRound{
+			{Number: 1, Pairings: []Pairing{{P1: 1, P2: 2, Winner: 1}, {P1: 3, P2: 4, Winner: 3}}},
+			{Number: 2, Pairings: []Pairing{{P1: 1, P2: 3, Winner: 1}, {P1: 2, P2: 4, Winner: 2}}},
+		}
+		// 1 já enfrentou 2 e 3: joga com 4, e 2 com 3.
+		got := tour.swissPairs()
+		if want := [][2]int{{1, 4}, {2, 3}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("pairs = %v, want %v", got, want)
+		}
+	})
+}
+
+// waitTournament espera o torneio chegar ao status informado.
+func waitTournament(t *testing.T, s *Store, tid, status string) Tournament {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tour, err := s.Tournament(tid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tour.Status == status {
+			return tour
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tournament status %q, want %q", tour.Status, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Só um dos jogadores joga antes do prazo: o timer de W.O. dá a vitória
+// a ele, e o torneio de dois jogadores termina com o prêmio pago.
+func TestTournamentWalkover(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	s.TournamentMatchTimeout = 100 * time.Millisecond
+	s.setLeading(true)
+	ctx := context.Background()
+
+	a, _, err := newTestPlayer(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := newTestPlayer(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tour, err := s.CreateTournament(ctx, a, "walkover", formatElimination, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.JoinTournament(ctx, b, tour.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tour, _ = s.Tournament(tour.ID)
+	pairing := tour.Rounds[0].Pairings[0]
+	power, err := deckPower(s, pairing.P2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sem a jogada do adversário, PlayCard só registra a carta.
+	if _, _, _, _, _, err := s.PlayCard(ctx, pairing.Game, pairing.P2, power); err == nil {
+		t.Fatal("PlayCard resolved a match with a single card")
+	}
+
+	tour = waitTournament(t, s, tour.ID, tournamentFinished)
+	if got := tour.Rounds[0].Pairings[0].Winner; got != pairing.P2 {
+		t.Errorf("walkover winner = %d, want %d (the player who played)", got, pairing.P2)
+	}
+	if got := tour.Standings[0].PlayerID; got != pairing.P2 {
+		t.Errorf("champion = %d, want %d", got, pairing.P2)
+	}
+	s.mu.Lock()
+	_, pending := s.matchHistory[pairing.Game]
+	s.mu.Unlock()
+	if pending {
+		t.Error("walkover left the match in matchHistory")
+	}
+	winner, _ := s.getPlayer(pairing.P2)
+	if got := bridge.paid(winner.Wallet.Address); got != 20 {
+		t.Errorf("champion paid %d, want 20", got)
+	}
+}
+
+// Cancelar devolve a inscrição de cada jogador pela carteira da loja.
+func TestCancelTournamentRefundsEntryFees(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	ctx := context.Background()
+
+	var ids []int
+	for range 2 {
+		id, _, err := newTestPlayer(s, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	tour, err := s.CreateTournament(ctx, ids[0], "cancel", formatSwiss, 50, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.JoinTournament(ctx, ids[1], tour.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	canceled, err := s.CancelTournament(ctx, tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.Status != tournamentCanceled || canceled.Err != "" {
+		t.Errorf("canceled tournament: status %q, err %q", canceled.Status, canceled.Err)
+	}
+	for _, id := range ids {
+		p, _ := s.getPlayer(id)
+		if got := bridge.paid(p.Wallet.Address); got != 50 {
+			t.Errorf("player %d refunded %d, want 50", id, got)
+		}
+	}
+	if _, err := s.CancelTournament(ctx, tour.ID); err == nil {
+		t.Error("second CancelTournament succeeded, want error (no double refund)")
+	}
+}
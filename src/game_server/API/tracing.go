@@ -0,0 +1,104 @@
+package API
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// --- RASTREAMENTO (OPENTELEMETRY) ---
+
+// O contexto de trace viaja nos headers NATS (traceparent/tracestate):
+// cliente → handler do tópico → chamadas internalServer.* ao worker.
+var tracer = otel.Tracer("server/API")
+
+// SetupTracing instala o propagador W3C e, quando exporter é "stdout",
+// um exportador local que escreve os spans finalizados em w (JSON).
+// Com qualquer outro valor os spans não são exportados, mas o contexto
+// continua sendo propagado. Retorna a função que descarrega os spans.
+func SetupTracing(exporter, service string, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if exporter != "stdout" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// headerCarrier adapta nats.Header ao formato usado pelos propagadores.
+// Acessa o mapa diretamente para não alterar a caixa das chaves.
+type headerCarrier nats.Header
+
+func (c headerCarrier) Get(key string) string {
+	if v := c[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = []string{value}
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Extrai o contexto de trace enviado pelo cliente nos headers da mensagem.
+func extractTrace(m *nats.Msg) context.Context {
+	ctx := context.Background()
+	if m.Header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier(m.Header))
+}
+
+// Injeta o contexto de trace atual nos headers de uma mensagem de saída.
+func injectTrace(ctx context.Context, m *nats.Msg) {
+	if m.Header == nil {
+		m.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(m.Header))
+}
+
+// Abre o span de um handler NATS, ligado ao trace do remetente.
+func startHandlerSpan(m *nats.Msg, subject string) (context.Context, trace.Span) {
+	return tracer.Start(extractTrace(m), subject,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", m.Subject),
+		),
+	)
+}
+
+// Nome do serviço nos spans: o NodeID permite distinguir os nós do cluster.
+func ServiceName(nodeID string) string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return "game-server/" + nodeID
+}
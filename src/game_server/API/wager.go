@@ -0,0 +1,189 @@
+package API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// --- PARTIDAS APOSTADAS ---
+//
+// Na fila de apostas os dois jogadores arriscam o mesmo valor em IOTA.
+// Quando o par é formado, as duas apostas são transferidas para a
+// carteira da loja, que as guarda até o fim da partida. Ao resolver, o
+// vencedor recebe o pote menos a taxa da casa (WagerRake); empate ou
+// cancelamento pelo administrador devolvem a aposta de cada um.
+
+// Maior aposta aceita mesmo sem MaxTransfer: o pote (duas apostas)
+// precisa caber em um uint64.
+const maxWager = math.MaxUint64 / 2
+
+// Jogador aguardando adversário na fila de apostas.
+type wagerEntry struct {
+	PlayerID int    `json:"player_id"`
+	Stake    uint64 `json:"stake"`
+}
+
+// JoinWagerQueue coloca o jogador na fila de apostas com o valor
+// informado. Só jogadores com a mesma aposta são pareados.
+func (s *Store) JoinWagerQueue(id int, stake uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return ErrShuttingDown
+	}
+	if stake == 0 {
+		return fmt.Errorf("a aposta deve ser maior que zero")
+	}
+	if s.MaxTransfer > 0 && stake > s.MaxTransfer {
+		return fmt.Errorf("aposta acima do limite de %d IOTA", s.MaxTransfer)
+	}
+	if stake > maxWager {
+		return fmt.Errorf("aposta acima do limite de %d IOTA", uint64(maxWager))
+	}
+	if err := s.canDuelLocked(id); err != nil {
+		return err
+	}
+	if _, err := spendable(s.players[id]); err != nil {
+		return err
+	}
+
+	s.wagerQueue = append(s.wagerQueue, wagerEntry{PlayerID: id, Stake: stake})
+	slog.Info("wager queued", logPlayer, id, "stake", stake, "queue_len", len(s.wagerQueue))
+	return nil
+}
+
+// takeWagerPairLocked retira da fila os dois primeiros jogadores com a
+// mesma aposta. Exige s.mu travado.
+func (s *Store) takeWagerPairLocked() (wagerEntry, wagerEntry, bool) {
+	for i, a := range s.wagerQueue {
+		for j := i + 1; j < len(s.wagerQueue); j++ {
+			b := s.wagerQueue[j]
+			if b.Stake != a.Stake {
+				continue
+			}
+			queue := append([]wagerEntry{}, s.wagerQueue[:i]...)
+			queue = append(queue, s.wagerQueue[i+1:j]...)
+			s.wagerQueue = append(queue, s.wagerQueue[j+1:]...)
+			return a, b, true
+		}
+	}
+	return wagerEntry{}, wagerEntry{}, false
+}
+
+// ProcessWagerQueue forma os pares da fila de apostas, retém as duas
+// apostas na carteira da loja e só então cria a partida. Os jogadores
+// recebem a partida (ou o erro) em topic.matchmaking, como na fila comum.
+func (s *Store) ProcessWagerQueue(ctx context.Context) {
+	done, err := s.beginWork("wagerQueue escrow")
+	if err != nil {
+		return
+	}
+	defer done()
+
+	for {
+		// A cobrança on-chain acontece com a Store liberada.
+		s.mu.Lock()
+		a, b, ok := s.takeWagerPairLocked()
+		p1, p2 := s.players[a.PlayerID].Wallet, s.players[b.PlayerID].Wallet
+		s.mu.Unlock()
+		if !ok {
+			return
+		}
+
+		slog.Info("wager matched", logPlayerA, a.PlayerID, logPlayerB, b.PlayerID, "stake", a.Stake)
+
+		if err := s.escrowStakes(ctx, a.PlayerID, p1, b.PlayerID, p2, a.Stake); err != nil {
+			slog.Error("wager escrow failed", logPlayerA, a.PlayerID, logPlayerB, b.PlayerID, "err", err)
+			s.announceMatch(map[string]any{"err": err.Error()}, a.PlayerID, b.PlayerID)
+			continue
+		}
+
+		s.mu.Lock()
+		match := s.newMatchLocked(a.PlayerID, b.PlayerID)
+		match.Wager = a.Stake
+		s.matchHistory[match.SelfId] = match
+		s.mu.Unlock()
+
+		s.announceMatch(map[string]any{"match": match.playerView()}, match.P1, match.P2)
+	}
+}
+
+// escrowStakes cobra a aposta dos dois jogadores. Se a segunda cobrança
+// falhar, a primeira é devolvida.
+func (s *Store) escrowStakes(ctx context.Context, id1 int, w1 Wallet, id2 int, w2 Wallet, stake uint64) error {
+	serverWallet := Wallet{Address: ServerWalletAddress}
+
+	if _, err := s.bridge.Transaction(ctx, w1, serverWallet, stake); err != nil {
+		return fmt.Errorf("falha ao reter a aposta do jogador %d: %v", id1, err)
+	}
+	if _, err := s.bridge.Transaction(ctx, w2, serverWallet, stake); err != nil {
+		s.refundStakes(ctx, "", stake, id1)
+		return fmt.Errorf("falha ao reter a aposta do jogador %d: %v", id2, err)
+	}
+	return nil
+}
+
+// announceMatch publica o resultado do pareamento para cada jogador em
+// topic.matchmaking.
+func (s *Store) announceMatch(payload map[string]any, ids ...int) {
+	for _, id := range ids {
+		payload["client_id"] = id
+		data, _ := json.Marshal(payload)
+		s.pub.Publish("topic.matchmaking", data)
+	}
+}
+
+// settleWager paga ao vencedor o pote da partida menos a taxa da casa e
+// avisa os dois jogadores em player.<id>.wager.
+func (s *Store) settleWager(ctx context.Context, game matchStruct, winnerID, loserID int) {
+	// pot*WagerRake/100 sem multiplicar o pote inteiro (evita overflow).
+	pot := 2 * game.Wager
+	rake := pot/100*s.WagerRake + pot%100*s.WagerRake/100
+	if rake > pot {
+		rake = pot
+	}
+	prize := pot - rake
+
+	s.mu.Lock()
+	address := s.players[winnerID].Wallet.Address
+	s.mu.Unlock()
+
+	won := map[string]any{"event": "won", "game": game.SelfId, "amount": prize, "rake": rake}
+	digest, err := s.bridge.Payout(ctx, address, prize)
+	if err != nil {
+		slog.Error("wager payout failed", logGame, game.SelfId, logPlayer, winnerID, "amount", prize, "err", err)
+		won["err"] = err.Error()
+	} else {
+		slog.Info("wager paid", logGame, game.SelfId, logPlayer, winnerID, "amount", prize, "rake", rake, logDigest, digest)
+		won["digest"] = digest
+	}
+
+	s.notify(winnerID, "wager", won)
+	s.notify(loserID, "wager", map[string]any{"event": "lost", "game": game.SelfId, "amount": game.Wager})
+}
+
+// refundStakes devolve a aposta de cada jogador (sem taxa) e os avisa
+// em player.<id>.wager. gameID fica vazio quando a partida nem chegou a
+// ser criada.
+func (s *Store) refundStakes(ctx context.Context, gameID string, stake uint64, ids ...int) {
+	for _, id := range ids {
+		s.mu.Lock()
+		address := s.players[id].Wallet.Address
+		s.mu.Unlock()
+
+		ev := map[string]any{"event": "refunded", "game": gameID, "amount": stake}
+		digest, err := s.bridge.Payout(ctx, address, stake)
+		if err != nil {
+			slog.Error("wager refund failed", logGame, gameID, logPlayer, id, "amount", stake, "err", err)
+			ev["err"] = err.Error()
+		} else {
+			slog.Info("wager refunded", logGame, gameID, logPlayer, id, "amount", stake, logDigest, digest)
+			ev["digest"] = digest
+		}
+		s.notify(id, "wager", ev)
+	}
+}
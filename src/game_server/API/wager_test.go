@@ -0,0 +1,206 @@
+package API
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newDuelists cria dois jogadores com deck ativo e garante que a carta
+// de partida de cada um tenha força diferente (empate não paga nada).
+func newDuelists(t *testing.T, s *Store) (a, b int) {
+	t.Helper()
+	var ids [2]int
+	for i := range ids {
+		id, _, err := newTestPlayer(s, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	pa, err := deckPower(s, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pb, _ := deckPower(s, ids[1]); pb == pa {
+		s.mu.Lock()
+		deck, _ := s.activeDeckLocked(ids[1])
+		s.updateCardsLocked(ids[1], func(cards map[string]int) { cards[deck.Cards[0]] = pa + 1 })
+		s.mu.Unlock()
+	}
+	return ids[0], ids[1]
+}
+
+// playBoth joga a carta de cada jogador na partida e devolve o vencedor.
+func playBoth(t *testing.T, s *Store, game string, a, b int) (winner, loser int) {
+	t.Helper()
+	ctx := context.Background()
+	pa, _ := deckPower(s, a)
+	pb, _ := deckPower(s, b)
+	s.PlayCard(ctx, game, a, pa)
+	if _, _, _, _, _, err := s.PlayCard(ctx, game, b, pb); err != nil {
+		t.Fatalf("PlayCard: %v", err)
+	}
+	if pa > pb {
+		return a, b
+	}
+	return b, a
+}
+
+// findMatch devolve a partida pendente que satisfaz match.
+func findMatch(s *Store, match func(matchStruct) bool) (matchStruct, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, game := range s.matchHistory {
+		if match(game) {
+			return game, true
+		}
+	}
+	return matchStruct{}, false
+}
+
+func wallet(t *testing.T, s *Store, id int) string {
+	t.Helper()
+	p, err := s.getPlayer(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Wallet.Address
+}
+
+// O vencedor recebe o pote menos a taxa da casa, arredondada para baixo
+// (floor(pote*rake/100)), mesmo no maior valor de aposta aceito.
+func TestSettleWagerRake(t *testing.T) {
+	for _, rake := range []uint64{0, 3, 5, 100} {
+		for _, stake := range []uint64{1, 33, 1_000_000_007, maxWager} {
+			s, bridge := newTestStore(t, 0)
+			s.WagerRake = rake
+			a, b := newDuelists(t, s)
+
+			s.settleWager(context.Background(), matchStruct{SelfId: "g", P1: a, P2: b, Wager: stake}, a, b)
+
+			pot := new(big.Int).Mul(big.NewInt(2), new(big.Int).SetUint64(stake))
+			cut := new(big.Int).Div(new(big.Int).Mul(pot, new(big.Int).SetUint64(rake)), big.NewInt(100))
+			want := new(big.Int).Sub(pot, cut).Uint64()
+			if got := bridge.paid(wallet(t, s, a)); got != want {
+				t.Errorf("rake %d%%, stake %d: winner paid %d, want %d", rake, stake, got, want)
+			}
+			if got := bridge.paid(wallet(t, s, b)); got != 0 {
+				t.Errorf("rake %d%%, stake %d: loser paid %d", rake, stake, got)
+			}
+		}
+	}
+}
+
+// Fluxo completo: as duas apostas vão para a loja e o vencedor recebe o
+// pote menos a taxa.
+func TestWagerMatchPaysWinner(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	s.WagerRake = 5
+	a, b := newDuelists(t, s)
+	const stake = 1_000
+	// A compra dos pacotes também sai da carteira: mede só a aposta.
+	before := map[int]uint64{a: bridge.debited(wallet(t, s, a)), b: bridge.debited(wallet(t, s, b))}
+
+	for _, id := range []int{a, b} {
+		if err := s.JoinWagerQueue(id, stake); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.ProcessWagerQueue(context.Background())
+
+	game, ok := findMatch(s, func(m matchStruct) bool { return m.Wager == stake })
+	if !ok {
+		t.Fatal("no wagered match created")
+	}
+	for _, id := range []int{a, b} {
+		if got := bridge.debited(wallet(t, s, id)) - before[id]; got != stake {
+			t.Errorf("player %d escrowed %d, want %d", id, got, stake)
+		}
+	}
+
+	winner, loser := playBoth(t, s, game.SelfId, a, b)
+	if got := bridge.paid(wallet(t, s, winner)); got != 1_900 {
+		t.Errorf("winner paid %d, want 1900 (pot 2000 minus 5%% rake)", got)
+	}
+	if got := bridge.paid(wallet(t, s, loser)); got != 0 {
+		t.Errorf("loser paid %d, want 0", got)
+	}
+}
+
+// Se a segunda cobrança falha, a primeira aposta é devolvida e nenhuma
+// partida é criada.
+func TestWagerEscrowRefundsFirstStake(t *testing.T) {
+	s, bridge := newTestStore(t, 0)
+	a, b := newDuelists(t, s)
+	const stake = 500
+	bridge.failTransactionsFrom(wallet(t, s, b), errors.New("insufficient balance"))
+
+	for _, id := range []int{a, b} {
+		if err := s.JoinWagerQueue(id, stake); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.ProcessWagerQueue(context.Background())
+
+	if _, ok := findMatch(s, func(m matchStruct) bool { return m.Wager > 0 }); ok {
+		t.Error("match created although the second escrow failed")
+	}
+	if got := bridge.paid(wallet(t, s, a)); got != stake {
+		t.Errorf("first player refunded %d, want %d", got, stake)
+	}
+	if got := bridge.paid(wallet(t, s, b)); got != 0 {
+		t.Errorf("second player (never charged) refunded %d, want 0", got)
+	}
+}
+
+// Partida com aposta abortada (cancelada pelo administrador ou
+// empatada): cada jogador recebe a própria aposta de volta, sem taxa.
+func TestWagerRefundOnAbort(t *testing.T) {
+	const stake = 700
+
+	t.Run("cancel", func(t *testing.T) {
+		s, bridge := newTestStore(t, 0)
+		s.WagerRake = 10
+		a, b := newDuelists(t, s)
+		s.mu.Lock()
+		game := s.newMatchLocked(a, b)
+		game.Wager = stake
+		s.matchHistory[game.SelfId] = game
+		s.mu.Unlock()
+
+		if err := s.CancelMatch(game.SelfId); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.WaitIdle(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []int{a, b} {
+			if got := bridge.paid(wallet(t, s, id)); got != stake {
+				t.Errorf("player %d refunded %d, want %d", id, got, stake)
+			}
+		}
+	})
+
+	t.Run("draw", func(t *testing.T) {
+		s, bridge := newTestStore(t, 0)
+		s.WagerRake = 10
+		a, b := newDuelists(t, s)
+		s.mu.Lock()
+		game := s.newMatchLocked(a, b)
+		game.Wager = stake
+		game.Card1, game.Card2 = 4, 4
+		s.mu.Unlock()
+
+		s.ResolveMatch(context.Background(), game)
+		for _, id := range []int{a, b} {
+			if got := bridge.paid(wallet(t, s, id)); got != stake {
+				t.Errorf("player %d refunded %d, want %d", id, got, stake)
+			}
+		}
+	})
+}
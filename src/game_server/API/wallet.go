@@ -0,0 +1,362 @@
+package API
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Preço de um pacote de cartas, pago à carteira da loja.
+const PackPrice = 1000
+
+// --- SALDO E FAUCET ---
+
+// Busca o jogador pelo ID sem segurar o lock durante chamadas on-chain.
+// Retorna uma cópia: o chamador pode lê-la livremente fora do lock.
+func (s *Store) getPlayer(id int) (Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[id]
+	if !exists {
+		return Player{}, fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		return Player{}, ErrBanned
+	}
+	return player.clone(), nil
+}
+
+// Balance consulta o saldo on-chain da carteira do jogador.
+func (s *Store) Balance(ctx context.Context, id int) (uint64, error) {
+	player, err := s.getPlayer(id)
+	if err != nil {
+		return 0, err
+	}
+	return s.bridge.Balance(ctx, player.Wallet)
+}
+
+// Faucet pede tokens de teste para a carteira do jogador.
+// Pode ser desligado (FaucetEnabled) e cada jogador só pode usá-lo
+// uma vez a cada FaucetCooldown.
+func (s *Store) Faucet(ctx context.Context, id int) (uint64, error) {
+	s.mu.Lock()
+	player, exists := s.players[id]
+	if !exists {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		s.mu.Unlock()
+		return 0, ErrBanned
+	}
+	if !s.FaucetEnabled {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("faucet desabilitado neste servidor")
+	}
+	if wait := s.FaucetCooldown - time.Since(s.lastFaucet[id]); wait > 0 {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("aguarde %s para pedir tokens novamente", wait.Round(time.Second))
+	}
+	// Reserva o uso antes da chamada para impedir pedidos simultâneos.
+	previous := s.lastFaucet[id]
+	s.lastFaucet[id] = time.Now()
+	s.mu.Unlock()
+
+	balance, err := s.bridge.Faucet(ctx, player.Wallet)
+	if err != nil {
+		s.mu.Lock()
+		s.lastFaucet[id] = previous
+		s.mu.Unlock()
+		return 0, err
+	}
+	return balance, nil
+}
+
+// --- TRANSFERÊNCIA ENTRE JOGADORES ---
+
+// Formato de endereço IOTA: 0x seguido de 64 dígitos hexadecimais.
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// SendTokens transfere IOTA da carteira de um jogador para outro jogador
+// (por ID) ou para um endereço qualquer. Valida limites e saldo antes de
+// enviar e retorna o digest da transação.
+func (s *Store) SendTokens(ctx context.Context, fromID, toID int, toAddress string, amount uint64) (string, error) {
+	if amount == 0 {
+		return "", fmt.Errorf("valor deve ser maior que zero")
+	}
+	if s.MaxTransfer > 0 && amount > s.MaxTransfer {
+		return "", fmt.Errorf("valor acima do limite por transferência (%d IOTA)", s.MaxTransfer)
+	}
+
+	sender, err := s.getPlayer(fromID)
+	if err != nil {
+		return "", err
+	}
+	wallet, err := spendable(sender)
+	if err != nil {
+		return "", err
+	}
+
+	// Resolve o destino: ID de jogador tem prioridade sobre endereço.
+	var dest Wallet
+	if toID != 0 {
+		recipient, err := s.getPlayer(toID)
+		if err != nil {
+			return "", fmt.Errorf("destinatário não encontrado")
+		}
+		dest = Wallet{Address: recipient.Wallet.Address}
+	} else {
+		if !addressPattern.MatchString(toAddress) {
+			return "", fmt.Errorf("endereço de destino inválido")
+		}
+		dest = Wallet{Address: toAddress}
+	}
+	if dest.Address == sender.Wallet.Address {
+		return "", fmt.Errorf("não é possível enviar para a própria carteira")
+	}
+
+	done, err := s.beginWork(fmt.Sprintf("sendTokens from=%d to=%s amount=%d", fromID, dest.Address, amount))
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	balance, err := s.bridge.Balance(ctx, sender.Wallet)
+	if err != nil {
+		return "", err
+	}
+	if balance < amount {
+		return "", ErrInsufficientFunds
+	}
+
+	digest, err := s.bridge.Transaction(ctx, wallet, dest, amount)
+	if err != nil {
+		return "", err
+	}
+	slog.Info("tokens sent", logPlayer, fromID, "to_address", dest.Address, "amount", amount, logDigest, digest)
+	return digest, nil
+}
+
+// --- CARTEIRAS EXTERNAS ---
+
+// Validade do desafio de vínculo de carteira.
+const linkChallengeTTL = 5 * time.Minute
+
+// Desafio pendente: a mensagem que deve ser assinada pela carteira.
+type linkChallenge struct {
+	Address string
+	Message string
+	Expires time.Time
+}
+
+// WalletChallenge cria a mensagem que o jogador deve assinar com a chave
+// da carteira externa para provar que é dono do endereço.
+func (s *Store) WalletChallenge(id int, address string) (string, error) {
+	address = strings.ToLower(address)
+	if !addressPattern.MatchString(address) {
+		return "", fmt.Errorf("endereço inválido")
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	message := fmt.Sprintf("PBL3 Redes: vincular %s ao jogador %d. Nonce: %s", address, id, hex.EncodeToString(nonce))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[id]
+	if !exists {
+		return "", fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		return "", ErrBanned
+	}
+	if address == player.Wallet.Address || slices.Contains(player.LinkedWallets, address) {
+		return "", fmt.Errorf("carteira já vinculada a este jogador")
+	}
+
+	s.linkChallenges[id] = linkChallenge{Address: address, Message: message, Expires: time.Now().Add(linkChallengeTTL)}
+	return message, nil
+}
+
+// LinkWallet confere a assinatura do desafio pendente e, se ela foi
+// feita pela chave do endereço informado, vincula a carteira ao jogador.
+func (s *Store) LinkWallet(id int, signature string) (string, error) {
+	s.mu.Lock()
+	challenge, ok := s.linkChallenges[id]
+	delete(s.linkChallenges, id) // Cada desafio vale para uma única tentativa
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(challenge.Expires) {
+		return "", fmt.Errorf("nenhum desafio pendente (ou expirado)")
+	}
+
+	signer, err := verifyPersonalMessage([]byte(challenge.Message), signature)
+	if err != nil {
+		return "", err
+	}
+	if signer != challenge.Address {
+		return "", fmt.Errorf("assinatura não pertence ao endereço %s", challenge.Address)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[id]
+	if !exists {
+		return "", fmt.Errorf("player not found")
+	}
+	if player.Banned {
+		return "", ErrBanned
+	}
+	if !slices.Contains(player.LinkedWallets, signer) {
+		player.LinkedWallets = append(player.LinkedWallets, signer)
+		s.players[id] = player
+	}
+	slog.Info("external wallet linked", logPlayer, id, "address", signer)
+	return signer, nil
+}
+
+// --- ASSINATURAS IOTA ---
+
+// Flag do esquema Ed25519 nas assinaturas e endereços IOTA.
+const ed25519Flag = 0x00
+
+// Intent de mensagem pessoal (scope=3, version=0, app=0), usado pelas
+// carteiras IOTA em signPersonalMessage para não confundir com transações.
+var personalMessageIntent = []byte{3, 0, 0}
+
+// verifyPersonalMessage confere uma assinatura serializada IOTA
+// (base64 de flag || assinatura || chave pública) sobre msg e
+// retorna o endereço derivado da chave pública que assinou.
+func verifyPersonalMessage(msg []byte, serialized string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(serialized))
+	if err != nil || len(raw) != 1+ed25519.SignatureSize+ed25519.PublicKeySize {
+		return "", fmt.Errorf("assinatura em formato inválido")
+	}
+	if raw[0] != ed25519Flag {
+		return "", fmt.Errorf("apenas assinaturas Ed25519 são aceitas")
+	}
+	sig := raw[1 : 1+ed25519.SignatureSize]
+	pub := ed25519.PublicKey(raw[1+ed25519.SignatureSize:])
+
+	// A mensagem é serializada em BCS (vector<u8>) e prefixada pelo intent.
+	payload := append(append([]byte{}, personalMessageIntent...), binary.AppendUvarint(nil, uint64(len(msg)))...)
+	payload = append(payload, msg...)
+	digest := blake2b.Sum256(payload)
+
+	if !ed25519.Verify(pub, digest[:], sig) {
+		return "", fmt.Errorf("assinatura inválida")
+	}
+	return addressFromPublicKey(pub), nil
+}
+
+// Endereço IOTA = blake2b-256(flag || chave pública), em hexadecimal.
+func addressFromPublicKey(pub ed25519.PublicKey) string {
+	sum := blake2b.Sum256(append([]byte{ed25519Flag}, pub...))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// Prefixo bech32 das chaves privadas exportadas pelo SDK IOTA.
+const privKeyPrefix = "iotaprivkey"
+
+// addressFromSecret deriva o endereço da chave privada de uma carteira
+// ("iotaprivkey1..." ou base64 de flag || chave), para conferir a chave
+// que o cliente devolve ao sair do modo sem custódia.
+func addressFromSecret(secret string) (string, error) {
+	secret = strings.TrimSpace(secret)
+
+	var raw []byte
+	var err error
+	if strings.HasPrefix(strings.ToLower(secret), privKeyPrefix+"1") {
+		raw, err = bech32Decode(secret, privKeyPrefix)
+	} else {
+		raw, err = base64.StdEncoding.DecodeString(secret)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(raw) != 1+ed25519.SeedSize || raw[0] != ed25519Flag {
+		return "", fmt.Errorf("apenas chaves Ed25519 são aceitas")
+	}
+	pub := ed25519.NewKeyFromSeed(raw[1:]).Public().(ed25519.PublicKey)
+	return addressFromPublicKey(pub), nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Decodifica uma string bech32 com o prefixo esperado e retorna os dados em bytes.
+func bech32Decode(text, hrp string) ([]byte, error) {
+	text = strings.ToLower(text)
+	sep := strings.LastIndexByte(text, '1')
+	if sep < 1 || text[:sep] != hrp || len(text)-sep-1 < 6 {
+		return nil, fmt.Errorf("chave bech32 inválida")
+	}
+
+	data := make([]byte, 0, len(text)-sep-1)
+	for _, c := range text[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return nil, fmt.Errorf("caractere inválido na chave bech32")
+		}
+		data = append(data, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), data...)) != 1 {
+		return nil, fmt.Errorf("checksum bech32 inválido")
+	}
+
+	// Converte os grupos de 5 bits (sem o checksum) para bytes.
+	var out []byte
+	acc, bits := 0, 0
+	for _, v := range data[:len(data)-6] {
+		acc = acc<<5 | int(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>bits))
+		}
+		acc &= 1<<bits - 1
+	}
+	return out, nil
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		out = append(out, byte(c>>5))
+	}
+	out = append(out, 0)
+	for _, c := range hrp {
+		out = append(out, byte(c&31))
+	}
+	return out
+}
+
+func bech32Polymod(values []byte) int {
+	gen := [5]int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ int(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,169 @@
+// Comando admin: CLI para o plano administrativo do game server (admin.*).
+//
+// Uso:
+//
+//	ADMIN_TOKEN=... go run ./cmd/admin [-nats URL] <comando> [args]
+//
+// O token não viaja na requisição: cada uma é assinada com HMAC-SHA256
+// (ver API.AdminSignature) e vale por poucos segundos.
+//
+// Comandos:
+//
+//	players               lista os jogadores
+//	player <id>           detalha um jogador (inclui saldo on-chain)
+//	queues                filas, partidas ativas, pacotes e journal
+//	cancel <game_id>      cancela uma partida em andamento
+//	ban <id>              bane um jogador
+//	unban <id>            reabilita um jogador
+//	refill <pacotes>      adiciona pacotes ao pool
+//	rotate                revela a semente do sorteio e inicia nova época
+//	tournaments           lista os torneios
+//	cancel-tournament <id> cancela um torneio e reembolsa as inscrições
+//	reconcile             repara carteiras e ressincroniza cartas com a blockchain
+//	repair                apenas repara carteiras vazias
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"server/API"
+
+	"github.com/nats-io/nats.go"
+)
+
+func main() {
+	natsURL := flag.String("nats", envOr("NATS_URL", nats.DefaultURL), "URL do servidor NATS")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "token administrativo (padrão: ADMIN_TOKEN)")
+	timeout := flag.Duration("timeout", 60*time.Second, "prazo da requisição")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "uso: admin [flags] players|player <id>|queues|cancel <game_id>|ban <id>|unban <id>|refill <pacotes>|rotate|tournaments|cancel-tournament <id>|reconcile|repair")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *token == "" {
+		fail("defina ADMIN_TOKEN ou -token")
+	}
+
+	req := map[string]any{}
+	var subject string
+	arg := flag.Arg(1)
+
+	switch cmd := flag.Arg(0); cmd {
+	case "players":
+		subject = "admin.players"
+	case "player":
+		subject = "admin.player"
+		req["id"] = mustInt(arg)
+	case "queues":
+		subject = "admin.queues"
+	case "cancel":
+		if arg == "" {
+			fail("informe o ID da partida")
+		}
+		subject = "admin.cancelMatch"
+		req["game_id"] = arg
+	case "ban", "unban":
+		subject = "admin.ban"
+		req["id"] = mustInt(arg)
+		req["banned"] = cmd == "ban"
+	case "refill":
+		subject = "admin.refillPacks"
+		req["packs"] = mustInt(arg)
+	case "rotate":
+		subject = "admin.rotateSeed"
+	case "tournaments":
+		subject = "admin.tournaments"
+	case "cancel-tournament":
+		if arg == "" {
+			fail("informe o ID do torneio")
+		}
+		subject = "admin.cancelTournament"
+		req["tournament"] = arg
+	case "reconcile":
+		subject = "admin.reconcile"
+	case "repair":
+		subject = "admin.repairWallets"
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	nc, err := nats.Connect(*natsURL)
+	if err != nil {
+		fail("erro ao conectar no NATS: %v", err)
+	}
+	defer nc.Close()
+
+	data, _ := json.Marshal(req)
+	msg, err := nc.RequestMsg(signed(*token, subject, data), *timeout)
+	if err != nil {
+		fail("sem resposta em %s: %v", subject, err)
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Err    string          `json:"err"`
+	}
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		fail("resposta inválida: %v", err)
+	}
+	if resp.Err != "" {
+		fail("%s", resp.Err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, resp.Result, "", "  "); err != nil {
+		fmt.Println(string(resp.Result))
+		return
+	}
+	fmt.Println(out.String())
+}
+
+// signed monta a requisição com os cabeçalhos de assinatura.
+func signed(token, subject string, data []byte) *nats.Msg {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	n := hex.EncodeToString(nonce)
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(API.AdminTimestampHeader, ts)
+	msg.Header.Set(API.AdminNonceHeader, n)
+	msg.Header.Set(API.AdminSignatureHeader, API.AdminSignature(token, subject, ts, n, data))
+	return msg
+}
+
+func mustInt(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		fail("número inválido: %q", s)
+	}
+	return n
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "erro: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -1,17 +1,15 @@
-#!/bin/bash
+#!/bin/sh
 
 # healthcheck.sh
-# Verifica a saúde do nó Raft/API
+# Verifica a saúde do nó do game server
 
-# Verifica se o endpoint da API está rodando
-STATUS_CODE=$(curl -s -o /dev/null -w "%{http_code}" http://localhost:8080/status)
+# /readyz confere NATS, worker blockchain, carteira da loja e persistência
+# do estado; responde 503 se alguma dependência estiver indisponível.
+URL="${HEALTH_URL:-http://localhost:8080/readyz}"
 
-if [ "$STATUS_CODE" -ne 200 ]; then
-    echo "API não está respondendo (Status: $STATUS_CODE)"
+if ! wget -q -O /dev/null "$URL"; then
+    echo "Nó não está pronto ($URL)"
     exit 1
 fi
 
-# Opcional: Adicionar checagem de estado Raft (depende do output do /status)
-# Por simplicidade, a checagem de /status é suficiente para a saúde básica.
-
-exit 0
\ No newline at end of file
+exit 0
@@ -1,27 +1,175 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 	"server/API" 
 )
 
 func main() {
-	// 1. Inicializa Store
-	store := API.NewStore()
+	// Identificador deste nó no cluster (flag --id ou variável NODE_ID).
+	defaultID := os.Getenv("NODE_ID")
+	if defaultID == "" {
+		defaultID = "server-central"
+	}
+	nodeID := flag.String("id", defaultID, "identificador do nó no cluster")
 
-	// 2. Inicializa NATS
-	go func() {
-		API.SetupPS(store)
-		log.Println("NATS Pub/Sub initialized.")
-	}()
+	// Lista de servidores NATS separados por vírgula (flag --nats ou NATS_URL).
+	natsURLs := flag.String("nats", os.Getenv("NATS_URL"), "URLs dos servidores NATS, separadas por vírgula")
 
-	// 3. Mantém rodando
+	// Tempo máximo para concluir o trabalho em andamento ao encerrar.
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "tempo máximo de encerramento gracioso")
+
+	// Prazos e retentativas das chamadas ao worker blockchain.
+	bridgeCfg := API.DefaultBridgeConfig()
+	flag.IntVar(&bridgeCfg.Retries, "bridge-retries", bridgeCfg.Retries, "retentativas de consultas ao worker blockchain")
+	bridgeTimeout := flag.Duration("bridge-timeout", 0, "prazo único para todas as chamadas ao worker (0 = padrão de cada operação)")
+
+	// Faucet de testes: desligue em produção (--faucet=false ou FAUCET_ENABLED=false).
+	faucetEnabled := flag.Bool("faucet", os.Getenv("FAUCET_ENABLED") != "false", "permite que jogadores peçam tokens de teste")
+	faucetCooldown := flag.Duration("faucet-cooldown", 10*time.Minute, "intervalo mínimo entre pedidos de faucet por jogador")
+
+	// Limite de IOTA por transferência entre jogadores.
+	maxTransfer := flag.Uint64("max-transfer", 10_000_000_000, "máximo de IOTA por transferência entre jogadores (0 = sem limite)")
+
+	// Regras dos decks usados no matchmaking.
+	deckSize := flag.Int("deck-size", 3, "número de cartas de um deck")
+	deckPowerCap := flag.Int("deck-power-cap", 0, "limite da soma das forças de um deck (0 = sem limite)")
+
+	// Oponente das partidas de treino.
+	botStrategy := flag.String("bot-strategy", envOr("BOT_STRATEGY", "adaptive"), "estratégia padrão do bot de treino: random, greedy ou adaptive")
+
+	// Prazo de cada partida de torneio antes do W.O.
+	tournamentTimeout := flag.Duration("tournament-match-timeout", 5*time.Minute, "prazo para os jogadores jogarem uma partida de torneio antes do W.O.")
+
+	// Taxa da casa sobre o pote das partidas apostadas.
+	wagerRake := flag.Uint64("wager-rake", 5, "taxa da casa sobre o pote das partidas apostadas, em % (0 a 100)")
+
+	// Endereço HTTP dos endpoints operacionais (/metrics, /healthz, /readyz).
+	httpAddr := flag.String("http-addr", envOr("HTTP_ADDR", ":8080"), "endereço HTTP para /metrics, /healthz e /readyz (vazio desliga)")
+
+	// Logs estruturados: nível (LOG_LEVEL) e formato text/json (LOG_FORMAT).
+	logLevel := flag.String("log-level", envOr("LOG_LEVEL", "info"), "nível de log: debug, info, warn ou error")
+	logFormat := flag.String("log-format", envOr("LOG_FORMAT", "text"), "formato dos logs: text ou json")
+	// Rastreamento: com OTEL_TRACES_EXPORTER=stdout os spans são escritos em stdout.
+	traceExporter := flag.String("trace-exporter", os.Getenv("OTEL_TRACES_EXPORTER"), "exportador de traces: stdout ou vazio (só propaga)")
+	flag.Parse()
+
+	API.SetupLogging(os.Stderr, *logLevel, *logFormat)
+
+	if *wagerRake > 100 {
+		slog.Error("invalid wager rake", "rake", *wagerRake)
+		os.Exit(1)
+	}
+	// Sem cartas no deck a mão do bot fica vazia e não há o que jogar.
+	if *deckSize < 1 {
+		slog.Error("invalid deck size", "deck_size", *deckSize)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := API.SetupTracing(*traceExporter, API.ServiceName(*nodeID), os.Stdout)
+	if err != nil {
+		slog.Error("tracing setup failed", "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background()) // Descarrega os spans pendentes ao sair
+
+	if *bridgeTimeout > 0 {
+		bridgeCfg.Timeouts = nil
+		bridgeCfg.Timeout = *bridgeTimeout
+	}
+
+	// 1. Conecta ao NATS
+	nc, err := API.BrokerConnect(splitList(*natsURLs))
+	if err != nil {
+		slog.Error("NATS connect failed", "err", err)
+		os.Exit(1)
+	}
+
+	// 2. Inicializa Store com o cliente da blockchain
+	store := API.NewStore(*nodeID, API.NewBlockchainClient(nc, bridgeCfg), nc)
+	store.FaucetEnabled = *faucetEnabled
+	store.FaucetCooldown = *faucetCooldown
+	store.MaxTransfer = *maxTransfer
+	store.DeckSize = *deckSize
+	store.DeckPowerCap = *deckPowerCap
+	store.BotStrategy = *botStrategy
+	store.TournamentMatchTimeout = *tournamentTimeout
+	store.WagerRake = *wagerRake
+
+	// Segredo comum aos nós do cluster para cifrar a semente e as chaves
+	// das carteiras no snapshot.
+	store.SetStateKey(os.Getenv("STATE_KEY"))
+	if os.Getenv("STATE_KEY") == "" {
+		slog.Warn("STATE_KEY not set, fairness seed and wallet keys will not be replicated")
+	}
+
+	// 3. Registra os handlers e entra na eleição de líder
+	srv, err := API.SetupPS(nc, store)
+	if err != nil {
+		slog.Error("NATS setup failed", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("server started", "node_id", *nodeID)
+
+	// 4. Endpoints HTTP (métricas e health checks)
+	var httpSrv *http.Server
+	if *httpAddr != "" {
+		if err := API.RegisterStoreMetrics(store); err != nil {
+			slog.Error("metrics setup failed", "err", err)
+			os.Exit(1)
+		}
+		httpSrv = &http.Server{Addr: *httpAddr, Handler: srv.HTTPHandler()}
+		go func() {
+			if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server failed", "addr", *httpAddr, "err", err)
+			}
+		}()
+		slog.Info("HTTP endpoints listening", "addr", *httpAddr)
+	}
+
+	// 5. Mantém rodando
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
-}
\ No newline at end of file
+	slog.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if httpSrv != nil {
+		httpSrv.Shutdown(ctx)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("shutdown incomplete", "err", err)
+		return
+	}
+	slog.Info("server stopped")
+}
+
+// Separa uma lista "a,b,c" ignorando espaços e itens vazios.
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Valor da variável de ambiente ou o padrão, se ela estiver vazia.
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}